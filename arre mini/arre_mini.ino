@@ -17,6 +17,9 @@
 #define RED_LED_PIN 9
 #define GREEN_LED_PIN 6
 
+#define BUTTON_DEBOUNCE_MS 50
+#define BUTTON_LONG_PRESS_MS 1500 // долгое нажатие переключает режим "только вес"
+
 #define NUM_SENSORS 4
 
 TroykaI2CHub splitter;
@@ -24,6 +27,9 @@ const byte sensors_pins[NUM_SENSORS] = {0, 2, 4, 6}; // LEFT, RIGHT, TOP, BACK
 
 int TOP_MAX = 68, WIDTH_MAX = 55, LENGTH_MAX = 70;
 bool onlyWeight = false, start = false;
+byte buttonPresses = 0; // нажатия кнопки с последнего запроса 0x87
+bool buttonHeld = false, buttonLongPress = false;
+unsigned long buttonDownAt = 0;
 
 int widthBox = 0, heightBox = 0, lengthBox = 0;
 int right = 0, prev_right = 0, left = 0, prev_left = 0, top = 0, prev_top = 0, back = 0, prev_back = 0;
@@ -234,6 +240,14 @@ void SerialExchange() {
         digitalWrite(GREEN_LED_PIN, onlyWeight);
         digitalWrite(RED_LED_PIN, !onlyWeight);
         return;
+      case 0x67: // зеленый — измерение выполнено
+        digitalWrite(GREEN_LED_PIN, HIGH);
+        digitalWrite(RED_LED_PIN, LOW);
+        return;
+      case 0x68: // красный — ошибка
+        digitalWrite(RED_LED_PIN, HIGH);
+        digitalWrite(GREEN_LED_PIN, LOW);
+        return;
       case 0x55:
         digitalWrite(RED_LED_PIN, LOW);
         digitalWrite(GREEN_LED_PIN, LOW);
@@ -241,11 +255,38 @@ void SerialExchange() {
       case 0x77:
         Serial.write("OK", 2);
         return;
+      case 0x87: {
+        byte buf[2] = {0x87, buttonPresses}; // маркер вне ASCII, чтобы не спутать с отладочным выводом
+        buttonPresses = 0;
+        Serial.write(buf, sizeof(buf));
+        return;
+      }
     }
   }
   while (Serial.available()) Serial.read();
 }
 
+// Короткое нажатие считается для запроса 0x87 и запускает измерение,
+// долгое (BUTTON_LONG_PRESS_MS) переключает режим "только вес" и не считается.
+void ReadButton() {
+  bool pressed = digitalRead(BUTTON) == LOW;
+  unsigned long now = millis();
+  if (pressed && !buttonHeld) {
+    buttonHeld = true;
+    buttonLongPress = false;
+    buttonDownAt = now;
+  } else if (pressed && !buttonLongPress && now - buttonDownAt >= BUTTON_LONG_PRESS_MS) {
+    buttonLongPress = true;
+    onlyWeight = !onlyWeight;
+    digitalWrite(RED_LED_PIN, !onlyWeight);
+    digitalWrite(GREEN_LED_PIN, onlyWeight);
+  } else if (!pressed && buttonHeld) {
+    buttonHeld = false;
+    if (!buttonLongPress && now - buttonDownAt >= BUTTON_DEBOUNCE_MS && buttonPresses < 255)
+      buttonPresses++;
+  }
+}
+
 void setup() {
   pinMode(RED_LED_PIN, OUTPUT);
   pinMode(GREEN_LED_PIN, OUTPUT);
@@ -287,12 +328,7 @@ void loop() {
 
   SerialExchange();
 
-  if (digitalRead(BUTTON) == LOW) {
-    onlyWeight = !onlyWeight;
-    digitalWrite(RED_LED_PIN, !onlyWeight);
-    digitalWrite(GREEN_LED_PIN, onlyWeight);
-    delay(400);
-  }
+  ReadButton();
 
   loop_counter++;
   delay(10);
@@ -0,0 +1,338 @@
+package auth
+
+import (
+    "context"
+    "crypto/rand"
+    "crypto/subtle"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "net"
+    "net/http"
+    "strings"
+    "sync"
+    "time"
+
+    "betelgeuze-measure-system-main/config"
+    "betelgeuze-measure-system-main/logging"
+)
+
+// Роли пользователей и токенов по возрастанию прав
+const (
+    RoleViewer   = "viewer"   // просмотр статуса, истории и логов
+    RoleOperator = "operator" // измерения, подтверждение, режим, тарирование
+    RoleAdmin    = "admin"    // команды и калибровка Arduino
+)
+
+// ROLES — роли по возрастанию прав
+var ROLES = []string{RoleViewer, RoleOperator, RoleAdmin}
+
+// SESSION_COOKIE — cookie сессии веб-интерфейса
+const SESSION_COOKIE = "betelgeuze_session"
+
+// DEFAULT_SESSION_HOURS — срок сессии, если session_hours не задан
+const DEFAULT_SESSION_HOURS = 12
+
+// Задержка входа после неудачных попыток: проверка пароля PBKDF2 дорогая, перебор
+// с одного адреса или по одному имени не должен занимать процессор станции
+const (
+    LOGIN_FREE_ATTEMPTS = 5               // неудачных попыток подряд без задержки
+    LOGIN_BASE_DELAY    = 1 * time.Second // задержка после первой лишней попытки, дальше удваивается
+    LOGIN_MAX_DELAY     = 5 * time.Minute
+)
+
+// ErrInvalidCredentials — неверное имя пользователя или пароль
+var ErrInvalidCredentials = errors.New("неверное имя пользователя или пароль")
+
+// ErrTooManyAttempts — слишком много неудачных попыток входа, пароль не проверялся
+var ErrTooManyAttempts = errors.New("слишком много неудачных попыток входа")
+
+// Principal — пользователь или токен, от имени которого выполняется запрос
+type Principal struct {
+    Name string `json:"name"`
+    Role string `json:"role"`
+}
+
+// Allows проверяет, что роль не ниже требуемой
+func Allows(role, need string) bool {
+    return rank(role) >= rank(need)
+}
+
+func rank(role string) int {
+    for i, r := range ROLES {
+        if r == role {
+            return i
+        }
+    }
+    return -1
+}
+
+type session struct {
+    principal Principal
+    expires   time.Time
+}
+
+// failures — неудачные попытки входа с одного адреса или по одному имени
+type failures struct {
+    count int
+    until time.Time // до этого времени пароль не проверяется
+    last  time.Time
+}
+
+// Service проверяет пароли, токены и сессии. Сессии хранятся в памяти:
+// после перезапуска станции нужно войти заново.
+type Service struct {
+    users    map[string]config.UserConfig
+    tokens   []config.TokenConfig
+    lifetime time.Duration
+
+    mu       sync.Mutex
+    sessions map[string]session
+    failed   map[string]*failures // ключи "addr:<адрес>" и "user:<имя>"
+}
+
+var defaultService *Service
+
+// dummyHash сравнивается с паролем неизвестного пользователя, чтобы время ответа
+// не выдавало, существует ли имя
+var dummyHash = sync.OnceValue(func() string {
+    hash, _ := HashPassword("")
+    return hash
+})
+
+// New создает проверку доступа по настройкам
+func New(cfg *config.AuthConfig) (*Service, error) {
+    s := &Service{
+        users:    make(map[string]config.UserConfig),
+        lifetime: time.Duration(cfg.SessionHours) * time.Hour,
+        sessions: make(map[string]session),
+        failed:   make(map[string]*failures),
+    }
+    if s.lifetime <= 0 {
+        s.lifetime = DEFAULT_SESSION_HOURS * time.Hour
+    }
+    for _, u := range cfg.Users {
+        if u.Name == "" {
+            return nil, errors.New("auth: у пользователя нет имени")
+        }
+        if rank(u.Role) < 0 {
+            return nil, fmt.Errorf("auth: неизвестная роль %q у пользователя %s", u.Role, u.Name)
+        }
+        if _, _, _, err := parseHash(u.PasswordHash); err != nil {
+            return nil, fmt.Errorf("auth: пользователь %s: %v", u.Name, err)
+        }
+        if _, ok := s.users[u.Name]; ok {
+            return nil, fmt.Errorf("auth: пользователь %s указан дважды", u.Name)
+        }
+        s.users[u.Name] = u
+    }
+    for _, t := range cfg.Tokens {
+        if rank(t.Role) < 0 {
+            return nil, fmt.Errorf("auth: неизвестная роль %q у токена %s", t.Role, t.Name)
+        }
+        if len(t.TokenHash) != 64 {
+            return nil, fmt.Errorf("auth: токен %s: token_hash должен быть SHA-256 в hex", t.Name)
+        }
+        s.tokens = append(s.tokens, t)
+    }
+    if len(s.users) == 0 && len(s.tokens) == 0 {
+        return nil, errors.New("auth: не задано ни одного пользователя или токена")
+    }
+    return s, nil
+}
+
+// Init включает проверку доступа, если она настроена. При ошибке в настройках
+// сервер не должен открываться всем: вызывающий завершает программу.
+func Init(cfg *config.AuthConfig) error {
+    defaultService = nil
+    if cfg == nil || !cfg.Enabled {
+        return nil
+    }
+    s, err := New(cfg)
+    if err != nil {
+        return err
+    }
+    defaultService = s
+    logging.BroadcastLog(fmt.Sprintf("Вход в веб-интерфейс включен: пользователей %d, токенов %d", len(s.users), len(s.tokens)), "system")
+    return nil
+}
+
+// Default возвращает проверку доступа станции или nil, если вход не настроен
+func Default() *Service {
+    return defaultService
+}
+
+// Enabled сообщает, включена ли проверка доступа
+func Enabled() bool {
+    return defaultService != nil
+}
+
+// Login проверяет пароль и открывает сессию. remote — адрес клиента (r.RemoteAddr).
+// После LOGIN_FREE_ATTEMPTS неудачных попыток с адреса или по имени следующие попытки
+// отклоняются с ErrTooManyAttempts, не проверяя пароль, пока не истечет задержка (LoginDelay).
+func (s *Service) Login(name, password, remote string) (string, Principal, time.Time, error) {
+    keys := failureKeys(name, remote)
+    if wait := s.reserve(keys); wait > 0 {
+        logging.Warn(fmt.Sprintf("Вход отложен: пользователь %q, адрес %s, повтор через %s", name, remote, wait.Round(time.Second)), "system")
+        return "", Principal{}, time.Time{}, fmt.Errorf("%w: повторите через %s", ErrTooManyAttempts, wait.Round(time.Second))
+    }
+
+    user, ok := s.users[name]
+    hash := user.PasswordHash
+    if !ok {
+        hash = dummyHash()
+    }
+    if !CheckPassword(hash, password) || !ok {
+        logging.Warn(fmt.Sprintf("Неудачный вход: пользователь %q, адрес %s", name, remote), "system")
+        return "", Principal{}, time.Time{}, ErrInvalidCredentials
+    }
+
+    raw := make([]byte, 32)
+    if _, err := rand.Read(raw); err != nil {
+        return "", Principal{}, time.Time{}, err
+    }
+    id := hex.EncodeToString(raw)
+    p := Principal{Name: user.Name, Role: user.Role}
+    expires := time.Now().Add(s.lifetime)
+
+    s.mu.Lock()
+    for _, key := range keys {
+        delete(s.failed, key)
+    }
+    s.sessions[id] = session{principal: p, expires: expires}
+    // Заодно убираем истекшие сессии
+    now := time.Now()
+    for k, v := range s.sessions {
+        if now.After(v.expires) {
+            delete(s.sessions, k)
+        }
+    }
+    s.mu.Unlock()
+
+    logging.BroadcastLog(fmt.Sprintf("Вход: %s (%s), адрес %s", p.Name, p.Role, remote), "system")
+    return id, p, expires, nil
+}
+
+// failureKeys возвращает ключи учета неудачных попыток: адрес клиента без порта и имя
+func failureKeys(name, remote string) []string {
+    if host, _, err := net.SplitHostPort(remote); err == nil {
+        remote = host
+    }
+    return []string{"addr:" + remote, "user:" + name}
+}
+
+// reserve учитывает попытку входа до проверки пароля: одновременные запросы тоже
+// попадают в счетчик. Успешный вход сбрасывает счетчики. Возвращает задержку,
+// если попытку нужно отклонить.
+func (s *Service) reserve(keys []string) time.Duration {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    now := time.Now()
+    for key, f := range s.failed {
+        if now.Sub(f.last) > LOGIN_MAX_DELAY && now.After(f.until) {
+            delete(s.failed, key)
+        }
+    }
+    wait := time.Duration(0)
+    for _, key := range keys {
+        if f := s.failed[key]; f != nil && f.until.After(now) && f.until.Sub(now) > wait {
+            wait = f.until.Sub(now)
+        }
+    }
+    if wait > 0 {
+        return wait
+    }
+    for _, key := range keys {
+        f := s.failed[key]
+        if f == nil {
+            f = &failures{}
+            s.failed[key] = f
+        }
+        f.count++
+        f.last = now
+        if f.count > LOGIN_FREE_ATTEMPTS {
+            f.until = now.Add(loginDelay(f.count - LOGIN_FREE_ATTEMPTS))
+        }
+    }
+    return 0
+}
+
+// loginDelay — задержка после n-й лишней неудачной попытки
+func loginDelay(n int) time.Duration {
+    delay := LOGIN_BASE_DELAY
+    for i := 1; i < n && delay < LOGIN_MAX_DELAY; i++ {
+        delay *= 2
+    }
+    if delay > LOGIN_MAX_DELAY {
+        delay = LOGIN_MAX_DELAY
+    }
+    return delay
+}
+
+// LoginDelay возвращает, сколько ждать до следующей попытки входа с адреса remote по имени name
+func (s *Service) LoginDelay(name, remote string) time.Duration {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    now := time.Now()
+    wait := time.Duration(0)
+    for _, key := range failureKeys(name, remote) {
+        if f := s.failed[key]; f != nil && f.until.Sub(now) > wait {
+            wait = f.until.Sub(now)
+        }
+    }
+    return wait
+}
+
+// Logout закрывает сессию
+func (s *Service) Logout(id string) {
+    s.mu.Lock()
+    delete(s.sessions, id)
+    s.mu.Unlock()
+}
+
+// Authenticate определяет, кто выполняет запрос: по заголовку Authorization: Bearer
+// или по cookie сессии. nil — запрос без действующих учетных данных.
+func (s *Service) Authenticate(r *http.Request) *Principal {
+    if header := r.Header.Get("Authorization"); header != "" {
+        token, ok := strings.CutPrefix(header, "Bearer ")
+        if ok {
+            hash := HashToken(strings.TrimSpace(token))
+            for _, t := range s.tokens {
+                if subtle.ConstantTimeCompare([]byte(hash), []byte(strings.ToLower(t.TokenHash))) == 1 {
+                    return &Principal{Name: t.Name, Role: t.Role}
+                }
+            }
+        }
+        logging.Warn(fmt.Sprintf("Неверный токен API, адрес %s", r.RemoteAddr), "system")
+        return nil
+    }
+    cookie, err := r.Cookie(SESSION_COOKIE)
+    if err != nil {
+        return nil
+    }
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    sess, ok := s.sessions[cookie.Value]
+    if !ok {
+        return nil
+    }
+    if time.Now().After(sess.expires) {
+        delete(s.sessions, cookie.Value)
+        return nil
+    }
+    p := sess.principal
+    return &p
+}
+
+type principalKey struct{}
+
+// WithPrincipal сохраняет пользователя запроса в контексте
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+    return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromRequest возвращает пользователя запроса или nil, если вход не настроен
+func FromRequest(r *http.Request) *Principal {
+    p, _ := r.Context().Value(principalKey{}).(*Principal)
+    return p
+}
\ No newline at end of file
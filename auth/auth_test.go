@@ -0,0 +1,256 @@
+package auth
+
+import (
+    "crypto/pbkdf2"
+    "crypto/sha256"
+    "encoding/base64"
+    "errors"
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "testing"
+    "time"
+
+    "betelgeuze-measure-system-main/config"
+)
+
+// cheapHash — хеш в формате HashPassword с малым числом итераций, чтобы тесты не ждали PBKDF2
+func cheapHash(t *testing.T, password string) string {
+    t.Helper()
+    salt := []byte("0123456789abcdef")
+    key, err := pbkdf2.Key(sha256.New, password, salt, 1000, sha256.Size)
+    if err != nil {
+        t.Fatal(err)
+    }
+    return fmt.Sprintf("%s$%d$%s$%s", hashScheme, 1000,
+        base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key))
+}
+
+func newTestService(t *testing.T, tokens ...config.TokenConfig) *Service {
+    t.Helper()
+    s, err := New(&config.AuthConfig{
+        Users: []config.UserConfig{
+            {Name: "admin", PasswordHash: cheapHash(t, "secret"), Role: RoleAdmin},
+            {Name: "smena1", PasswordHash: cheapHash(t, "smena"), Role: RoleOperator},
+        },
+        Tokens: tokens,
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+    return s
+}
+
+func TestPasswordHash(t *testing.T) {
+    hash, err := HashPassword("secret")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if iterations, _, _, err := parseHash(hash); err != nil || iterations != PBKDF2_ITERATIONS {
+        t.Fatalf("хеш %q: итераций %d, %v", hash, iterations, err)
+    }
+    if !CheckPassword(hash, "secret") {
+        t.Error("верный пароль не принят")
+    }
+    if CheckPassword(hash, "Secret") {
+        t.Error("неверный пароль принят")
+    }
+    if other, _ := HashPassword("secret"); other == hash {
+        t.Error("у двух хешей одного пароля одинаковая соль")
+    }
+
+    for _, bad := range []string{
+        "",
+        "secret",
+        "bcrypt$600000$c2FsdA$a2V5",
+        "pbkdf2-sha256$0$c2FsdA$a2V5",
+        "pbkdf2-sha256$много$c2FsdA$a2V5",
+        "pbkdf2-sha256$1000$не base64$a2V5",
+        "pbkdf2-sha256$1000$c2FsdA$",
+    } {
+        if _, _, _, err := parseHash(bad); !errors.Is(err, ErrBadHash) {
+            t.Errorf("хеш %q: ожидалась ErrBadHash, получено %v", bad, err)
+        }
+        if CheckPassword(bad, "secret") {
+            t.Errorf("пароль принят по неверному хешу %q", bad)
+        }
+    }
+}
+
+func TestNewRejectsBadConfig(t *testing.T) {
+    hash := cheapHash(t, "secret")
+    for name, cfg := range map[string]config.AuthConfig{
+        "пусто":            {},
+        "без имени":        {Users: []config.UserConfig{{PasswordHash: hash, Role: RoleAdmin}}},
+        "неизвестная роль": {Users: []config.UserConfig{{Name: "a", PasswordHash: hash, Role: "root"}}},
+        "неверный хеш":     {Users: []config.UserConfig{{Name: "a", PasswordHash: "secret", Role: RoleAdmin}}},
+        "имя дважды":       {Users: []config.UserConfig{{Name: "a", PasswordHash: hash, Role: RoleAdmin}, {Name: "a", PasswordHash: hash, Role: RoleViewer}}},
+        "короткий токен":   {Tokens: []config.TokenConfig{{Name: "wms", TokenHash: "abc", Role: RoleOperator}}},
+    } {
+        if _, err := New(&cfg); err == nil {
+            t.Errorf("%s: настройка принята", name)
+        }
+    }
+}
+
+func TestSessionLoginAndExpiry(t *testing.T) {
+    s := newTestService(t)
+    if _, _, _, err := s.Login("admin", "wrong", "10.0.0.5:40000"); !errors.Is(err, ErrInvalidCredentials) {
+        t.Fatalf("неверный пароль: %v", err)
+    }
+    id, p, expires, err := s.Login("smena1", "smena", "10.0.0.5:40000")
+    if err != nil {
+        t.Fatal(err)
+    }
+    if p.Name != "smena1" || p.Role != RoleOperator {
+        t.Errorf("пользователь сессии: %+v", p)
+    }
+    if d := time.Until(expires); d < DEFAULT_SESSION_HOURS*time.Hour-time.Minute || d > DEFAULT_SESSION_HOURS*time.Hour {
+        t.Errorf("срок сессии: %s", d)
+    }
+
+    r := httptest.NewRequest("GET", "/api/v1/status", nil)
+    r.AddCookie(&http.Cookie{Name: SESSION_COOKIE, Value: id})
+    if got := s.Authenticate(r); got == nil || *got != p {
+        t.Fatalf("сессия не принята: %+v", got)
+    }
+
+    s.mu.Lock()
+    sess := s.sessions[id]
+    sess.expires = time.Now().Add(-time.Second)
+    s.sessions[id] = sess
+    s.mu.Unlock()
+    if got := s.Authenticate(r); got != nil {
+        t.Fatalf("истекшая сессия принята: %+v", got)
+    }
+    if _, ok := s.sessions[id]; ok {
+        t.Error("истекшая сессия не удалена")
+    }
+
+    id, _, _, _ = s.Login("smena1", "smena", "10.0.0.5:40000")
+    s.Logout(id)
+    r = httptest.NewRequest("GET", "/api/v1/status", nil)
+    r.AddCookie(&http.Cookie{Name: SESSION_COOKIE, Value: id})
+    if got := s.Authenticate(r); got != nil {
+        t.Errorf("закрытая сессия принята: %+v", got)
+    }
+}
+
+func TestBearerToken(t *testing.T) {
+    token, hash, err := NewToken()
+    if err != nil {
+        t.Fatal(err)
+    }
+    s := newTestService(t, config.TokenConfig{Name: "wms", TokenHash: hash, Role: RoleOperator})
+    id, _, _, err := s.Login("admin", "secret", "10.0.0.5:40000")
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    // Заголовок Authorization проверяется первым: неверный токен не заменяется cookie сессии администратора
+    tests := []struct {
+        name   string
+        header string
+        want   *Principal
+    }{
+        {"верный токен", "Bearer " + token, &Principal{Name: "wms", Role: RoleOperator}},
+        {"пробелы вокруг токена", "Bearer  " + token + " ", &Principal{Name: "wms", Role: RoleOperator}},
+        {"неверный токен", "Bearer " + token + "x", nil},
+        {"другая схема", "Basic " + token, nil},
+        {"пустой токен", "Bearer ", nil},
+    }
+    for _, tt := range tests {
+        r := httptest.NewRequest("GET", "/api/v1/status", nil)
+        r.Header.Set("Authorization", tt.header)
+        r.AddCookie(&http.Cookie{Name: SESSION_COOKIE, Value: id})
+        got := s.Authenticate(r)
+        if (got == nil) != (tt.want == nil) || got != nil && *got != *tt.want {
+            t.Errorf("%s: получено %+v, ожидалось %+v", tt.name, got, tt.want)
+        }
+    }
+}
+
+func TestAllows(t *testing.T) {
+    tests := []struct {
+        role, need string
+        want       bool
+    }{
+        {RoleViewer, RoleViewer, true},
+        {RoleViewer, RoleOperator, false},
+        {RoleOperator, RoleViewer, true},
+        {RoleOperator, RoleAdmin, false},
+        {RoleAdmin, RoleOperator, true},
+        {"", RoleViewer, false},
+        {"root", RoleViewer, false},
+    }
+    for _, tt := range tests {
+        if got := Allows(tt.role, tt.need); got != tt.want {
+            t.Errorf("Allows(%q, %q) = %v, ожидалось %v", tt.role, tt.need, got, tt.want)
+        }
+    }
+}
+
+func TestLoginBackoff(t *testing.T) {
+    s := newTestService(t)
+    const remote = "10.0.0.5:40000"
+    for i := 0; i < LOGIN_FREE_ATTEMPTS; i++ {
+        if _, _, _, err := s.Login("admin", "wrong", remote); !errors.Is(err, ErrInvalidCredentials) {
+            t.Fatalf("попытка %d: %v", i+1, err)
+        }
+    }
+    // Следующая попытка еще проверяет пароль и назначает задержку
+    if _, _, _, err := s.Login("admin", "wrong", remote); !errors.Is(err, ErrInvalidCredentials) {
+        t.Fatalf("первая лишняя попытка: %v", err)
+    }
+    if _, _, _, err := s.Login("admin", "secret", remote); !errors.Is(err, ErrTooManyAttempts) {
+        t.Fatalf("во время задержки ожидалась ErrTooManyAttempts, получено %v", err)
+    }
+    if wait := s.LoginDelay("admin", remote); wait <= 0 || wait > LOGIN_BASE_DELAY {
+        t.Errorf("задержка: %s", wait)
+    }
+
+    // Тот же адрес с другим портом и другое имя с того же адреса тоже ждут
+    if _, _, _, err := s.Login("smena1", "smena", "10.0.0.5:40001"); !errors.Is(err, ErrTooManyAttempts) {
+        t.Errorf("другое имя с того же адреса: %v", err)
+    }
+    // Другой адрес по тому же имени тоже ждет: перебор пароля одного пользователя с разных адресов
+    if _, _, _, err := s.Login("admin", "secret", "10.0.0.6:40000"); !errors.Is(err, ErrTooManyAttempts) {
+        t.Errorf("то же имя с другого адреса: %v", err)
+    }
+    // Другой пользователь с другого адреса входит
+    if _, _, _, err := s.Login("smena1", "smena", "10.0.0.7:40000"); err != nil {
+        t.Errorf("другой пользователь с другого адреса: %v", err)
+    }
+
+    // После задержки пароль снова проверяется, успешный вход сбрасывает счетчики
+    s.mu.Lock()
+    for _, f := range s.failed {
+        f.until = time.Now().Add(-time.Millisecond)
+    }
+    s.mu.Unlock()
+    if _, _, _, err := s.Login("admin", "secret", remote); err != nil {
+        t.Fatalf("вход после задержки: %v", err)
+    }
+    for _, key := range failureKeys("admin", remote) {
+        if _, ok := s.failed[key]; ok {
+            t.Errorf("счетчик %s не сброшен после входа", key)
+        }
+    }
+}
+
+func TestLoginDelayGrows(t *testing.T) {
+    tests := []struct {
+        n    int
+        want time.Duration
+    }{
+        {1, LOGIN_BASE_DELAY},
+        {2, 2 * LOGIN_BASE_DELAY},
+        {4, 8 * LOGIN_BASE_DELAY},
+        {30, LOGIN_MAX_DELAY},
+    }
+    for _, tt := range tests {
+        if got := loginDelay(tt.n); got != tt.want {
+            t.Errorf("loginDelay(%d) = %s, ожидалось %s", tt.n, got, tt.want)
+        }
+    }
+}
\ No newline at end of file
@@ -0,0 +1,84 @@
+package auth
+
+import (
+    "crypto/pbkdf2"
+    "crypto/rand"
+    "crypto/sha256"
+    "crypto/subtle"
+    "encoding/base64"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "strconv"
+    "strings"
+)
+
+// PBKDF2_ITERATIONS — число итераций PBKDF2-SHA256 для новых паролей
+const PBKDF2_ITERATIONS = 600000
+
+const hashScheme = "pbkdf2-sha256"
+
+// ErrBadHash — хеш пароля в настройках записан не в формате HashPassword
+var ErrBadHash = errors.New("неверный формат хеша пароля")
+
+// HashPassword возвращает хеш пароля для настроек: pbkdf2-sha256$итерации$соль$хеш
+func HashPassword(password string) (string, error) {
+    salt := make([]byte, 16)
+    if _, err := rand.Read(salt); err != nil {
+        return "", err
+    }
+    key, err := pbkdf2.Key(sha256.New, password, salt, PBKDF2_ITERATIONS, sha256.Size)
+    if err != nil {
+        return "", err
+    }
+    return fmt.Sprintf("%s$%d$%s$%s", hashScheme, PBKDF2_ITERATIONS,
+        base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// parseHash разбирает хеш пароля
+func parseHash(hash string) (iterations int, salt, key []byte, err error) {
+    parts := strings.Split(hash, "$")
+    if len(parts) != 4 || parts[0] != hashScheme {
+        return 0, nil, nil, ErrBadHash
+    }
+    iterations, err = strconv.Atoi(parts[1])
+    if err != nil || iterations < 1 {
+        return 0, nil, nil, ErrBadHash
+    }
+    if salt, err = base64.RawStdEncoding.DecodeString(parts[2]); err != nil {
+        return 0, nil, nil, ErrBadHash
+    }
+    if key, err = base64.RawStdEncoding.DecodeString(parts[3]); err != nil || len(key) == 0 {
+        return 0, nil, nil, ErrBadHash
+    }
+    return iterations, salt, key, nil
+}
+
+// CheckPassword сравнивает пароль с хешем за постоянное время
+func CheckPassword(hash, password string) bool {
+    iterations, salt, key, err := parseHash(hash)
+    if err != nil {
+        return false
+    }
+    got, err := pbkdf2.Key(sha256.New, password, salt, iterations, len(key))
+    if err != nil {
+        return false
+    }
+    return subtle.ConstantTimeCompare(got, key) == 1
+}
+
+// NewToken создает токен API и его хеш для настроек. Сам токен нигде не хранится.
+func NewToken() (token, hash string, err error) {
+    raw := make([]byte, 32)
+    if _, err := rand.Read(raw); err != nil {
+        return "", "", err
+    }
+    token = base64.RawURLEncoding.EncodeToString(raw)
+    return token, HashToken(token), nil
+}
+
+// HashToken возвращает SHA-256 токена в hex: у токена достаточно случайности, медленный хеш не нужен
+func HashToken(token string) string {
+    sum := sha256.Sum256([]byte(token))
+    return hex.EncodeToString(sum[:])
+}
\ No newline at end of file
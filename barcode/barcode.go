@@ -0,0 +1,170 @@
+package barcode
+
+import (
+    "fmt"
+    "strings"
+    "sync"
+    "time"
+)
+
+// Поддерживаемые символики для проверки кода
+const (
+    EAN13   = "ean13"
+    EAN8    = "ean8"
+    UPCA    = "upca"
+    GTIN14  = "gtin14" // ITF-14 и GS1-128 с 14-значным GTIN
+    CODE39  = "code39"
+    CODE128 = "code128"
+)
+
+// SYMBOLOGIES — все символики, которые можно указать в настройках сканера
+var SYMBOLOGIES = []string{EAN13, EAN8, UPCA, GTIN14, CODE39, CODE128}
+
+// Normalize убирает пробелы и управляющие символы по краям, затем префикс и суффикс сканера
+func Normalize(raw, prefix, suffix string) string {
+    code := strings.TrimSpace(raw)
+    code = strings.TrimPrefix(code, prefix)
+    code = strings.TrimSuffix(code, suffix)
+    return strings.TrimSpace(code)
+}
+
+// Detect возвращает символики, которым соответствует код
+func Detect(code string) []string {
+    var found []string
+    if isDigits(code) {
+        switch len(code) {
+        case 13:
+            if checkDigit(code) {
+                found = append(found, EAN13)
+            }
+        case 8:
+            if checkDigit(code) {
+                found = append(found, EAN8)
+            }
+        case 12:
+            if checkDigit(code) {
+                found = append(found, UPCA)
+            }
+        case 14:
+            if checkDigit(code) {
+                found = append(found, GTIN14)
+            }
+        }
+    }
+    if code39(code) {
+        found = append(found, CODE39)
+    }
+    if code128(code) {
+        found = append(found, CODE128)
+    }
+    return found
+}
+
+// Validate проверяет, что код относится к одной из разрешенных символик.
+// Пустой список разрешает любой непустой код.
+func Validate(code string, allowed []string) error {
+    if code == "" {
+        return fmt.Errorf("пустой штрихкод")
+    }
+    if len(allowed) == 0 {
+        return nil
+    }
+    for _, s := range Detect(code) {
+        for _, a := range allowed {
+            if s == a {
+                return nil
+            }
+        }
+    }
+    return fmt.Errorf("штрихкод %q не соответствует символикам %s", code, strings.Join(allowed, ", "))
+}
+
+// ValidSymbology проверяет имя символики из настроек
+func ValidSymbology(name string) bool {
+    for _, s := range SYMBOLOGIES {
+        if s == name {
+            return true
+        }
+    }
+    return false
+}
+
+func isDigits(code string) bool {
+    if code == "" {
+        return false
+    }
+    for _, c := range code {
+        if c < '0' || c > '9' {
+            return false
+        }
+    }
+    return true
+}
+
+// checkDigit проверяет контрольную цифру GS1 (EAN, UPC, GTIN)
+func checkDigit(code string) bool {
+    sum := 0
+    for i := len(code) - 2; i >= 0; i-- {
+        digit := int(code[i] - '0')
+        if (len(code)-2-i)%2 == 0 {
+            digit *= 3
+        }
+        sum += digit
+    }
+    return (10-sum%10)%10 == int(code[len(code)-1]-'0')
+}
+
+func code39(code string) bool {
+    for _, c := range code {
+        if !(c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || strings.ContainsRune(" -.$/+%", c)) {
+            return false
+        }
+    }
+    return code != ""
+}
+
+func code128(code string) bool {
+    for _, c := range code {
+        if c < 32 || c > 126 {
+            return false
+        }
+    }
+    return code != ""
+}
+
+// pending — последний отсканированный код, ожидающий измерения
+var pending struct {
+    mu   sync.Mutex
+    code string
+    at   time.Time
+}
+
+// SetPending запоминает код для следующего измерения (заменяет предыдущий)
+func SetPending(code string) {
+    pending.mu.Lock()
+    defer pending.mu.Unlock()
+    pending.code = code
+    pending.at = time.Now()
+}
+
+// Pending возвращает ожидающий код, если он не старше maxAge
+func Pending(maxAge time.Duration) string {
+    pending.mu.Lock()
+    defer pending.mu.Unlock()
+    if pending.code == "" || time.Since(pending.at) > maxAge {
+        return ""
+    }
+    return pending.code
+}
+
+// TakePending возвращает ожидающий код и забирает его
+func TakePending(maxAge time.Duration) string {
+    pending.mu.Lock()
+    defer pending.mu.Unlock()
+    code := pending.code
+    pending.code = ""
+    if time.Since(pending.at) > maxAge {
+        return ""
+    }
+    return code
+}
\ No newline at end of file
@@ -9,7 +9,15 @@ const (
     CMD_RESET_SENSORS = 0x93
     CMD_LED_ON        = 0x66
     CMD_LED_OFF       = 0x55
+    CMD_LED_GREEN     = 0x67 // только зеленый светодиод, независимо от режима
+    CMD_LED_RED       = 0x68 // только красный светодиод
     CMD_PING          = 0x77
+    CMD_GET_BUTTON    = 0x87 // число нажатий кнопки с прошлого запроса
+    
+    // Команды весов
+    CMD_SCALE_IDENT  = 0x48
+    CMD_SCALE_WEIGHT = 0x4A
+    CMD_SCALE_TARE   = 0x45
     
     SERVER_PORT = ":8080"
     WEIGHT_THRESHOLD = 1.0
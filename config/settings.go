@@ -0,0 +1,392 @@
+package config
+
+import (
+    "encoding/json"
+    "errors"
+    "os"
+    "sync"
+)
+
+// SETTINGS_FILE — файл настроек станции, ищется в рабочем каталоге программы
+const SETTINGS_FILE = "betelgeuze.json"
+
+// Settings содержит настройки станции, которые можно менять без пересборки
+type Settings struct {
+    StationID  string           `json:"station_id"`
+    WeightUnit string           `json:"weight_unit"` // g, kg, lb — для полей шаблона без unit; вес в измерении всегда в граммах
+    HistoryDir string           `json:"history_dir"`
+    Carrier    string           `json:"carrier"`
+    Carriers   []CarrierProfile `json:"carriers"`
+
+    // Шаблоны вывода результата: имя шаблона станции и набор именованных шаблонов.
+    // StationTemplates позволяет задать шаблон для каждой станции в общем файле настроек.
+    OutputTemplate   string                    `json:"output_template"`
+    StationTemplates map[string]string         `json:"station_templates"`
+    OutputTemplates  map[string]OutputTemplate `json:"output_templates"`
+
+    // Политика запуска измерений
+    Trigger TriggerConfig `json:"trigger"`
+
+    // Проверка измерений перед отправкой в выходы
+    Validation ValidationConfig `json:"validation"`
+
+    // ConfirmBeforeSend — измерение уходит в выходы только после подтверждения оператором
+    ConfirmBeforeSend bool `json:"confirm_before_send"`
+
+    // Сигналы оператору: подсветка Arduino и звук в веб-интерфейсе по событиям измерения
+    Feedback FeedbackConfig `json:"feedback"`
+
+    // Живой вес: опрос весов и поток показаний для табло и интеграций
+    LiveWeight LiveWeightConfig `json:"live_weight"`
+
+    // Выходы, в которые отправляется каждое измерение
+    Outputs []SinkConfig `json:"outputs"`
+
+    // MQTT — брокер для публикации измерений, живого веса и состояния станции
+    MQTT *MQTTConfig `json:"mqtt,omitempty"`
+
+    // Modbus — сервер Modbus TCP для ПЛК конвейера
+    Modbus *ModbusConfig `json:"modbus,omitempty"`
+
+    // Scanner — сканер штрихкодов для привязки измерений к посылкам
+    Scanner *ScannerConfig `json:"scanner,omitempty"`
+
+    // Auth — вход в веб-интерфейс и API. Без этой настройки сервер открыт всем в сети.
+    Auth *AuthConfig `json:"auth,omitempty"`
+
+    // Headless — режим сервера без рабочего стола: выходы paste, clipboard и type отключаются
+    Headless bool `json:"headless"`
+}
+
+// SinkConfig — настройка одного выхода результата измерения
+type SinkConfig struct {
+    Type        string            `json:"type"`                   // paste, clipboard, type, file, webhook, tcp, mqtt, scale, stdout
+    Name        string            `json:"name,omitempty"`         // имя для логов и статуса, по умолчанию Type
+    Triggers    []string          `json:"triggers,omitempty"`     // источники измерений для выхода; пусто — все
+    Format      string            `json:"format,omitempty"`       // "text" (по шаблону) или "json"
+    Path        string            `json:"path,omitempty"`         // file: путь к файлу; scale: ссылка на виртуальный порт
+    URL         string            `json:"url,omitempty"`          // webhook: адрес
+    Headers     map[string]string `json:"headers,omitempty"`      // webhook: дополнительные заголовки
+    Secret      string            `json:"secret,omitempty"`       // webhook: ключ HMAC-SHA256 подписи тела
+    MaxAttempts int               `json:"max_attempts,omitempty"` // webhook: попыток до переноса в мертвые письма
+    Address     string            `json:"address,omitempty"`      // tcp: host:port; scale: COM-порт из пары виртуальных
+    Peer        string            `json:"peer,omitempty"`         // scale: вторая сторона пары, ее открывает старая программа
+    Topic       string            `json:"topic,omitempty"`        // mqtt: топик, по умолчанию <topic_prefix>/measurement
+    TimeoutMs   int               `json:"timeout_ms,omitempty"`
+    Keyboard    *KeyboardConfig   `json:"keyboard,omitempty"`     // paste, type: клавиши и задержки
+
+    // paste, clipboard: вернуть прежнее содержимое буфера обмена после вывода.
+    // По умолчанию включено для paste и выключено для clipboard.
+    RestoreClipboard *bool `json:"restore_clipboard,omitempty"`
+    RestoreDelayMs   int   `json:"restore_delay_ms,omitempty"`  // пауза перед восстановлением, по умолчанию 1000
+}
+
+// MQTTConfig — подключение к брокеру MQTT 3.1.1 или 5.
+// Станция публикует в топики <topic_prefix>/measurement, /weight, /device/<устройство>,
+// /health и /status; в /status брокер сам опубликует "offline", если станция пропадет.
+type MQTTConfig struct {
+    Broker            string         `json:"broker"`                        // tcp://host:1883 или ssl://host:8883
+    ClientID          string         `json:"client_id,omitempty"`           // по умолчанию betelgeuze-<station_id>
+    Username          string         `json:"username,omitempty"`
+    Password          string         `json:"password,omitempty"`
+    ProtocolVersion   int            `json:"protocol_version,omitempty"`    // 4 (MQTT 3.1.1, по умолчанию) или 5
+    TopicPrefix       string         `json:"topic_prefix,omitempty"`        // по умолчанию betelgeuze/<station_id>
+    QoS               int            `json:"qos"`                           // 0, 1 или 2 для измерений и состояния
+    KeepAliveSec      int            `json:"keep_alive_sec,omitempty"`      // по умолчанию 30
+    WeightIntervalMs  int            `json:"weight_interval_ms,omitempty"`  // живой вес не чаще, по умолчанию 1000
+    HealthIntervalSec int            `json:"health_interval_sec,omitempty"` // по умолчанию 30
+    TLS               *MQTTTLSConfig `json:"tls,omitempty"`
+}
+
+// MQTTTLSConfig — сертификаты для подключения к брокеру по TLS
+type MQTTTLSConfig struct {
+    CAFile             string `json:"ca_file,omitempty"`   // корневой сертификат брокера; пусто — системные
+    CertFile           string `json:"cert_file,omitempty"` // клиентский сертификат для взаимной аутентификации
+    KeyFile            string `json:"key_file,omitempty"`
+    InsecureSkipVerify bool   `json:"insecure_skip_verify,omitempty"`
+}
+
+// TriggerConfig — политика запуска измерений. Каждый элемент policy — источник
+// (weight, button, barcode, web, modbus) или сочетание через "+", например "barcode+weight":
+// измерение начнется, когда все события сочетания произойдут в пределах window_sec.
+type TriggerConfig struct {
+    Policy         []string `json:"policy"`
+    WindowSec      int      `json:"window_sec,omitempty"`      // по умолчанию 60
+    StableReadings int      `json:"stable_readings,omitempty"` // weight: показаний подряд без изменения, по умолчанию 1
+    ButtonPollMs   int      `json:"button_poll_ms,omitempty"`  // button: период опроса Arduino, по умолчанию 300
+}
+
+// ValidationConfig — правила проверки измерений. Отклоненное измерение сохраняется в историю
+// со статусом rejected и причинами, но в выходы не отправляется.
+// Габариты в сантиметрах, вес в граммах, плотность в кг/м³; 0 — без ограничения.
+type ValidationConfig struct {
+    Enabled           bool    `json:"enabled"`
+    NonZeroDimensions bool    `json:"non_zero_dimensions"` // с Arduino все габариты должны быть больше нуля
+    FirmwareLimits    bool    `json:"firmware_limits"`     // габариты не больше максимумов прошивки
+    MinLength         int     `json:"min_length,omitempty"`
+    MaxLength         int     `json:"max_length,omitempty"`
+    MinWidth          int     `json:"min_width,omitempty"`
+    MaxWidth          int     `json:"max_width,omitempty"`
+    MinHeight         int     `json:"min_height,omitempty"`
+    MaxHeight         int     `json:"max_height,omitempty"`
+    MinWeight         float64 `json:"min_weight,omitempty"`
+    MaxWeight         float64 `json:"max_weight,omitempty"`
+    MinDensity        float64 `json:"min_density,omitempty"`
+    MaxDensity        float64 `json:"max_density,omitempty"`
+}
+
+// FeedbackConfig — сигналы оператору по событиям: success, rejected, delivery_failed,
+// error, waiting (ожидание штрихкода или других условий запуска) и idle (станция свободна)
+type FeedbackConfig struct {
+    Enabled bool                   `json:"enabled"`
+    Events  map[string]FeedbackCue `json:"events"`
+}
+
+// FeedbackCue — сигнал одного события
+type FeedbackCue struct {
+    Led        string `json:"led"`                   // on, off, blink, blink_fast, none (не менять)
+    Color      string `json:"color,omitempty"`       // green, red; пусто — цвет режима прошивки (0x66)
+    DurationMs int    `json:"duration_ms,omitempty"` // через сколько вернуться к idle; 0 — до следующего события
+    Sound      string `json:"sound"`                 // success, warning, error, none — звук в веб-интерфейсе
+}
+
+// LiveWeightConfig — непрерывный опрос весов. Показания публикуются в поток /api/v1/scale/stream;
+// каждый клиент получает не чаще своего интервала, промежуточные показания медленному клиенту не копятся.
+type LiveWeightConfig struct {
+    IntervalMs       int `json:"interval_ms"`        // период опроса весов, по умолчанию 500
+    StableMs         int `json:"stable_ms"`          // вес стабилен, если не менялся столько миллисекунд, по умолчанию 1000
+    ClientIntervalMs int `json:"client_interval_ms"` // минимальный интервал между сообщениями клиенту, по умолчанию 200
+}
+
+// ModbusConfig — сервер Modbus TCP: регистры с весом, габаритами и состоянием станции,
+// катушки запуска измерения, тарирования и подсветки. Карта регистров — в пакете modbus.
+type ModbusConfig struct {
+    Enabled bool   `json:"enabled"`
+    Address string `json:"address,omitempty"` // по умолчанию :502
+    UnitID  int    `json:"unit_id,omitempty"` // 0 — отвечать на любой идентификатор устройства
+}
+
+// AuthConfig — пользователи веб-интерфейса и токены API для программ.
+// Пароли и токены хранятся только в виде хешей: betelgeuze hash-password и betelgeuze new-token.
+type AuthConfig struct {
+    Enabled      bool          `json:"enabled"`
+    Users        []UserConfig  `json:"users"`
+    Tokens       []TokenConfig `json:"tokens,omitempty"`
+    SessionHours int           `json:"session_hours,omitempty"` // срок сессии после входа, по умолчанию 12 ч
+}
+
+// UserConfig — пользователь веб-интерфейса
+type UserConfig struct {
+    Name         string `json:"name"`
+    PasswordHash string `json:"password_hash"` // pbkdf2-sha256$итерации$соль$хеш
+    Role         string `json:"role"`          // viewer, operator или admin
+}
+
+// TokenConfig — токен API для программ: заголовок Authorization: Bearer <токен>
+type TokenConfig struct {
+    Name      string `json:"name"`
+    TokenHash string `json:"token_hash"` // SHA-256 токена, hex
+    Role      string `json:"role"`
+}
+
+// ScannerConfig — сканер штрихкодов на последовательном порту или USB-CDC.
+// Скан привязывается к следующему измерению или, в режиме attach "current", к только что завершенному.
+type ScannerConfig struct {
+    Enabled         bool     `json:"enabled"`
+    Port            string   `json:"port,omitempty"`              // имя порта; пусто — автопоиск
+    VID             string   `json:"vid,omitempty"`               // закрепить сканер по USB VID/PID (hex)
+    PID             string   `json:"pid,omitempty"`
+    BaudRate        int      `json:"baud_rate,omitempty"`         // по умолчанию 9600
+    StripPrefix     string   `json:"strip_prefix,omitempty"`      // префикс, который сканер добавляет к коду
+    StripSuffix     string   `json:"strip_suffix,omitempty"`
+    Symbologies     []string `json:"symbologies,omitempty"`       // ean13, ean8, upca, gtin14, code39, code128; пусто — любые
+    Attach          string   `json:"attach,omitempty"`            // next (по умолчанию) или current
+    AttachWindowSec int      `json:"attach_window_sec,omitempty"` // current: скан относится к измерению не старше, по умолчанию 10
+    MaxAgeSec       int      `json:"max_age_sec,omitempty"`       // скан ждет измерения не дольше, по умолчанию 300
+    RequireBarcode  bool     `json:"require_barcode"`             // не измерять, пока нет штрихкода
+}
+
+// KeyboardConfig — настройки клавиатурного вывода. В разделителях и терминаторе
+// можно использовать клавиши {TAB}, {ENTER}, {SPACE}; остальной текст печатается как есть.
+type KeyboardConfig struct {
+    FieldSeparators   []string `json:"field_separators,omitempty"` // type: разделитель после каждого поля, последний повторяется; пусто — разделитель шаблона
+    Terminator        string   `json:"terminator,omitempty"`       // клавиши после результата, по умолчанию {ENTER}; {NONE} — ничего
+    PreDelayMs        int      `json:"pre_delay_ms,omitempty"`     // пауза перед вводом
+    KeyDelayMs        int      `json:"key_delay_ms,omitempty"`     // type: пауза между нажатиями
+    TerminatorDelayMs int      `json:"terminator_delay_ms,omitempty"`
+}
+
+// OutputTemplate — шаблон строки результата измерения
+type OutputTemplate struct {
+    Fields           []TemplateField `json:"fields"`
+    WeightOnlyFields []TemplateField `json:"weight_only_fields"` // поля для режима "только весы"; пусто — как Fields
+    Separator        string          `json:"separator"`
+    Prefix           string          `json:"prefix"`
+    Suffix           string          `json:"suffix"`
+}
+
+// TemplateField — одно поле шаблона. Field — имя поля измерения
+// (weight, length, width, height, volume, volumetric_weight, chargeable_weight,
+// barcode, id, station_id, carrier, timestamp) или "text" для постоянного текста.
+type TemplateField struct {
+    Field     string `json:"field"`
+    Unit      string `json:"unit,omitempty"`      // единица веса или длины; пусто — граммы/сантиметры
+    Precision int    `json:"precision,omitempty"` // знаков после запятой
+    Width     int    `json:"width,omitempty"`     // минимальная ширина поля
+    Pad       string `json:"pad,omitempty"`       // символ дополнения слева, по умолчанию пробел
+    Label     string `json:"label,omitempty"`     // текст после значения, например " кг"
+    Text      string `json:"text,omitempty"`      // значение для поля "text"
+    Layout    string `json:"layout,omitempty"`    // формат времени для поля "timestamp"
+}
+
+// Template возвращает шаблон вывода для станции
+func (s *Settings) Template() (OutputTemplate, bool) {
+    name := s.OutputTemplate
+    if station, ok := s.StationTemplates[s.StationID]; ok {
+        name = station
+    }
+    tpl, ok := s.OutputTemplates[name]
+    return tpl, ok
+}
+
+// CarrierProfile — правила перевозчика для расчета объемного и оплачиваемого веса.
+// Объемный вес (кг) = Д × Ш × В (см) / Divisor.
+type CarrierProfile struct {
+    Name              string  `json:"name"`
+    Divisor           float64 `json:"divisor"`
+    RoundingStep      float64 `json:"rounding_step"`       // шаг округления в кг, 0 — без округления
+    RoundingMode      string  `json:"rounding_mode"`       // "up", "nearest" или "down"
+    MinBillableWeight float64 `json:"min_billable_weight"` // минимальный оплачиваемый вес в кг
+}
+
+// CarrierProfile возвращает профиль перевозчика по имени (пустое имя — активный профиль)
+func (s *Settings) CarrierProfile(name string) (CarrierProfile, bool) {
+    if name == "" {
+        name = s.Carrier
+    }
+    for _, c := range s.Carriers {
+        if c.Name == name {
+            return c, true
+        }
+    }
+    return CarrierProfile{}, false
+}
+
+var (
+    settings      = defaultSettings()
+    settingsMutex sync.RWMutex
+)
+
+func defaultSettings() *Settings {
+    stationID, err := os.Hostname()
+    if err != nil || stationID == "" {
+        stationID = "station-1"
+    }
+    return &Settings{
+        StationID:  stationID,
+        WeightUnit: "g",
+        HistoryDir: "data",
+        Carrier:    "express",
+        Carriers: []CarrierProfile{
+            {Name: "express", Divisor: 5000, RoundingStep: 0.5, RoundingMode: "up", MinBillableWeight: 0.5},
+            {Name: "standard", Divisor: 6000, RoundingStep: 1, RoundingMode: "up", MinBillableWeight: 1},
+        },
+        Trigger: TriggerConfig{
+            Policy: []string{"weight", "web", "modbus"},
+        },
+        Validation: ValidationConfig{
+            Enabled:           true,
+            NonZeroDimensions: true,
+            FirmwareLimits:    true,
+        },
+        LiveWeight: LiveWeightConfig{
+            IntervalMs:       500,
+            StableMs:         1000,
+            ClientIntervalMs: 200,
+        },
+        Feedback: FeedbackConfig{
+            Enabled: true,
+            Events: map[string]FeedbackCue{
+                "success":         {Led: "on", Color: "green", DurationMs: 3000, Sound: "success"},
+                "rejected":        {Led: "blink", Color: "red", DurationMs: 5000, Sound: "error"},
+                "delivery_failed": {Led: "blink_fast", Color: "red", DurationMs: 5000, Sound: "warning"},
+                "error":           {Led: "blink", Color: "red", DurationMs: 5000, Sound: "error"},
+                "waiting":         {Led: "none", Sound: "none"},
+                "idle":            {Led: "none", Sound: "none"}, // подсветку ведет прошивка: индикатор режима
+            },
+        },
+        Outputs: []SinkConfig{
+            // Автоматические измерения вставляются в активное окно, ручные из веб-интерфейса — только в буфер
+            {Type: "paste", Triggers: []string{"weight", "button", "barcode", "modbus"}},
+            {Type: "clipboard", Triggers: []string{"web"}},
+        },
+        OutputTemplate: "default",
+        OutputTemplates: map[string]OutputTemplate{
+            // вес:длина:ширина:высота, как раньше вводилось в WMS
+            "default": {
+                Fields: []TemplateField{
+                    {Field: "weight"}, {Field: "length"}, {Field: "width"}, {Field: "height"},
+                },
+                WeightOnlyFields: []TemplateField{{Field: "weight"}},
+                Separator:        ":",
+            },
+            // вес:высота:ширина:длина — порядок старой кнопки веб-интерфейса
+            "weight-hwl": {
+                Fields: []TemplateField{
+                    {Field: "weight"}, {Field: "height"}, {Field: "width"}, {Field: "length"},
+                },
+                WeightOnlyFields: []TemplateField{{Field: "weight"}},
+                Separator:        ":",
+            },
+            // штрихкод;вес;длина;ширина;высота — для WMS, которая принимает посылку целиком
+            "barcode": {
+                Fields: []TemplateField{
+                    {Field: "barcode"}, {Field: "weight"}, {Field: "length"}, {Field: "width"}, {Field: "height"},
+                },
+                WeightOnlyFields: []TemplateField{{Field: "barcode"}, {Field: "weight"}},
+                Separator:        ";",
+            },
+            "kg-chargeable": {
+                Fields: []TemplateField{
+                    {Field: "weight", Unit: "kg", Precision: 3},
+                    {Field: "length"}, {Field: "width"}, {Field: "height"},
+                    {Field: "chargeable_weight", Unit: "kg", Precision: 1},
+                },
+                WeightOnlyFields: []TemplateField{{Field: "weight", Unit: "kg", Precision: 3}},
+                Separator:        ";",
+            },
+        },
+    }
+}
+
+// LoadSettings читает настройки из файла. Отсутствие файла не считается ошибкой —
+// в этом случае используются значения по умолчанию.
+func LoadSettings(path string) error {
+    s := defaultSettings()
+    data, err := os.ReadFile(path)
+    if err != nil {
+        if errors.Is(err, os.ErrNotExist) {
+            setSettings(s)
+            return nil
+        }
+        return err
+    }
+    if err := json.Unmarshal(data, s); err != nil {
+        return err
+    }
+    setSettings(s)
+    return nil
+}
+
+// Get возвращает текущие настройки станции
+func Get() *Settings {
+    settingsMutex.RLock()
+    defer settingsMutex.RUnlock()
+    return settings
+}
+
+func setSettings(s *Settings) {
+    settingsMutex.Lock()
+    defer settingsMutex.Unlock()
+    settings = s
+}
\ No newline at end of file
@@ -25,7 +25,7 @@ func ConnectToArduino() (*types.ArduinoPort, error) {
     fmt.Println("🔍 Searching for Arduino via PING...")
 
     for _, port := range ports {
-        if !port.IsUSB {
+        if !port.IsUSB || Excluded(port.Name) {
             continue
         }
 
@@ -76,6 +76,34 @@ func SendCommandToArduino(a *types.ArduinoPort, cmd byte) {
     time.Sleep(200 * time.Millisecond)
 }
 
+// Цвета подсветки. Пустой цвет — команда 0x66, цвет которой зависит от режима прошивки:
+// красный в обычном режиме, зеленый в режиме "только вес".
+const (
+    LED_GREEN = "green"
+    LED_RED   = "red"
+)
+
+// SetLED включает или выключает подсветку Arduino без паузы после команды,
+// чтобы из нее можно было собрать мигание
+func SetLED(a *types.ArduinoPort, on bool, color string) error {
+    a.Mutex.Lock()
+    defer a.Mutex.Unlock()
+
+    cmd := byte(config.CMD_LED_OFF)
+    if on {
+        switch color {
+        case LED_GREEN:
+            cmd = config.CMD_LED_GREEN
+        case LED_RED:
+            cmd = config.CMD_LED_RED
+        default:
+            cmd = config.CMD_LED_ON
+        }
+    }
+    _, err := a.Port.Write([]byte{cmd})
+    return err
+}
+
 func GetDimensionsFromArduino(a *types.ArduinoPort) (int, int, int) {
     // Очищаем буфер перед чтением
     flush(a.Port)
@@ -106,24 +134,135 @@ func GetDimensionsFromArduino(a *types.ArduinoPort) (int, int, int) {
     logging.BroadcastLog(fmt.Sprintf("Всего получено: %s", totalDataStr), "arduino")
     
     if len(allData) < 41 {
-        logging.BroadcastLog("Недостаточно данных для парсинга размеров", "arduino")
+        logging.Warn("Недостаточно данных для парсинга размеров", "arduino")
         return 0, 0, 0
     }
     
     // Ищем начало валидных данных
     validData := findValidDataPattern(allData)
     if len(validData) < 41 {
-        logging.BroadcastLog("Не найден валидный паттерн данных", "arduino")
+        logging.Warn("Не найден валидный паттерн данных", "arduino")
         return 0, 0, 0
     }
     
     length, width, height := parseDimensionsData(validData)
+    a.MaxLength, a.MaxWidth, a.MaxHeight = parseLimitsData(validData)
     logging.BroadcastLog(fmt.Sprintf("Распознаны размеры: Длина=%d, Ширина=%d, Высота=%d", length, width, height), "arduino")
     
     return length, width, height
 }
 
+// Ошибки команд Arduino
+var (
+    ErrUnknownCommand = errors.New("неизвестная команда")
+    ErrInvalidValue   = errors.New("неверное значение (1-255)")
+    ErrNoReply        = errors.New("нет ответа от Arduino")
+)
+
+// ARDUINO_COMMANDS — команды Arduino без ответа
+var ARDUINO_COMMANDS = map[string]byte{
+    "start":         config.CMD_START,
+    "reset_sensors": config.CMD_RESET_SENSORS,
+    "led_on":        config.CMD_LED_ON,
+    "led_off":       config.CMD_LED_OFF,
+}
+
+// MAX_COMMANDS — команды установки максимумов габаритов по осям
+var MAX_COMMANDS = map[string]byte{
+    "length": config.CMD_SET_LENGTH_MAX,
+    "width":  config.CMD_SET_WIDTH_MAX,
+    "height": config.CMD_SET_TOP_MAX,
+}
+
+// SendNamedCommand отправляет Arduino команду из ARDUINO_COMMANDS
+func SendNamedCommand(a *types.ArduinoPort, name string) error {
+    cmd, ok := ARDUINO_COMMANDS[name]
+    if !ok {
+        return fmt.Errorf("%w: %s", ErrUnknownCommand, name)
+    }
+    a.Mutex.Lock()
+    defer a.Mutex.Unlock()
+    SendCommandToArduino(a, cmd)
+    return nil
+}
+
+// Ping проверяет связь с Arduino и возвращает его ответ
+func Ping(a *types.ArduinoPort) (string, error) {
+    a.Mutex.Lock()
+    defer a.Mutex.Unlock()
+    return ping(a)
+}
+
+// SetMaximum задает в прошивке максимум габарита по оси length, width или height
+func SetMaximum(a *types.ArduinoPort, axis string, value int) error {
+    a.Mutex.Lock()
+    defer a.Mutex.Unlock()
+    return setMaximum(a, axis, value)
+}
+
+func ping(arduino *types.ArduinoPort) (string, error) {
+    flush(arduino.Port)
+    SendCommandToArduino(arduino, config.CMD_PING)
+    logging.BroadcastLog("Отправлена команда PING (0x77)", "arduino")
+    
+    // Читаем данные в течение ~500ms
+    arduino.Port.SetReadTimeout(50 * time.Millisecond)
+    allData := make([]byte, 0, 200)
+    
+    startTime := time.Now()
+    for time.Since(startTime) < 700*time.Millisecond {
+        buf := make([]byte, 20)
+        n, err := arduino.Port.Read(buf)
+        if err == nil && n > 0 {
+            allData = append(allData, buf[:n]...)
+            
+            // Логируем полученные данные
+            hexStr := make([]string, n)
+            decStr := make([]string, n)
+            for i := 0; i < n; i++ {
+                hexStr[i] = fmt.Sprintf("0x%02X", buf[i])
+                decStr[i] = fmt.Sprintf("%d", buf[i])
+            }
+            logging.BroadcastLog(fmt.Sprintf("PING ответ: %d байт HEX:[%s] DEC:[%s] ASCII:%s", 
+                n, strings.Join(hexStr, ","), strings.Join(decStr, ","), string(buf[:n])), "arduino")
+        }
+        time.Sleep(10 * time.Millisecond)
+    }
+    
+    if len(allData) == 0 {
+        logging.Error("Нет ответа от Arduino на PING", "arduino")
+        return "", ErrNoReply
+    }
+    return strings.TrimSpace(string(allData)), nil
+}
+
+func setMaximum(a *types.ArduinoPort, axis string, value int) error {
+    cmd, ok := MAX_COMMANDS[axis]
+    if !ok {
+        return fmt.Errorf("%w: %s", ErrUnknownCommand, axis)
+    }
+    if value < 1 || value > 255 {
+        return ErrInvalidValue
+    }
+    if _, err := a.Port.Write([]byte{cmd, byte(value)}); err != nil {
+        return err
+    }
+    // Прошивка подтвердит значение в следующем ответе на 0x89, до этого показываем заданное
+    switch axis {
+    case "length":
+        a.MaxLength = value
+    case "width":
+        a.MaxWidth = value
+    case "height":
+        a.MaxHeight = value
+    }
+    return nil
+}
+
 func ExecuteArduinoCommand(arduino *types.ArduinoPort, command string) string {
+    arduino.Mutex.Lock()
+    defer arduino.Mutex.Unlock()
+    
     parts := strings.Split(command, ":")
     cmd := parts[0]
     
@@ -133,51 +272,20 @@ func ExecuteArduinoCommand(arduino *types.ArduinoPort, command string) string {
         return "Команда START отправлена"
     
     case "ping":
-        flush(arduino.Port)
-        SendCommandToArduino(arduino, config.CMD_PING)
-        logging.BroadcastLog("Отправлена команда PING (0x77)", "arduino")
-        
-        // Читаем данные в течение ~500ms
-        arduino.Port.SetReadTimeout(50 * time.Millisecond)
-        allData := make([]byte, 0, 200)
-        
-        startTime := time.Now()
-        for time.Since(startTime) < 700*time.Millisecond {
-            buf := make([]byte, 20)
-            n, err := arduino.Port.Read(buf)
-            if err == nil && n > 0 {
-                allData = append(allData, buf[:n]...)
-                
-                // Логируем полученные данные
-                hexStr := make([]string, n)
-                decStr := make([]string, n)
-                for i := 0; i < n; i++ {
-                    hexStr[i] = fmt.Sprintf("0x%02X", buf[i])
-                    decStr[i] = fmt.Sprintf("%d", buf[i])
-                }
-                logging.BroadcastLog(fmt.Sprintf("PING ответ: %d байт HEX:[%s] DEC:[%s] ASCII:%s", 
-                    n, strings.Join(hexStr, ","), strings.Join(decStr, ","), string(buf[:n])), "arduino")
-            }
-            time.Sleep(10 * time.Millisecond)
-        }
-        
-        if len(allData) == 0 {
-            logging.BroadcastLog("Нет ответа от Arduino на PING", "arduino")
+        response, err := ping(arduino)
+        if err != nil {
             return "Нет ответа от Arduino"
         }
         
-        // Преобразуем полученные данные в строку
-        response := string(allData)
-        
         // Ищем "OK" в ответе
         if strings.Contains(response, "OK") {
             logging.BroadcastLog("Arduino ответил корректно: OK", "arduino")
             return "Arduino ответил: OK"
         } else {
             // Если "OK" не найдено, показываем что получили
-            logging.BroadcastLog(fmt.Sprintf("Arduino ответил нестандартно: %s", strings.TrimSpace(response)), "arduino")
+            logging.Warn(fmt.Sprintf("Arduino ответил нестандартно: %s", response), "arduino")
             return fmt.Sprintf("Arduino ответил: %s (%d байт, 'OK' не найдено)", 
-                strings.TrimSpace(response), len(allData))
+                response, len(response))
         }
     
     case "reset_sensors":
@@ -197,38 +305,20 @@ func ExecuteArduinoCommand(arduino *types.ArduinoPort, command string) string {
         length, width, height := GetDimensionsFromArduino(arduino)
         return fmt.Sprintf("Размеры: Д=%d, Ш=%d, В=%d", length, width, height)
     
-    case "set_top_max":
+    case "set_top_max", "set_width_max", "set_length_max":
         if len(parts) < 2 {
             return "Не указано значение"
         }
         value, err := strconv.Atoi(parts[1])
-        if err != nil || value < 1 || value > 255 {
-            return "Неверное значение (1-255)"
-        }
-        arduino.Port.Write([]byte{config.CMD_SET_TOP_MAX, byte(value)})
-        return fmt.Sprintf("Максимальная высота установлена: %d", value)
-    
-    case "set_width_max":
-        if len(parts) < 2 {
-            return "Не указано значение"
-        }
-        value, err := strconv.Atoi(parts[1])
-        if err != nil || value < 1 || value > 255 {
+        if err != nil {
             return "Неверное значение (1-255)"
         }
-        arduino.Port.Write([]byte{config.CMD_SET_WIDTH_MAX, byte(value)})
-        return fmt.Sprintf("Максимальная ширина установлена: %d", value)
-    
-    case "set_length_max":
-        if len(parts) < 2 {
-            return "Не указано значение"
-        }
-        value, err := strconv.Atoi(parts[1])
-        if err != nil || value < 1 || value > 255 {
+        axes := map[string]string{"set_top_max": "height", "set_width_max": "width", "set_length_max": "length"}
+        names := map[string]string{"set_top_max": "высота", "set_width_max": "ширина", "set_length_max": "длина"}
+        if err := setMaximum(arduino, axes[cmd], value); err != nil {
             return "Неверное значение (1-255)"
         }
-        arduino.Port.Write([]byte{config.CMD_SET_LENGTH_MAX, byte(value)})
-        return fmt.Sprintf("Максимальная длина установлена: %d", value)
+        return fmt.Sprintf("Максимальная %s установлена: %d", names[cmd], value)
     
     default:
         return "Неизвестная команда"
@@ -302,4 +392,50 @@ func parseDimensionsData(buf []byte) (int, int, int) {
     }
     
     return length_box, width_box, height_box
+}
+
+// parseLimitsData возвращает максимумы габаритов прошивки (блоки 4-6 ответа 0x89)
+func parseLimitsData(buf []byte) (int, int, int) {
+    var widthMax, topMax, lengthMax int
+    for i := 4; i <= 6 && i*4+3 < len(buf); i++ {
+        block := buf[i*4 : i*4+4]
+        if block[0] != 0x2D || block[3] != 0x7B {
+            continue
+        }
+        switch block[1] {
+        case 0x0B:
+            widthMax = int(block[2])
+        case 0x16:
+            topMax = int(block[2])
+        case 0x21:
+            lengthMax = int(block[2])
+        }
+    }
+    return lengthMax, widthMax, topMax
+}
+
+// ReadButtonPresses возвращает число нажатий кнопки Arduino с прошлого запроса.
+// Ответ прошивки: маркер 0x87 и число нажатий.
+func ReadButtonPresses(a *types.ArduinoPort) (int, error) {
+    a.Mutex.Lock()
+    defer a.Mutex.Unlock()
+
+    flush(a.Port)
+    if _, err := a.Port.Write([]byte{config.CMD_GET_BUTTON}); err != nil {
+        return 0, fmt.Errorf("ошибка записи команды: %v", err)
+    }
+    a.Port.SetReadTimeout(50 * time.Millisecond)
+    data := make([]byte, 0, 8)
+    start := time.Now()
+    for time.Since(start) < 300*time.Millisecond {
+        buf := make([]byte, 8)
+        n, _ := a.Port.Read(buf)
+        data = append(data, buf[:n]...)
+        for i := 0; i+1 < len(data); i++ {
+            if data[i] == config.CMD_GET_BUTTON {
+                return int(data[i+1]), nil
+            }
+        }
+    }
+    return 0, errors.New("нет ответа на запрос кнопки")
 }
\ No newline at end of file
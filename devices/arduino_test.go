@@ -0,0 +1,48 @@
+package devices
+
+import "testing"
+
+// dimensionsReply — ответ прошивки на 0x89: 10 блоков 0x2D <датчик> <значение> 0x7B и завершающий байт.
+// Блоки 0-3 — показания датчиков, 4-6 — максимумы прошивки, 7-9 — габариты объекта.
+func dimensionsReply(widthMax, topMax, lengthMax, width, height, length byte) []byte {
+    blocks := [][3]byte{
+        {0x0B, 12}, {0x16, 34}, {0x21, 56}, {0x0B, 12},
+        {0x0B, widthMax}, {0x16, topMax}, {0x21, lengthMax},
+        {0x0B, width}, {0x16, height}, {0x21, length},
+    }
+    var reply []byte
+    for _, b := range blocks {
+        reply = append(reply, 0x2D, b[0], b[1], 0x7B)
+    }
+    return append(reply, 0x0A)
+}
+
+func TestParseDimensionsReply(t *testing.T) {
+    reply := dimensionsReply(60, 40, 80, 20, 10, 30)
+    // Перед ответом в буфере порта остались байты прошлой команды
+    data := append([]byte{0x66, 0x2D, 0x00}, reply...)
+
+    valid := findValidDataPattern(data)
+    if len(valid) != 41 || valid[0] != 0x2D {
+        t.Fatalf("ответ не найден: % X", valid)
+    }
+    if l, w, h := parseDimensionsData(valid); l != 30 || w != 20 || h != 10 {
+        t.Errorf("габариты %d×%d×%d, ожидалось 30×20×10", l, w, h)
+    }
+    if l, w, h := parseLimitsData(valid); l != 80 || w != 60 || h != 40 {
+        t.Errorf("максимумы прошивки %d×%d×%d, ожидалось 80×60×40", l, w, h)
+    }
+}
+
+func TestParseLimitsSkipsBrokenBlocks(t *testing.T) {
+    reply := dimensionsReply(60, 40, 80, 20, 10, 30)
+    // Поврежденный блок максимума высоты: максимум остается неизвестным
+    reply[5*4+3] = 0x00
+    if l, w, h := parseLimitsData(reply); l != 80 || w != 60 || h != 0 {
+        t.Errorf("максимумы прошивки %d×%d×%d, ожидалось 80×60×0", l, w, h)
+    }
+    // Короткий ответ старой прошивки без блоков максимумов
+    if l, w, h := parseLimitsData(reply[:16]); l != 0 || w != 0 || h != 0 {
+        t.Errorf("максимумы из короткого ответа: %d×%d×%d", l, w, h)
+    }
+}
\ No newline at end of file
@@ -0,0 +1,42 @@
+package devices
+
+import (
+    "path/filepath"
+    "strings"
+    "sync"
+)
+
+// Порты, которые автопоиск весов, Arduino и сканера не открывает,
+// например обе стороны пары виртуальных портов эмулятора весов
+var (
+    excludedMu    sync.Mutex
+    excludedPorts []string
+)
+
+// ExcludePorts исключает порты из автопоиска устройств. Пустые имена пропускаются.
+func ExcludePorts(names ...string) {
+    excludedMu.Lock()
+    defer excludedMu.Unlock()
+    for _, name := range names {
+        if name != "" {
+            excludedPorts = append(excludedPorts, name)
+        }
+    }
+}
+
+// Excluded сообщает, исключен ли порт из автопоиска. Ссылка (например, на псевдотерминал
+// эмулятора) сравнивается и по своему имени, и по порту, на который она указывает.
+// Имена COM-портов Windows сравниваются без учета регистра.
+func Excluded(name string) bool {
+    excludedMu.Lock()
+    defer excludedMu.Unlock()
+    for _, excluded := range excludedPorts {
+        if strings.EqualFold(excluded, name) {
+            return true
+        }
+        if target, err := filepath.EvalSymlinks(excluded); err == nil && target == name {
+            return true
+        }
+    }
+    return false
+}
\ No newline at end of file
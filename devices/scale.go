@@ -4,11 +4,13 @@ import (
     "context"
     "errors"
     "fmt"
+    "math"
     "runtime"
     "strings"
     "sync"
     "time"
     
+    "betelgeuze-measure-system-main/config"
     "betelgeuze-measure-system-main/types"
     
     "go.bug.st/serial"
@@ -90,6 +92,10 @@ func ConnectToScale() (*types.ScalePort, error) {
         if runtime.GOOS != "windows" && strings.HasPrefix(name, "COM") {
             continue
         }
+        // Виртуальные порты эмулятора весов
+        if Excluded(name) {
+            continue
+        }
         validPorts = append(validPorts, name)
     }
     
@@ -306,7 +312,7 @@ func testScaleCommunicationWithContext(ctx context.Context, conn serial.Port, po
     
     // Отправляем команду
     fmt.Printf("    📤 Отправляем команду 0x48 с конфигурацией %s...\n", configName)
-    _, writeErr := conn.Write([]byte{0x48})
+    _, writeErr := conn.Write([]byte{config.CMD_SCALE_IDENT})
     if writeErr != nil {
         return nil, fmt.Errorf("ошибка записи: %v", writeErr)
     }
@@ -394,7 +400,10 @@ func testScaleCommunicationWithContext(ctx context.Context, conn serial.Port, po
 
 // ReadWeight читает вес с весов (оригинальная функция)
 func ReadWeight(p *types.ScalePort) (float64, error) {
-    _, err := p.Connection.Write([]byte{0x4A})
+    p.Mutex.Lock()
+    defer p.Mutex.Unlock()
+    
+    _, err := p.Connection.Write([]byte{config.CMD_SCALE_WEIGHT})
     if err != nil {
         return 0, fmt.Errorf("ошибка записи команды: %v", err)
     }
@@ -405,15 +414,49 @@ func ReadWeight(p *types.ScalePort) (float64, error) {
     if err != nil || n != 5 {
         return 0, errors.New("не удалось прочитать вес")
     }
+    return DecodeWeight(buf), nil
+}
+
+// DecodeWeight разбирает 5-байтовый ответ весов на команду 0x4A: состояние (128 — вес готов),
+// цена деления (0 — 1 г, 4 — 10 г) и вес младшим байтом вперед
+func DecodeWeight(buf []byte) float64 {
     if buf[0] != 128 {
-        return 0, nil
+        return 0
     }
     switch buf[1] {
     case 0:
-        return float64(buf[3])*256 + float64(buf[2]), nil
+        return float64(buf[3])*256 + float64(buf[2])
     case 4:
-        return (float64(buf[3])*256 + float64(buf[2])) * 10, nil
+        return (float64(buf[3])*256 + float64(buf[2])) * 10
     default:
-        return 0, nil
+        return 0
+    }
+}
+
+// EncodeWeight формирует ответ на команду 0x4A так, чтобы DecodeWeight вернул вес.
+// Вес больше 65535 г передается с ценой деления 10 г.
+func EncodeWeight(weight float64) []byte {
+    grams := math.Max(math.Round(weight), 0)
+    division := byte(0)
+    if grams > 0xFFFF {
+        grams = math.Min(math.Round(grams/10), 0xFFFF)
+        division = 4
     }
+    value := uint16(grams)
+    return []byte{128, division, byte(value), byte(value >> 8), 0}
+}
+
+// SCALE_IDENT_REPLY — ответ на команду 0x48, по которому ConnectToScale узнает весы
+var SCALE_IDENT_REPLY = []byte{128, 192}
+
+// TareScale обнуляет показания весов с учетом тары на платформе
+func TareScale(p *types.ScalePort) error {
+    p.Mutex.Lock()
+    defer p.Mutex.Unlock()
+    
+    if _, err := p.Connection.Write([]byte{config.CMD_SCALE_TARE}); err != nil {
+        return fmt.Errorf("ошибка записи команды: %v", err)
+    }
+    time.Sleep(200 * time.Millisecond)
+    return nil
 }
\ No newline at end of file
@@ -0,0 +1,101 @@
+package devices
+
+import (
+    "bufio"
+    "errors"
+    "fmt"
+    "strings"
+
+    "betelgeuze-measure-system-main/types"
+
+    "go.bug.st/serial"
+    "go.bug.st/serial/enumerator"
+)
+
+// SCANNER_VENDORS — USB VID производителей сканеров штрихкодов для автопоиска.
+// Мосты USB-UART общего назначения (CH340, CP210x, FTDI) сюда не входят: на них же стоят Arduino
+// и весы. Сканер на таком мосте задается явно через port или vid/pid.
+var SCANNER_VENDORS = map[string]string{
+    "0C2E": "Honeywell",
+    "05E0": "Zebra (Symbol)",
+    "05F9": "Datalogic",
+    "1EAB": "Newland",
+    "0536": "Hand Held Products",
+    "2DD6": "Mindeo",
+}
+
+// ConnectToScanner открывает сканер штрихкодов в режиме USB-CDC или последовательного порта.
+// Порт ищется по имени, затем по VID/PID, а без них — по известным производителям сканеров.
+// Порты из exclude (Arduino, весы) и исключенные через ExcludePorts пропускаются.
+func ConnectToScanner(portName, vid, pid string, baudRate int, exclude []string) (*types.ScannerPort, error) {
+    if baudRate == 0 {
+        baudRate = 9600
+    }
+    if portName == "" {
+        ports, err := enumerator.GetDetailedPortsList()
+        if err != nil {
+            return nil, err
+        }
+        portName, err = findScannerPort(ports, vid, pid, exclude)
+        if err != nil {
+            return nil, err
+        }
+    }
+
+    conn, err := serial.Open(portName, &serial.Mode{BaudRate: baudRate})
+    if err != nil {
+        return nil, fmt.Errorf("не удалось открыть %s: %v", portName, err)
+    }
+    return &types.ScannerPort{Port: conn, PortName: portName}, nil
+}
+
+func findScannerPort(ports []*enumerator.PortDetails, vid, pid string, exclude []string) (string, error) {
+    for _, port := range ports {
+        if !port.IsUSB || contains(exclude, port.Name) || Excluded(port.Name) {
+            continue
+        }
+        if vid != "" {
+            if strings.EqualFold(port.VID, vid) && (pid == "" || strings.EqualFold(port.PID, pid)) {
+                return port.Name, nil
+            }
+            continue
+        }
+        if vendor, ok := SCANNER_VENDORS[strings.ToUpper(port.VID)]; ok {
+            fmt.Printf("🔍 Найден сканер %s на %s (VID: %s, PID: %s)\n", vendor, port.Name, port.VID, port.PID)
+            return port.Name, nil
+        }
+    }
+    if vid != "" {
+        return "", fmt.Errorf("сканер VID %s PID %s не найден", vid, pid)
+    }
+    return "", errors.New("сканер штрихкодов не найден")
+}
+
+func contains(list []string, value string) bool {
+    for _, v := range list {
+        if v == value {
+            return true
+        }
+    }
+    return false
+}
+
+// ReadBarcodes читает коды сканера построчно (CR или LF в конце кода) до ошибки порта
+func ReadBarcodes(s *types.ScannerPort, handle func(code string)) error {
+    reader := bufio.NewReader(s.Port)
+    var line []byte
+    for {
+        b, err := reader.ReadByte()
+        if err != nil {
+            return err
+        }
+        if b != '\r' && b != '\n' {
+            line = append(line, b)
+            continue
+        }
+        if len(line) > 0 {
+            handle(string(line))
+            line = line[:0]
+        }
+    }
+}
\ No newline at end of file
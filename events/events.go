@@ -0,0 +1,162 @@
+package events
+
+import (
+    "encoding/json"
+    "strings"
+    "sync"
+    "time"
+
+    "betelgeuze-measure-system-main/logging"
+)
+
+// Типы событий станции
+const (
+    MeasurementCompleted = "measurement.completed" // измерение выполнено: отправлено, ждет подтверждения или отклонено
+    MeasurementFailed    = "measurement.failed"    // измерение не удалось выполнить
+    MeasurementUpdated   = "measurement.updated"   // оператор подтвердил или отменил измерение
+    DeviceConnected      = "device.connected"
+    DeviceDisconnected   = "device.disconnected"
+    StateChanged         = "state.changed" // сменился режим автоматических измерений
+    StreamGap            = "stream.gap"    // часть событий после Last-Event-ID уже вытеснена из буфера
+)
+
+// REPLAY_SIZE — сколько последних событий хранится для продолжения потока после переподключения
+const REPLAY_SIZE = 500
+
+// SUBSCRIBER_BUFFER — сколько событий может ждать отправки одному клиенту
+const SUBSCRIBER_BUFFER = 64
+
+// Event — событие станции. Data сериализуется при публикации: последующие
+// изменения объекта не меняют уже опубликованное событие.
+type Event struct {
+    ID   uint64          `json:"id"`
+    Type string          `json:"type"`
+    Time time.Time       `json:"time"`
+    Data json.RawMessage `json:"data"`
+}
+
+// Subscription получает события выбранных типов. Если клиент не успевает их забирать,
+// канал закрывается: клиент переподключается с Last-Event-ID и получает пропущенное из буфера.
+type Subscription struct {
+    C      chan Event
+    topics []string
+    closed bool
+}
+
+// Matches проверяет тип события по фильтру: точное имя, префикс до точки ("measurement")
+// или "*"; пустой фильтр пропускает все
+func Matches(topics []string, eventType string) bool {
+    if len(topics) == 0 {
+        return true
+    }
+    for _, t := range topics {
+        if t == "*" || t == eventType || strings.HasPrefix(eventType, t+".") {
+            return true
+        }
+    }
+    return false
+}
+
+// Bus раздает события подписчикам и хранит последние для повтора
+type Bus struct {
+    mu     sync.Mutex
+    nextID uint64
+    replay []Event // кольцевой буфер в порядке публикации
+    start  int
+    subs   map[*Subscription]struct{}
+}
+
+// NewBus создает шину событий
+func NewBus() *Bus {
+    return &Bus{nextID: 1, subs: make(map[*Subscription]struct{})}
+}
+
+// Publish публикует событие. Не блокируется на медленных подписчиках.
+func (b *Bus) Publish(eventType string, data interface{}) Event {
+    payload, err := json.Marshal(data)
+    if err != nil {
+        payload = []byte("null")
+    }
+
+    b.mu.Lock()
+    e := Event{ID: b.nextID, Type: eventType, Time: time.Now().UTC(), Data: payload}
+    b.nextID++
+    if len(b.replay) < REPLAY_SIZE {
+        b.replay = append(b.replay, e)
+    } else {
+        b.replay[b.start] = e
+        b.start = (b.start + 1) % REPLAY_SIZE
+    }
+    var overflowed int
+    for s := range b.subs {
+        if !Matches(s.topics, eventType) {
+            continue
+        }
+        select {
+        case s.C <- e:
+        default:
+            b.drop(s)
+            overflowed++
+        }
+    }
+    b.mu.Unlock()
+
+    if overflowed > 0 {
+        logging.Warn("События: медленный клиент отключен, он продолжит поток с последнего полученного события", "system")
+    }
+    return e
+}
+
+// Subscribe подписывает клиента на события типов topics. lastID > 0 — последнее событие,
+// которое клиент уже получил: события после него возвращаются в replay. gap сообщает, что
+// часть событий после lastID уже вытеснена из буфера или lastID выдан до перезапуска станции.
+func (b *Bus) Subscribe(topics []string, lastID uint64) (s *Subscription, replay []Event, gap bool) {
+    s = &Subscription{C: make(chan Event, SUBSCRIBER_BUFFER), topics: topics}
+
+    b.mu.Lock()
+    defer b.mu.Unlock()
+    if lastID > 0 {
+        if lastID >= b.nextID {
+            lastID, gap = 0, true
+        } else if len(b.replay) > 0 && b.replay[b.start].ID > lastID+1 {
+            gap = true
+        }
+        for i := range b.replay {
+            e := b.replay[(b.start+i)%len(b.replay)]
+            if e.ID > lastID && Matches(topics, e.Type) {
+                replay = append(replay, e)
+            }
+        }
+    }
+    b.subs[s] = struct{}{}
+    return s, replay, gap
+}
+
+// Unsubscribe отписывает клиента
+func (b *Bus) Unsubscribe(s *Subscription) {
+    b.mu.Lock()
+    b.drop(s)
+    b.mu.Unlock()
+}
+
+// drop удаляет подписчика и закрывает его канал. Вызывается под блокировкой.
+func (b *Bus) drop(s *Subscription) {
+    if s.closed {
+        return
+    }
+    delete(b.subs, s)
+    s.closed = true
+    close(s.C)
+}
+
+var defaultBus = NewBus()
+
+// Default возвращает шину событий станции
+func Default() *Bus {
+    return defaultBus
+}
+
+// Publish публикует событие в шину станции
+func Publish(eventType string, data interface{}) {
+    defaultBus.Publish(eventType, data)
+}
\ No newline at end of file
@@ -0,0 +1,41 @@
+package events
+
+import (
+    "time"
+
+    "betelgeuze-measure-system-main/types"
+)
+
+// Device — данные событий device.connected и device.disconnected
+type Device struct {
+    Device string `json:"device"` // arduino, scale или scanner
+    Port   string `json:"port,omitempty"`
+}
+
+// Watch следит за подключением устройств и публикует события при изменениях.
+// Начальное состояние не публикуется: его отдает /api/v1/status.
+func Watch(state *types.AppState) {
+    go func() {
+        status := state.Status
+        arduino, scale, scanner := status.ArduinoConnected, status.ScaleConnected, status.ScannerConnected
+        for {
+            time.Sleep(time.Second)
+            status = state.Status
+            arduino = watchDevice("arduino", arduino, status.ArduinoConnected, status.ArduinoPort)
+            scale = watchDevice("scale", scale, status.ScaleConnected, status.ScalePort)
+            scanner = watchDevice("scanner", scanner, status.ScannerConnected, status.ScannerPort)
+        }
+    }()
+}
+
+func watchDevice(name string, was, connected bool, port string) bool {
+    if connected == was {
+        return connected
+    }
+    if connected {
+        Publish(DeviceConnected, Device{Device: name, Port: port})
+    } else {
+        Publish(DeviceDisconnected, Device{Device: name, Port: port})
+    }
+    return connected
+}
\ No newline at end of file
@@ -0,0 +1,98 @@
+package export
+
+import (
+    "flag"
+    "fmt"
+    "os"
+    "path/filepath"
+    "strings"
+
+    "betelgeuze-measure-system-main/config"
+    "betelgeuze-measure-system-main/history"
+    "betelgeuze-measure-system-main/utils"
+)
+
+// RunCLI выполняет подкоманду "export": выгрузку истории в файл без запуска станции.
+// Пример: mainV2 export -format xlsx -from 2025-01-01 -to 2025-01-31 -out january.xlsx
+func RunCLI(args []string) int {
+    fs := flag.NewFlagSet("export", flag.ContinueOnError)
+    format := fs.String("format", FormatCSV, "формат файла: csv или xlsx")
+    from := fs.String("from", "", "начало периода (2006-01-02 или RFC3339)")
+    to := fs.String("to", "", "конец периода (2006-01-02 или RFC3339)")
+    station := fs.String("station", "", "ID станции (по умолчанию все станции)")
+    columns := fs.String("columns", "", "колонки через запятую: "+strings.Join(ColumnNames(), ","))
+    weightUnit := fs.String("weight-unit", "g", "единица веса: g, kg, lb, oz")
+    dimensionUnit := fs.String("dimension-unit", "cm", "единица длины: mm, cm, m, in")
+    dir := fs.String("history", "", "каталог истории (по умолчанию из настроек)")
+    out := fs.String("out", "", "файл результата (по умолчанию measurements.<формат>)")
+    if err := fs.Parse(args); err != nil {
+        return 2
+    }
+
+    if err := config.LoadSettings(config.SETTINGS_FILE); err != nil {
+        fmt.Fprintf(os.Stderr, "Ошибка чтения настроек: %v\n", err)
+        return 1
+    }
+    if *dir == "" {
+        *dir = config.Get().HistoryDir
+    }
+    if *out == "" {
+        *out = "measurements." + *format
+    }
+
+    var q history.Query
+    var err error
+    if q.From, err = utils.ParseTimeParam(*from, false); err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        return 2
+    }
+    if q.To, err = utils.ParseTimeParam(*to, true); err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        return 2
+    }
+    q.StationID = *station
+
+    opts := Options{WeightUnit: *weightUnit, DimensionUnit: *dimensionUnit}
+    if *columns != "" {
+        opts.Columns = strings.Split(*columns, ",")
+    }
+    if err := Validate(opts); err != nil {
+        fmt.Fprintln(os.Stderr, err)
+        return 2
+    }
+
+    // Выгрузка может идти рядом с работающей станцией: журнал только читаем
+    store, err := history.OpenReadOnly(*dir)
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "Ошибка открытия истории %s: %v\n", *dir, err)
+        return 1
+    }
+    defer store.Close()
+
+    // Пишем во временный файл, чтобы при ошибке не оставить половину выгрузки
+    tmp := *out + ".tmp"
+    f, err := os.Create(tmp)
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "Ошибка создания файла: %v\n", err)
+        return 1
+    }
+    if err := Write(f, *format, store, q, opts); err != nil {
+        f.Close()
+        os.Remove(tmp)
+        fmt.Fprintf(os.Stderr, "Ошибка выгрузки: %v\n", err)
+        return 1
+    }
+    if err := f.Close(); err != nil {
+        os.Remove(tmp)
+        fmt.Fprintf(os.Stderr, "Ошибка записи файла: %v\n", err)
+        return 1
+    }
+    if err := os.Rename(tmp, *out); err != nil {
+        fmt.Fprintf(os.Stderr, "Ошибка записи файла: %v\n", err)
+        return 1
+    }
+
+    abs, _ := filepath.Abs(*out)
+    fmt.Printf("✅ Выгрузка сохранена: %s\n", abs)
+    return 0
+}
\ No newline at end of file
@@ -0,0 +1,150 @@
+package export
+
+import (
+    "fmt"
+    "math"
+    "strconv"
+    "strings"
+    "time"
+
+    "betelgeuze-measure-system-main/measurement"
+    "betelgeuze-measure-system-main/types"
+)
+
+// Options — параметры выгрузки: набор колонок и единицы измерения
+type Options struct {
+    Columns       []string
+    WeightUnit    string
+    DimensionUnit string
+}
+
+// Column — колонка выгрузки
+type Column struct {
+    Name    string
+    Header  string
+    Numeric bool
+    Value   func(m *types.Measurement, opts Options) string
+}
+
+// DefaultColumns — колонки выгрузки, если набор не указан
+var DefaultColumns = []string{
+    "timestamp", "id", "station_id", "barcode", "weight", "length", "width", "height", "volume",
+    "volumetric_weight", "chargeable_weight", "trigger", "output_status",
+}
+
+var columns = []Column{
+    {Name: "id", Header: "ID", Value: func(m *types.Measurement, opts Options) string { return m.ID }},
+    {Name: "timestamp", Header: "Время", Value: func(m *types.Measurement, opts Options) string {
+        return m.Timestamp.In(time.Local).Format("2006-01-02 15:04:05")
+    }},
+    {Name: "station_id", Header: "Станция", Value: func(m *types.Measurement, opts Options) string { return m.StationID }},
+    {Name: "barcode", Header: "Штрихкод", Value: func(m *types.Measurement, opts Options) string { return m.Barcode }},
+    {Name: "weight", Header: "Вес", Numeric: true, Value: func(m *types.Measurement, opts Options) string {
+        v, _ := measurement.ConvertWeight(m.Weight, opts.WeightUnit)
+        return formatNumber(v)
+    }},
+    {Name: "length", Header: "Длина", Numeric: true, Value: func(m *types.Measurement, opts Options) string {
+        return length(m.Length, opts)
+    }},
+    {Name: "width", Header: "Ширина", Numeric: true, Value: func(m *types.Measurement, opts Options) string {
+        return length(m.Width, opts)
+    }},
+    {Name: "height", Header: "Высота", Numeric: true, Value: func(m *types.Measurement, opts Options) string {
+        return length(m.Height, opts)
+    }},
+    {Name: "volume", Header: "Объем", Numeric: true, Value: func(m *types.Measurement, opts Options) string {
+        v, _ := measurement.ConvertVolume(float64(m.Volume), opts.DimensionUnit)
+        return formatNumber(v)
+    }},
+    {Name: "carrier", Header: "Перевозчик", Value: func(m *types.Measurement, opts Options) string { return m.Carrier }},
+    {Name: "volumetric_weight", Header: "Объемный вес", Numeric: true, Value: func(m *types.Measurement, opts Options) string {
+        v, _ := measurement.ConvertWeight(m.VolumetricWeight, opts.WeightUnit)
+        return formatNumber(v)
+    }},
+    {Name: "chargeable_weight", Header: "Оплачиваемый вес", Numeric: true, Value: func(m *types.Measurement, opts Options) string {
+        v, _ := measurement.ConvertWeight(m.ChargeableWeight, opts.WeightUnit)
+        return formatNumber(v)
+    }},
+    {Name: "trigger", Header: "Источник", Value: func(m *types.Measurement, opts Options) string { return m.Trigger }},
+    {Name: "scale_port", Header: "Порт весов", Value: func(m *types.Measurement, opts Options) string { return m.ScalePort }},
+    {Name: "arduino_port", Header: "Порт Arduino", Value: func(m *types.Measurement, opts Options) string { return m.ArduinoPort }},
+    {Name: "confidence", Header: "Достоверность", Numeric: true, Value: func(m *types.Measurement, opts Options) string {
+        return formatNumber(m.Confidence)
+    }},
+    {Name: "output_status", Header: "Вывод", Value: func(m *types.Measurement, opts Options) string { return m.OutputStatus }},
+}
+
+func length(cm int, opts Options) string {
+    v, _ := measurement.ConvertLength(float64(cm), opts.DimensionUnit)
+    return formatNumber(v)
+}
+
+// formatNumber убирает погрешность перевода единиц (не более 6 знаков после запятой)
+func formatNumber(v float64) string {
+    return strconv.FormatFloat(math.Round(v*1e6)/1e6, 'f', -1, 64)
+}
+
+// ColumnNames возвращает имена всех доступных колонок
+func ColumnNames() []string {
+    names := make([]string, len(columns))
+    for i, c := range columns {
+        names[i] = c.Name
+    }
+    return names
+}
+
+// Validate проверяет параметры выгрузки до начала передачи файла
+func Validate(opts Options) error {
+    _, err := resolve(&opts)
+    return err
+}
+
+// resolve проверяет параметры выгрузки и возвращает выбранные колонки
+func resolve(opts *Options) ([]Column, error) {
+    if opts.WeightUnit == "" {
+        opts.WeightUnit = "g"
+    }
+    if opts.DimensionUnit == "" {
+        opts.DimensionUnit = "cm"
+    }
+    if !measurement.ValidWeightUnit(opts.WeightUnit) {
+        return nil, fmt.Errorf("неизвестная единица веса: %s", opts.WeightUnit)
+    }
+    if !measurement.ValidLengthUnit(opts.DimensionUnit) {
+        return nil, fmt.Errorf("неизвестная единица длины: %s", opts.DimensionUnit)
+    }
+
+    names := opts.Columns
+    if len(names) == 0 {
+        names = DefaultColumns
+    }
+    var selected []Column
+    for _, name := range names {
+        name = strings.TrimSpace(name)
+        found := false
+        for _, c := range columns {
+            if c.Name == name {
+                selected = append(selected, c)
+                found = true
+                break
+            }
+        }
+        if !found {
+            return nil, fmt.Errorf("неизвестная колонка: %s", name)
+        }
+    }
+    return selected, nil
+}
+
+// header возвращает заголовок колонки с единицей измерения
+func header(c Column, opts Options) string {
+    switch c.Name {
+    case "weight", "volumetric_weight", "chargeable_weight":
+        return fmt.Sprintf("%s, %s", c.Header, opts.WeightUnit)
+    case "length", "width", "height":
+        return fmt.Sprintf("%s, %s", c.Header, opts.DimensionUnit)
+    case "volume":
+        return fmt.Sprintf("%s, %s³", c.Header, opts.DimensionUnit)
+    }
+    return c.Header
+}
\ No newline at end of file
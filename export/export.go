@@ -0,0 +1,197 @@
+package export
+
+import (
+    "archive/zip"
+    "encoding/csv"
+    "encoding/xml"
+    "fmt"
+    "io"
+    "strings"
+
+    "betelgeuze-measure-system-main/history"
+    "betelgeuze-measure-system-main/types"
+)
+
+// Поддерживаемые форматы выгрузки
+const (
+    FormatCSV  = "csv"
+    FormatXLSX = "xlsx"
+)
+
+// ContentType возвращает MIME-тип файла выгрузки
+func ContentType(format string) string {
+    if format == FormatXLSX {
+        return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+    }
+    return "text/csv; charset=utf-8"
+}
+
+// Write выгружает измерения из истории в указанном формате
+func Write(w io.Writer, format string, store *history.Store, q history.Query, opts Options) error {
+    switch format {
+    case FormatCSV:
+        return WriteCSV(w, store, q, opts)
+    case FormatXLSX:
+        return WriteXLSX(w, store, q, opts)
+    default:
+        return fmt.Errorf("неизвестный формат выгрузки: %s", format)
+    }
+}
+
+// WriteCSV построчно выгружает измерения в CSV (с BOM, чтобы Excel распознал UTF-8)
+func WriteCSV(w io.Writer, store *history.Store, q history.Query, opts Options) error {
+    cols, err := resolve(&opts)
+    if err != nil {
+        return err
+    }
+    if _, err := w.Write([]byte("\xEF\xBB\xBF")); err != nil {
+        return err
+    }
+    cw := csv.NewWriter(w)
+    cw.Comma = ';'
+
+    row := make([]string, len(cols))
+    for i, c := range cols {
+        row[i] = header(c, opts)
+    }
+    if err := cw.Write(row); err != nil {
+        return err
+    }
+
+    err = store.Each(q, func(m *types.Measurement) error {
+        for i, c := range cols {
+            row[i] = c.Value(m, opts)
+        }
+        return cw.Write(row)
+    })
+    if err != nil {
+        return err
+    }
+    cw.Flush()
+    return cw.Error()
+}
+
+// WriteXLSX выгружает измерения в XLSX. Лист пишется в архив потоком,
+// без построения всей таблицы в памяти.
+func WriteXLSX(w io.Writer, store *history.Store, q history.Query, opts Options) error {
+    cols, err := resolve(&opts)
+    if err != nil {
+        return err
+    }
+
+    zw := zip.NewWriter(w)
+    static := map[string]string{
+        "[Content_Types].xml":        xlsxContentTypes,
+        "_rels/.rels":                xlsxRootRels,
+        "xl/workbook.xml":            xlsxWorkbook,
+        "xl/_rels/workbook.xml.rels": xlsxWorkbookRels,
+        "xl/styles.xml":              xlsxStyles,
+    }
+    for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/styles.xml"} {
+        f, err := zw.Create(name)
+        if err != nil {
+            return err
+        }
+        if _, err := io.WriteString(f, static[name]); err != nil {
+            return err
+        }
+    }
+
+    sheet, err := zw.Create("xl/worksheets/sheet1.xml")
+    if err != nil {
+        return err
+    }
+    if _, err := io.WriteString(sheet, xml.Header+`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`); err != nil {
+        return err
+    }
+
+    rowNum := 1
+    headers := make([]string, len(cols))
+    for i, c := range cols {
+        headers[i] = header(c, opts)
+    }
+    if err := writeXLSXRow(sheet, rowNum, cols, headers, true); err != nil {
+        return err
+    }
+
+    values := make([]string, len(cols))
+    err = store.Each(q, func(m *types.Measurement) error {
+        rowNum++
+        for i, c := range cols {
+            values[i] = c.Value(m, opts)
+        }
+        return writeXLSXRow(sheet, rowNum, cols, values, false)
+    })
+    if err != nil {
+        return err
+    }
+
+    if _, err := io.WriteString(sheet, `</sheetData></worksheet>`); err != nil {
+        return err
+    }
+    return zw.Close()
+}
+
+func writeXLSXRow(w io.Writer, rowNum int, cols []Column, values []string, isHeader bool) error {
+    var b strings.Builder
+    fmt.Fprintf(&b, `<row r="%d">`, rowNum)
+    for i, value := range values {
+        ref := fmt.Sprintf("%s%d", columnLetter(i), rowNum)
+        switch {
+        case isHeader:
+            fmt.Fprintf(&b, `<c r="%s" t="inlineStr" s="1"><is><t>%s</t></is></c>`, ref, escapeXML(value))
+        case cols[i].Numeric && value != "":
+            fmt.Fprintf(&b, `<c r="%s"><v>%s</v></c>`, ref, value)
+        default:
+            fmt.Fprintf(&b, `<c r="%s" t="inlineStr"><is><t>%s</t></is></c>`, ref, escapeXML(value))
+        }
+    }
+    b.WriteString(`</row>`)
+    _, err := io.WriteString(w, b.String())
+    return err
+}
+
+// columnLetter возвращает буквенное обозначение колонки (0 → A, 26 → AA)
+func columnLetter(i int) string {
+    name := ""
+    for i >= 0 {
+        name = string(rune('A'+i%26)) + name
+        i = i/26 - 1
+    }
+    return name
+}
+
+func escapeXML(s string) string {
+    var b strings.Builder
+    xml.EscapeText(&b, []byte(s))
+    return b.String()
+}
+
+const xlsxContentTypes = xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>
+<Default Extension="xml" ContentType="application/xml"/>
+<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>
+<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>
+<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>
+</Types>`
+
+const xlsxRootRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>
+</Relationships>`
+
+const xlsxWorkbook = xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="Измерения" sheetId="1" r:id="rId1"/></sheets>
+</workbook>`
+
+const xlsxWorkbookRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>
+</Relationships>`
+
+const xlsxStyles = xml.Header + `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>
+<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>
+<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>
+<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>
+<cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/><xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/></cellXfs>
+</styleSheet>`
\ No newline at end of file
@@ -0,0 +1,175 @@
+package feedback
+
+import (
+    "fmt"
+    "time"
+
+    "betelgeuze-measure-system-main/config"
+    "betelgeuze-measure-system-main/devices"
+    "betelgeuze-measure-system-main/logging"
+    "betelgeuze-measure-system-main/types"
+)
+
+// События, на которые станция подает сигнал оператору
+const (
+    EventSuccess        = "success"         // измерение выполнено и доставлено
+    EventRejected       = "rejected"        // измерение не прошло проверку
+    EventDeliveryFailed = "delivery_failed" // измерение не доставлено во все выходы
+    EventError          = "error"           // измерение не удалось выполнить
+    EventWaiting        = "waiting"         // ожидание штрихкода или других условий запуска
+    EventIdle           = "idle"            // станция свободна
+)
+
+// Режимы подсветки
+const (
+    LedOn        = "on"
+    LedOff       = "off"
+    LedBlink     = "blink"
+    LedBlinkFast = "blink_fast"
+    LedNone      = "none"
+)
+
+// LOG_TYPE — тип сообщения в потоке логов, по которому веб-интерфейс проигрывает звук
+const LOG_TYPE = "feedback"
+
+// Feedback переводит события измерения в сигналы подсветки Arduino и звуки веб-интерфейса
+type Feedback struct {
+    state  *types.AppState
+    events map[string]config.FeedbackCue
+    cues   chan config.FeedbackCue
+}
+
+var defaultFeedback *Feedback
+
+// Init запускает сигналы оператору, если они включены в настройках
+func Init(cfg config.FeedbackConfig, state *types.AppState) error {
+    if !cfg.Enabled {
+        return nil
+    }
+    for event, cue := range cfg.Events {
+        switch cue.Led {
+        case LedOn, LedOff, LedBlink, LedBlinkFast, LedNone, "":
+        default:
+            return fmt.Errorf("событие %s: неизвестный режим подсветки %q", event, cue.Led)
+        }
+        switch cue.Color {
+        case devices.LED_GREEN, devices.LED_RED, "":
+        default:
+            return fmt.Errorf("событие %s: неизвестный цвет подсветки %q", event, cue.Color)
+        }
+    }
+    f := &Feedback{
+        state:  state,
+        events: cfg.Events,
+        cues:   make(chan config.FeedbackCue, 8),
+    }
+    defaultFeedback = f
+    go f.run()
+    return nil
+}
+
+// Emit подает сигнал события. Без настройки сигналов ничего не делает.
+func Emit(event string) {
+    f := defaultFeedback
+    if f == nil {
+        return
+    }
+    cue, ok := f.events[event]
+    if !ok {
+        return
+    }
+    if cue.Sound != "" && cue.Sound != "none" {
+        logging.BroadcastLog(event, LOG_TYPE)
+    }
+    if cue.Led == "" || cue.Led == LedNone {
+        return
+    }
+    select {
+    case f.cues <- cue:
+    default:
+        // Подсветка не успевает за событиями — важнее не задерживать измерения
+    }
+}
+
+// Events возвращает настройку сигналов для веб-интерфейса
+func Events() map[string]config.FeedbackCue {
+    if f := defaultFeedback; f != nil {
+        return f.events
+    }
+    return nil
+}
+
+// run управляет подсветкой: держит режим последнего события и возвращается к idle.
+// Без подсветки idle (none) подсветку ведет прошивка: она показывает режим "только вес"
+// цветом, и run ее не трогает, пока не придет сигнал, а после сигнала возвращает индикатор режима.
+func (f *Feedback) run() {
+    var (
+        mode, color = f.idle()
+        // После запуска прошивка показывает режим, как после команды 0x66
+        lit      = true
+        litColor = ""
+        until    time.Time
+        tick     = time.NewTicker(100 * time.Millisecond)
+        phase    = 0
+    )
+    defer tick.Stop()
+
+    for {
+        select {
+        case cue := <-f.cues:
+            mode = cue.Led
+            color = cue.Color
+            phase = 0
+            until = time.Time{}
+            if cue.DurationMs > 0 {
+                until = time.Now().Add(time.Duration(cue.DurationMs) * time.Millisecond)
+            }
+        case <-tick.C:
+            phase++
+        }
+
+        if !until.IsZero() && time.Now().After(until) {
+            mode, color = f.idle()
+            until = time.Time{}
+            if mode == LedNone && f.apply(true, "") {
+                lit, litColor = true, ""
+            }
+        }
+
+        want := lit
+        switch mode {
+        case LedNone:
+            continue
+        case LedOn:
+            want = true
+        case LedOff:
+            want = false
+        case LedBlink:
+            want = phase/4%2 == 0 // 400 мс горит, 400 мс нет
+        case LedBlinkFast:
+            want = phase%2 == 0
+        }
+        if want != lit || (lit && color != litColor) {
+            if f.apply(want, color) {
+                lit = want
+                litColor = color
+            }
+        }
+    }
+}
+
+// idle возвращает подсветку свободной станции; LedNone — индикатор режима прошивки
+func (f *Feedback) idle() (string, string) {
+    if idle, ok := f.events[EventIdle]; ok && idle.Led != "" && idle.Led != LedNone {
+        return idle.Led, idle.Color
+    }
+    return LedNone, ""
+}
+
+// apply переключает подсветку; без Arduino возвращает false
+func (f *Feedback) apply(on bool, color string) bool {
+    if !f.state.Status.ArduinoConnected || f.state.Arduino == nil {
+        return false
+    }
+    return devices.SetLED(f.state.Arduino, on, color) == nil
+}
\ No newline at end of file
@@ -0,0 +1,78 @@
+package feedback
+
+import (
+    "bytes"
+    "sync"
+    "testing"
+    "time"
+
+    "betelgeuze-measure-system-main/config"
+    "betelgeuze-measure-system-main/types"
+
+    arduinoSerial "go.bug.st/serial"
+)
+
+// ledPort запоминает команды подсветки, отправленные Arduino
+type ledPort struct {
+    arduinoSerial.Port
+    mu      sync.Mutex
+    written []byte
+}
+
+func (p *ledPort) Write(b []byte) (int, error) {
+    p.mu.Lock()
+    defer p.mu.Unlock()
+    p.written = append(p.written, b...)
+    return len(b), nil
+}
+
+func (p *ledPort) commands() []byte {
+    p.mu.Lock()
+    defer p.mu.Unlock()
+    return bytes.Clone(p.written)
+}
+
+func start(t *testing.T, events map[string]config.FeedbackCue) *ledPort {
+    t.Helper()
+    port := &ledPort{}
+    state := &types.AppState{Arduino: &types.ArduinoPort{Port: port}}
+    state.Status.ArduinoConnected = true
+    if err := Init(config.FeedbackConfig{Enabled: true, Events: events}, state); err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(func() { defaultFeedback = nil })
+    return port
+}
+
+// Без подсветки idle станция не трогает индикатор режима прошивки и возвращает его после сигнала
+func TestIdleKeepsModeIndicator(t *testing.T) {
+    port := start(t, map[string]config.FeedbackCue{
+        EventSuccess: {Led: LedOn, Color: "green", DurationMs: 200},
+        EventIdle:    {Led: LedNone},
+    })
+    time.Sleep(300 * time.Millisecond)
+    if got := port.commands(); len(got) != 0 {
+        t.Fatalf("при запуске отправлено % X", got)
+    }
+
+    Emit(EventSuccess)
+    time.Sleep(500 * time.Millisecond)
+    want := []byte{config.CMD_LED_GREEN, config.CMD_LED_ON}
+    if got := port.commands(); !bytes.Equal(got, want) {
+        t.Errorf("команды подсветки % X, ожидалось % X", got, want)
+    }
+}
+
+func TestIdleLedConfigured(t *testing.T) {
+    port := start(t, map[string]config.FeedbackCue{
+        EventRejected: {Led: LedOn, Color: "red", DurationMs: 200},
+        EventIdle:     {Led: LedOff},
+    })
+    time.Sleep(300 * time.Millisecond)
+    Emit(EventRejected)
+    time.Sleep(500 * time.Millisecond)
+    want := []byte{config.CMD_LED_OFF, config.CMD_LED_RED, config.CMD_LED_OFF}
+    if got := port.commands(); !bytes.Equal(got, want) {
+        t.Errorf("команды подсветки % X, ожидалось % X", got, want)
+    }
+}
\ No newline at end of file
@@ -0,0 +1,44 @@
+package history
+
+import (
+    "fmt"
+
+    "betelgeuze-measure-system-main/logging"
+    "betelgeuze-measure-system-main/types"
+)
+
+// store — хранилище истории станции, открывается при запуске программы
+var store *Store
+
+// Init открывает хранилище истории станции в каталоге dir. Каждая смена статуса добавляет
+// в журнал новую версию измерения, поэтому журнал сжимается, когда устаревшие версии
+// занимают больше половины файла.
+func Init(dir string) error {
+    s, err := OpenStore(dir)
+    if err != nil {
+        return err
+    }
+    if size, garbage := s.Garbage(); size >= COMPACT_MIN_SIZE && garbage*2 > size {
+        if err := s.Compact(); err != nil {
+            logging.Warn(fmt.Sprintf("История: не удалось сжать журнал: %v", err), "system")
+        } else {
+            logging.BroadcastLog(fmt.Sprintf("История: журнал сжат с %d до %d байт", size, size-garbage), "system")
+        }
+    }
+    store = s
+    return nil
+}
+
+// Default возвращает хранилище станции (nil, если история не открыта)
+func Default() *Store {
+    return store
+}
+
+// Save сохраняет версию измерения в историю станции.
+// Если история не открыта, измерение просто не сохраняется.
+func Save(m *types.Measurement) error {
+    if store == nil {
+        return nil
+    }
+    return store.Save(m)
+}
\ No newline at end of file
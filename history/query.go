@@ -0,0 +1,147 @@
+package history
+
+import (
+    "slices"
+    "sort"
+    "time"
+
+    "betelgeuze-measure-system-main/types"
+)
+
+// MAX_PAGE_SIZE ограничивает размер одной страницы выборки
+const MAX_PAGE_SIZE = 500
+
+// Query — фильтр выборки из истории. Нулевые значения полей означают "без ограничения".
+type Query struct {
+    From      time.Time
+    To        time.Time
+    StationID string
+    Barcode   string
+    MinWeight float64
+    MaxWeight float64
+    Statuses  []string // статусы вывода, например awaiting и rejected
+    Offset    int
+    Limit     int
+    Ascending bool // по умолчанию сначала новые
+}
+
+// Page — страница результатов выборки
+type Page struct {
+    Total  int                  `json:"total"`
+    Offset int                  `json:"offset"`
+    Limit  int                  `json:"limit"`
+    Items  []*types.Measurement `json:"items"`
+}
+
+func (q Query) match(e *entry) bool {
+    if q.StationID != "" && e.StationID != q.StationID {
+        return false
+    }
+    if q.Barcode != "" && e.Barcode != q.Barcode {
+        return false
+    }
+    if q.MinWeight > 0 && e.Weight < q.MinWeight {
+        return false
+    }
+    if q.MaxWeight > 0 && e.Weight > q.MaxWeight {
+        return false
+    }
+    if len(q.Statuses) > 0 && !slices.Contains(q.Statuses, e.Status) {
+        return false
+    }
+    return true
+}
+
+// candidates выбирает индексные записи по времени (или по штрихкоду) без учета остальных фильтров
+func (s *Store) candidates(q Query) []*entry {
+    if q.Barcode != "" {
+        list := append([]*entry(nil), s.byBarcode[q.Barcode]...)
+        sort.Slice(list, func(i, j int) bool { return list[i].Time.Before(list[j].Time) })
+        return filterTime(list, q)
+    }
+    lo, hi := 0, len(s.entries)
+    if !q.From.IsZero() {
+        lo = sort.Search(len(s.entries), func(i int) bool { return !s.entries[i].Time.Before(q.From) })
+    }
+    if !q.To.IsZero() {
+        hi = sort.Search(len(s.entries), func(i int) bool { return s.entries[i].Time.After(q.To) })
+    }
+    if lo >= hi {
+        return nil
+    }
+    return s.entries[lo:hi]
+}
+
+func filterTime(list []*entry, q Query) []*entry {
+    var out []*entry
+    for _, e := range list {
+        if !q.From.IsZero() && e.Time.Before(q.From) {
+            continue
+        }
+        if !q.To.IsZero() && e.Time.After(q.To) {
+            continue
+        }
+        out = append(out, e)
+    }
+    return out
+}
+
+// Query выполняет выборку с фильтрами и постраничной разбивкой
+func (s *Store) Query(q Query) (*Page, error) {
+    if q.Limit <= 0 || q.Limit > MAX_PAGE_SIZE {
+        q.Limit = MAX_PAGE_SIZE
+    }
+    if q.Offset < 0 {
+        q.Offset = 0
+    }
+
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    var matched []*entry
+    for _, e := range s.candidates(q) {
+        if q.match(e) {
+            matched = append(matched, e)
+        }
+    }
+    if !q.Ascending {
+        for i, j := 0, len(matched)-1; i < j; i, j = i+1, j-1 {
+            matched[i], matched[j] = matched[j], matched[i]
+        }
+    }
+
+    page := &Page{Total: len(matched), Offset: q.Offset, Limit: q.Limit, Items: []*types.Measurement{}}
+    for i := q.Offset; i < len(matched) && i < q.Offset+q.Limit; i++ {
+        m, err := s.read(matched[i])
+        if err != nil {
+            return nil, err
+        }
+        page.Items = append(page.Items, m)
+    }
+    return page, nil
+}
+
+// Each обходит все измерения, подходящие под фильтр, в порядке времени без разбивки на страницы
+func (s *Store) Each(q Query, fn func(*types.Measurement) error) error {
+    s.mu.RLock()
+    var matched []*entry
+    for _, e := range s.candidates(q) {
+        if q.match(e) {
+            matched = append(matched, e)
+        }
+    }
+    s.mu.RUnlock()
+
+    for _, e := range matched {
+        s.mu.RLock()
+        m, err := s.read(e)
+        s.mu.RUnlock()
+        if err != nil {
+            return err
+        }
+        if err := fn(m); err != nil {
+            return err
+        }
+    }
+    return nil
+}
\ No newline at end of file
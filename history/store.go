@@ -0,0 +1,378 @@
+package history
+
+import (
+    "bufio"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "hash/crc32"
+    "io"
+    "os"
+    "path/filepath"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+
+    "betelgeuze-measure-system-main/logging"
+    "betelgeuze-measure-system-main/types"
+)
+
+// JOURNAL_FILE — имя файла журнала измерений в каталоге истории
+const JOURNAL_FILE = "measurements.journal"
+
+// COMPACT_MIN_SIZE — журнал меньше этого размера не сжимается при открытии
+const COMPACT_MIN_SIZE = 1 << 20
+
+// Формат строки журнала: "<crc32 в hex> <JSON измерения>\n".
+// Контрольная сумма позволяет отличить недописанную при сбое строку от целой.
+
+// entry — индексная запись: ключевые поля измерения и положение в журнале
+type entry struct {
+    ID        string
+    Time      time.Time
+    StationID string
+    Barcode   string
+    Weight    float64
+    Status    string
+    Offset    int64
+    Size      int
+}
+
+// Store — встроенное хранилище истории измерений: журнал только на дозапись
+// и индексы в памяти, которые восстанавливаются из журнала при открытии.
+// Повторная запись измерения с тем же ID добавляет новую версию, последняя версия побеждает.
+type Store struct {
+    mu        sync.RWMutex
+    path      string
+    file      *os.File
+    readOnly  bool // открыт для чтения рядом с работающей станцией: журнал не дописывается и не обрезается
+    size      int64
+    entries   []*entry          // отсортированы по времени измерения
+    byID      map[string]*entry
+    byBarcode map[string][]*entry
+}
+
+// OpenStore открывает (или создает) хранилище в каталоге dir
+func OpenStore(dir string) (*Store, error) {
+    if err := os.MkdirAll(dir, 0755); err != nil {
+        return nil, err
+    }
+    path := filepath.Join(dir, JOURNAL_FILE)
+    f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+    if err != nil {
+        return nil, err
+    }
+    s := &Store{
+        path:      path,
+        file:      f,
+        byID:      make(map[string]*entry),
+        byBarcode: make(map[string][]*entry),
+    }
+    if err := s.load(); err != nil {
+        f.Close()
+        return nil, err
+    }
+    return s, nil
+}
+
+// OpenReadOnly открывает существующее хранилище только для чтения, например для выгрузки
+// рядом с работающей станцией: ничего не создает и не обрезает недописанную станцией строку.
+// Если каталога или журнала нет, возвращает ошибку.
+func OpenReadOnly(dir string) (*Store, error) {
+    info, err := os.Stat(dir)
+    if err != nil {
+        return nil, err
+    }
+    if !info.IsDir() {
+        return nil, fmt.Errorf("%s не каталог", dir)
+    }
+    path := filepath.Join(dir, JOURNAL_FILE)
+    f, err := os.Open(path)
+    if err != nil {
+        return nil, err
+    }
+    s := &Store{
+        path:      path,
+        file:      f,
+        readOnly:  true,
+        byID:      make(map[string]*entry),
+        byBarcode: make(map[string][]*entry),
+    }
+    if err := s.load(); err != nil {
+        f.Close()
+        return nil, err
+    }
+    return s, nil
+}
+
+// load читает журнал и строит индексы. Поврежденный хвост журнала
+// (строка, недописанная из-за сбоя питания) отрезается; в режиме только для чтения — пропускается.
+func (s *Store) load() error {
+    reader := bufio.NewReader(s.file)
+    var offset, goodEnd int64
+    skipped := 0
+    for {
+        line, err := reader.ReadBytes('\n')
+        if len(line) > 0 && err == nil {
+            m, ok := decodeLine(line)
+            if ok {
+                s.index(m, offset, len(line))
+                goodEnd = offset + int64(len(line))
+            } else {
+                skipped++
+            }
+            offset += int64(len(line))
+            continue
+        }
+        if err == io.EOF {
+            // Строка без перевода строки в конце — недописанная запись.
+            // Рядом с работающей станцией это может быть строка, которую она пишет прямо сейчас.
+            if len(line) > 0 && !s.readOnly {
+                logging.Warn(fmt.Sprintf("История: отрезан недописанный хвост журнала (%d байт)", len(line)), "system")
+            }
+            break
+        }
+        if err != nil {
+            return err
+        }
+    }
+    if skipped > 0 {
+        logging.Warn(fmt.Sprintf("История: пропущено поврежденных записей: %d", skipped), "system")
+    }
+    if !s.readOnly {
+        if err := s.file.Truncate(goodEnd); err != nil {
+            return err
+        }
+    }
+    s.size = goodEnd
+    return nil
+}
+
+func decodeLine(line []byte) (*types.Measurement, bool) {
+    text := strings.TrimRight(string(line), "\r\n")
+    sep := strings.IndexByte(text, ' ')
+    if sep != 8 {
+        return nil, false
+    }
+    var sum uint32
+    if _, err := fmt.Sscanf(text[:sep], "%08x", &sum); err != nil {
+        return nil, false
+    }
+    payload := []byte(text[sep+1:])
+    if crc32.ChecksumIEEE(payload) != sum {
+        return nil, false
+    }
+    var m types.Measurement
+    if err := json.Unmarshal(payload, &m); err != nil || m.ID == "" {
+        return nil, false
+    }
+    return &m, true
+}
+
+func encodeLine(m *types.Measurement) ([]byte, error) {
+    payload, err := json.Marshal(m)
+    if err != nil {
+        return nil, err
+    }
+    return []byte(fmt.Sprintf("%08x %s\n", crc32.ChecksumIEEE(payload), payload)), nil
+}
+
+// index добавляет или обновляет индексную запись для версии измерения
+func (s *Store) index(m *types.Measurement, offset int64, size int) {
+    if e, ok := s.byID[m.ID]; ok {
+        if e.Barcode != m.Barcode {
+            s.removeBarcode(e)
+            if m.Barcode != "" {
+                s.byBarcode[m.Barcode] = append(s.byBarcode[m.Barcode], e)
+            }
+        }
+        e.StationID = m.StationID
+        e.Barcode = m.Barcode
+        e.Weight = m.Weight
+        e.Status = m.OutputStatus
+        e.Offset = offset
+        e.Size = size
+        return
+    }
+    e := &entry{
+        ID:        m.ID,
+        Time:      m.Timestamp,
+        StationID: m.StationID,
+        Barcode:   m.Barcode,
+        Weight:    m.Weight,
+        Status:    m.OutputStatus,
+        Offset:    offset,
+        Size:      size,
+    }
+    s.byID[m.ID] = e
+    if m.Barcode != "" {
+        s.byBarcode[m.Barcode] = append(s.byBarcode[m.Barcode], e)
+    }
+    // Обычно измерения приходят по порядку, поэтому вставка почти всегда в конец
+    i := sort.Search(len(s.entries), func(i int) bool { return s.entries[i].Time.After(m.Timestamp) })
+    s.entries = append(s.entries, nil)
+    copy(s.entries[i+1:], s.entries[i:])
+    s.entries[i] = e
+}
+
+func (s *Store) removeBarcode(e *entry) {
+    list := s.byBarcode[e.Barcode]
+    for i, other := range list {
+        if other == e {
+            s.byBarcode[e.Barcode] = append(list[:i], list[i+1:]...)
+            break
+        }
+    }
+    if len(s.byBarcode[e.Barcode]) == 0 {
+        delete(s.byBarcode, e.Barcode)
+    }
+}
+
+// Save дописывает версию измерения в журнал и синхронизирует его на диск
+func (s *Store) Save(m *types.Measurement) error {
+    line, err := encodeLine(m)
+    if err != nil {
+        return err
+    }
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if s.file == nil {
+        return errors.New("хранилище истории закрыто")
+    }
+    if s.readOnly {
+        return errors.New("хранилище истории открыто только для чтения")
+    }
+    if _, err := s.file.WriteAt(line, s.size); err != nil {
+        return err
+    }
+    if err := s.file.Sync(); err != nil {
+        return err
+    }
+    s.index(m, s.size, len(line))
+    s.size += int64(len(line))
+    return nil
+}
+
+// Get возвращает последнюю версию измерения по ID
+func (s *Store) Get(id string) (*types.Measurement, error) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+    e, ok := s.byID[id]
+    if !ok {
+        return nil, nil
+    }
+    return s.read(e)
+}
+
+// read читает запись журнала по индексу. Вызывается под блокировкой.
+func (s *Store) read(e *entry) (*types.Measurement, error) {
+    buf := make([]byte, e.Size)
+    if _, err := s.file.ReadAt(buf, e.Offset); err != nil {
+        return nil, err
+    }
+    m, ok := decodeLine(buf)
+    if !ok {
+        return nil, fmt.Errorf("поврежденная запись журнала для %s", e.ID)
+    }
+    return m, nil
+}
+
+// Garbage возвращает размер журнала и сколько в нем занимают устаревшие версии измерений
+func (s *Store) Garbage() (size, garbage int64) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+    live := int64(0)
+    for _, e := range s.entries {
+        live += int64(e.Size)
+    }
+    return s.size, s.size - live
+}
+
+// Compact переписывает журнал, оставляя только последнюю версию каждого измерения.
+// Новый журнал пишется во временный файл и заменяет старый переименованием, поэтому
+// сбой посреди сжатия оставляет прежний журнал целым. Вызывать может только процесс,
+// который пишет в историю: другой процесс продолжил бы писать в замененный файл.
+func (s *Store) Compact() error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if s.file == nil {
+        return errors.New("хранилище истории закрыто")
+    }
+    if s.readOnly {
+        return errors.New("хранилище истории открыто только для чтения")
+    }
+
+    tmpPath := s.path + ".tmp"
+    tmp, err := os.OpenFile(tmpPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+    if err != nil {
+        return err
+    }
+    offsets := make([]int64, len(s.entries))
+    var size int64
+    writer := bufio.NewWriter(tmp)
+    for i, e := range s.entries {
+        line := make([]byte, e.Size)
+        if _, err = s.file.ReadAt(line, e.Offset); err != nil {
+            break
+        }
+        if _, err = writer.Write(line); err != nil {
+            break
+        }
+        offsets[i] = size
+        size += int64(e.Size)
+    }
+    if err == nil {
+        err = writer.Flush()
+    }
+    if err == nil {
+        err = tmp.Sync()
+    }
+    tmp.Close()
+    if err != nil {
+        os.Remove(tmpPath)
+        return err
+    }
+
+    // На Windows открытый файл нельзя заменить, поэтому журнал закрывается до переименования
+    s.file.Close()
+    renameErr := os.Rename(tmpPath, s.path)
+    f, err := os.OpenFile(s.path, os.O_RDWR, 0644)
+    if err != nil {
+        s.file = nil
+        return err
+    }
+    s.file = f
+    if renameErr != nil {
+        os.Remove(tmpPath)
+        return renameErr
+    }
+    if dir, err := os.Open(filepath.Dir(s.path)); err == nil {
+        dir.Sync()
+        dir.Close()
+    }
+    for i, e := range s.entries {
+        e.Offset = offsets[i]
+    }
+    s.size = size
+    return nil
+}
+
+// Count возвращает количество измерений в истории
+func (s *Store) Count() int {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+    return len(s.entries)
+}
+
+// Close закрывает журнал
+func (s *Store) Close() error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    if s.file == nil {
+        return nil
+    }
+    err := s.file.Close()
+    s.file = nil
+    return err
+}
\ No newline at end of file
@@ -0,0 +1,115 @@
+package history
+
+import (
+    "bytes"
+    "os"
+    "path/filepath"
+    "testing"
+    "time"
+
+    "betelgeuze-measure-system-main/types"
+)
+
+func journalLines(t *testing.T, dir string) int {
+    data, err := os.ReadFile(filepath.Join(dir, JOURNAL_FILE))
+    if err != nil {
+        t.Fatal(err)
+    }
+    return bytes.Count(data, []byte("\n"))
+}
+
+func TestCompactKeepsLatestVersions(t *testing.T) {
+    dir := t.TempDir()
+    s, err := OpenStore(dir)
+    if err != nil {
+        t.Fatal(err)
+    }
+    start := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+    statuses := []string{types.OutputPending, types.OutputQueued, types.OutputSent}
+    for i, id := range []string{"a", "b"} {
+        m := &types.Measurement{ID: id, Timestamp: start.Add(time.Duration(i) * time.Minute), Weight: 1000, Unit: "g"}
+        for _, status := range statuses {
+            m.OutputStatus = status
+            if err := s.Save(m); err != nil {
+                t.Fatal(err)
+            }
+        }
+    }
+    size, garbage := s.Garbage()
+    if garbage*2 <= size {
+        t.Fatalf("устаревшие версии: %d из %d байт, ожидалось больше половины", garbage, size)
+    }
+
+    if err := s.Compact(); err != nil {
+        t.Fatal(err)
+    }
+    if n := journalLines(t, dir); n != 2 {
+        t.Fatalf("после сжатия в журнале %d строк, ожидалось 2", n)
+    }
+    if _, garbage := s.Garbage(); garbage != 0 {
+        t.Errorf("после сжатия осталось %d байт устаревших версий", garbage)
+    }
+
+    // Журнал после сжатия читается и дописывается
+    c := &types.Measurement{ID: "c", Timestamp: start.Add(time.Hour), OutputStatus: types.OutputSent}
+    if err := s.Save(c); err != nil {
+        t.Fatal(err)
+    }
+    s.Close()
+
+    s, err = OpenStore(dir)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer s.Close()
+    if s.Count() != 3 {
+        t.Fatalf("измерений после открытия: %d, ожидалось 3", s.Count())
+    }
+    for _, id := range []string{"a", "b", "c"} {
+        m, err := s.Get(id)
+        if err != nil || m == nil {
+            t.Fatalf("измерение %s: %v", id, err)
+        }
+        if m.OutputStatus != types.OutputSent {
+            t.Errorf("измерение %s: статус %q, ожидался sent", id, m.OutputStatus)
+        }
+    }
+}
+
+func TestOpenReadOnly(t *testing.T) {
+    dir := t.TempDir()
+    if _, err := OpenReadOnly(filepath.Join(dir, "missing")); err == nil {
+        t.Fatal("несуществующий каталог должен давать ошибку")
+    }
+    if _, err := os.Stat(filepath.Join(dir, "missing")); !os.IsNotExist(err) {
+        t.Fatal("OpenReadOnly создал каталог")
+    }
+
+    s, err := OpenStore(dir)
+    if err != nil {
+        t.Fatal(err)
+    }
+    s.Save(&types.Measurement{ID: "a", Timestamp: time.Now().UTC()})
+    s.Close()
+    // Станция дописывает строку: хвост без перевода строки
+    path := filepath.Join(dir, JOURNAL_FILE)
+    f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+    f.WriteString("0000abcd {\"id\":\"b\"")
+    f.Close()
+    before, _ := os.Stat(path)
+
+    ro, err := OpenReadOnly(dir)
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer ro.Close()
+    if ro.Count() != 1 {
+        t.Errorf("измерений %d, ожидалось 1", ro.Count())
+    }
+    if after, _ := os.Stat(path); after.Size() != before.Size() {
+        t.Errorf("журнал обрезан: %d -> %d байт", before.Size(), after.Size())
+    }
+    if err := ro.Save(&types.Measurement{ID: "c"}); err == nil {
+        t.Error("запись в хранилище только для чтения должна давать ошибку")
+    }
+}
\ No newline at end of file
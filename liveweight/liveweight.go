@@ -0,0 +1,202 @@
+package liveweight
+
+import (
+    "fmt"
+    "math"
+    "sync"
+    "time"
+
+    "betelgeuze-measure-system-main/config"
+    "betelgeuze-measure-system-main/devices"
+    "betelgeuze-measure-system-main/logging"
+    "betelgeuze-measure-system-main/mqtt"
+    "betelgeuze-measure-system-main/types"
+)
+
+// Reading — одно показание весов
+type Reading struct {
+    Weight    float64   `json:"weight"`
+    Unit      string    `json:"unit"`
+    Stable    bool      `json:"stable"` // вес не менялся дольше stable_ms
+    Tare      float64   `json:"tare"`   // вес тары, снятой командой тарирования станции
+    Timestamp time.Time `json:"timestamp"`
+}
+
+// Subscriber получает показания через канал на одно значение: если клиент не успевает
+// забрать показание, его заменяет более свежее, а Dropped растет
+type Subscriber struct {
+    C       chan Reading
+    mu      sync.Mutex
+    dropped uint64
+}
+
+// Dropped возвращает число показаний, замененных до того, как клиент их забрал
+func (s *Subscriber) Dropped() uint64 {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    return s.dropped
+}
+
+func (s *Subscriber) offer(r Reading) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    select {
+    case s.C <- r:
+        return
+    default:
+    }
+    // Канал занят старым показанием: заменяем его
+    select {
+    case <-s.C:
+        s.dropped++
+    default:
+    }
+    s.C <- r
+}
+
+// Feed опрашивает весы и раздает показания подписчикам
+type Feed struct {
+    cfg   config.LiveWeightConfig
+    state *types.AppState
+
+    mu          sync.Mutex
+    last        Reading
+    hasLast     bool
+    stableSince time.Time
+    tare        float64
+    subs        map[*Subscriber]struct{}
+}
+
+var defaultFeed *Feed
+
+// Start запускает опрос весов станции
+func Start(cfg config.LiveWeightConfig, state *types.AppState) *Feed {
+    f := NewFeed(cfg, state)
+    defaultFeed = f
+    go f.Run()
+    return f
+}
+
+// Default возвращает поток станции или nil, если опрос не запущен
+func Default() *Feed {
+    return defaultFeed
+}
+
+// NewFeed создает поток показаний; значения 0 в настройках заменяются значениями по умолчанию
+func NewFeed(cfg config.LiveWeightConfig, state *types.AppState) *Feed {
+    if cfg.IntervalMs <= 0 {
+        cfg.IntervalMs = 500
+    }
+    if cfg.StableMs <= 0 {
+        cfg.StableMs = 1000
+    }
+    if cfg.ClientIntervalMs <= 0 {
+        cfg.ClientIntervalMs = 200
+    }
+    return &Feed{cfg: cfg, state: state, subs: make(map[*Subscriber]struct{})}
+}
+
+// ClientInterval — минимальный интервал между сообщениями одному клиенту
+func (f *Feed) ClientInterval() time.Duration {
+    return time.Duration(f.cfg.ClientIntervalMs) * time.Millisecond
+}
+
+// Run опрашивает весы, пока работает программа. Без весов ждет их подключения.
+func (f *Feed) Run() {
+    interval := time.Duration(f.cfg.IntervalMs) * time.Millisecond
+    failing := false
+    for {
+        time.Sleep(interval)
+        if !f.state.Status.ScaleConnected || f.state.Scale == nil {
+            continue
+        }
+        weight, err := devices.ReadWeight(f.state.Scale)
+        if err != nil {
+            // Сообщаем один раз за серию ошибок, чтобы не забивать лог
+            if !failing {
+                logging.Error(fmt.Sprintf("Живой вес: %v", err), "scale")
+                failing = true
+            }
+            continue
+        }
+        failing = false
+        f.state.Status.CurrentWeight = weight
+        mqtt.PublishWeight(weight)
+        f.publish(weight, time.Now().UTC())
+    }
+}
+
+// publish отмечает стабильность показания и раздает его подписчикам
+func (f *Feed) publish(weight float64, now time.Time) {
+    f.mu.Lock()
+    if !f.hasLast || math.Abs(weight-f.last.Weight) >= config.WEIGHT_THRESHOLD {
+        f.stableSince = now
+    }
+    r := Reading{
+        Weight:    weight,
+        Unit:      "g",
+        Stable:    now.Sub(f.stableSince) >= time.Duration(f.cfg.StableMs)*time.Millisecond,
+        Tare:      f.tare,
+        Timestamp: now,
+    }
+    f.last, f.hasLast = r, true
+    subs := make([]*Subscriber, 0, len(f.subs))
+    for s := range f.subs {
+        subs = append(subs, s)
+    }
+    f.mu.Unlock()
+
+    for _, s := range subs {
+        s.offer(r)
+    }
+}
+
+// Latest возвращает последнее показание
+func (f *Feed) Latest() (Reading, bool) {
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    return f.last, f.hasLast
+}
+
+// Subscribe подписывает клиента на показания. Последнее показание приходит сразу.
+func (f *Feed) Subscribe() *Subscriber {
+    s := &Subscriber{C: make(chan Reading, 1)}
+    f.mu.Lock()
+    f.subs[s] = struct{}{}
+    if f.hasLast {
+        s.C <- f.last
+    }
+    f.mu.Unlock()
+    return s
+}
+
+// Unsubscribe отписывает клиента
+func (f *Feed) Unsubscribe(s *Subscriber) {
+    f.mu.Lock()
+    delete(f.subs, s)
+    f.mu.Unlock()
+}
+
+// Tare тарирует весы и запоминает снятый вес тары: к нему добавляется текущее показание.
+// Тарирование пустой платформы сбрасывает тару.
+func (f *Feed) Tare() error {
+    if err := devices.TareScale(f.state.Scale); err != nil {
+        return err
+    }
+    f.mu.Lock()
+    if f.hasLast && f.last.Weight >= config.WEIGHT_THRESHOLD {
+        f.tare += f.last.Weight
+    } else {
+        f.tare = 0
+    }
+    f.mu.Unlock()
+    return nil
+}
+
+// Tare тарирует весы станции. Без запущенного потока просто отправляет команду.
+func Tare(state *types.AppState) error {
+    if f := defaultFeed; f != nil {
+        return f.Tare()
+    }
+    return devices.TareScale(state.Scale)
+}
\ No newline at end of file
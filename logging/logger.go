@@ -7,9 +7,89 @@ import (
     "betelgeuze-measure-system-main/types"
 )
 
+// Уровни сообщений лога
+const (
+    LevelInfo  = "info"
+    LevelWarn  = "warn"
+    LevelError = "error"
+)
+
+// LEVELS — уровни по возрастанию важности
+var LEVELS = []string{LevelInfo, LevelWarn, LevelError}
+
+// BACKLOG_SIZE — сколько последних сообщений получает клиент при подключении
+const BACKLOG_SIZE = 200
+
+// CLIENT_BUFFER — сколько сообщений может ждать отправки одному клиенту
+const CLIENT_BUFFER = 100
+
+// Сигналы оператору идут через поток логов, но это не записи лога: в буфер они не попадают,
+// чтобы веб-интерфейс не проигрывал старые звуки при подключении
+const typeFeedback = "feedback"
+
+// Filter выбирает сообщения по типу и минимальному уровню. Пустые поля пропускают все.
+type Filter struct {
+    Types []string
+    Level string
+}
+
+// Matches проверяет сообщение по фильтру
+func (f Filter) Matches(msg types.LogMessage) bool {
+    if len(f.Types) > 0 {
+        found := false
+        for _, t := range f.Types {
+            if t == msg.Type {
+                found = true
+                break
+            }
+        }
+        if !found {
+            return false
+        }
+    }
+    // Сигналы оператору не записи лога: уровень к ним не применяется
+    return f.Level == "" || msg.Type == typeFeedback || levelRank(msg.Level) >= levelRank(f.Level)
+}
+
+func levelRank(level string) int {
+    for i, l := range LEVELS {
+        if l == level {
+            return i
+        }
+    }
+    return 0
+}
+
+// ValidLevel проверяет имя уровня
+func ValidLevel(level string) bool {
+    for _, l := range LEVELS {
+        if l == level {
+            return true
+        }
+    }
+    return false
+}
+
+// Client получает сообщения лога. Если клиент не успевает их забирать, новые
+// сообщения не ставятся в очередь, а учитываются в Dropped.
+type Client struct {
+    C       chan types.LogMessage
+    filter  Filter
+    dropped uint64
+}
+
+// Dropped возвращает число сообщений, пропущенных из-за медленного клиента
+func (c *Client) Dropped() uint64 {
+    logMutex.Lock()
+    defer logMutex.Unlock()
+    return c.dropped
+}
+
 var (
-    logClients = make(map[chan types.LogMessage]bool)
-    logMutex   = sync.RWMutex{}
+    logClients = make(map[*Client]bool)
+    logMutex   = sync.Mutex{}
+    backlog    []types.LogMessage // кольцевой буфер в порядке поступления
+    backlogAt  int
 )
 
 func Init() {
@@ -17,34 +97,76 @@ func Init() {
     BroadcastLog("Система логирования инициализирована", "system")
 }
 
-func AddLogClient(client chan types.LogMessage) {
+// Subscribe подключает клиента и возвращает последние сообщения, подходящие под фильтр.
+// Между буфером и первым живым сообщением ничего не теряется.
+func Subscribe(filter Filter) (*Client, []types.LogMessage) {
+    client := &Client{C: make(chan types.LogMessage, CLIENT_BUFFER), filter: filter}
     logMutex.Lock()
     defer logMutex.Unlock()
+    var recent []types.LogMessage
+    for i := range backlog {
+        msg := backlog[(backlogAt+i)%len(backlog)]
+        if filter.Matches(msg) {
+            recent = append(recent, msg)
+        }
+    }
     logClients[client] = true
+    return client, recent
 }
 
-func RemoveLogClient(client chan types.LogMessage) {
+// Unsubscribe отключает клиента
+func Unsubscribe(client *Client) {
     logMutex.Lock()
     defer logMutex.Unlock()
-    delete(logClients, client)
-    close(client)
+    if logClients[client] {
+        delete(logClients, client)
+        close(client.C)
+    }
 }
 
+// BroadcastLog отправляет информационное сообщение
 func BroadcastLog(message, logType string) {
+    Log(LevelInfo, message, logType)
+}
+
+// Warn отправляет предупреждение: работа продолжается, но что-то пошло не так
+func Warn(message, logType string) {
+    Log(LevelWarn, message, logType)
+}
+
+// Error отправляет сообщение об ошибке
+func Error(message, logType string) {
+    Log(LevelError, message, logType)
+}
+
+// Log сохраняет сообщение в буфер и раздает его клиентам
+func Log(level, message, logType string) {
     logMsg := types.LogMessage{
         Time:    time.Now().Format("15:04:05"),
         Message: message,
         Type:    logType,
+        Level:   level,
     }
     
-    logMutex.RLock()
-    defer logMutex.RUnlock()
+    logMutex.Lock()
+    defer logMutex.Unlock()
     
+    if logType != typeFeedback {
+        if len(backlog) < BACKLOG_SIZE {
+            backlog = append(backlog, logMsg)
+        } else {
+            backlog[backlogAt] = logMsg
+            backlogAt = (backlogAt + 1) % BACKLOG_SIZE
+        }
+    }
     for client := range logClients {
+        if !client.filter.Matches(logMsg) {
+            continue
+        }
         select {
-        case client <- logMsg:
+        case client.C <- logMsg:
         default:
-            // Клиент не готов принять сообщение, пропускаем
+            client.dropped++
         }
     }
 }
\ No newline at end of file
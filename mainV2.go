@@ -1,29 +1,131 @@
 package main
 
 import (
+    "bufio"
+    "flag"
     "fmt"
     "log"
+    "net/http"
+    "os"
     "runtime"
+    "strings"
     "time"
     
+    "betelgeuze-measure-system-main/auth"
     "betelgeuze-measure-system-main/config"
     "betelgeuze-measure-system-main/devices"
+    "betelgeuze-measure-system-main/events"
+    "betelgeuze-measure-system-main/export"
+    "betelgeuze-measure-system-main/feedback"
+    "betelgeuze-measure-system-main/history"
+    "betelgeuze-measure-system-main/liveweight"
     "betelgeuze-measure-system-main/logging"
+    "betelgeuze-measure-system-main/measurement"
+    "betelgeuze-measure-system-main/modbus"
+    "betelgeuze-measure-system-main/mqtt"
+    "betelgeuze-measure-system-main/output"
+    "betelgeuze-measure-system-main/trigger"
     "betelgeuze-measure-system-main/types"
     "betelgeuze-measure-system-main/web"
     "betelgeuze-measure-system-main/utils"
     "math"
-    
-    "github.com/atotto/clipboard"
-    "github.com/micmonay/keybd_event"
 )
 
 func main() {
+    // Подкоманда выгрузки истории работает без подключения устройств
+    if len(os.Args) > 1 && os.Args[1] == "export" {
+        os.Exit(export.RunCLI(os.Args[2:]))
+    }
+    // Сверка маршрутов веб-сервера со спецификацией OpenAPI (для сборки и CI)
+    if len(os.Args) > 1 && os.Args[1] == "check-api" {
+        problems := web.CheckSpec(web.RegisterRoutes(http.NewServeMux(), &types.AppState{}))
+        for _, problem := range problems {
+            fmt.Println(problem)
+        }
+        if len(problems) > 0 {
+            os.Exit(1)
+        }
+        fmt.Println("Спецификация OpenAPI соответствует маршрутам")
+        os.Exit(0)
+    }
+    
+    // Хеш пароля и токен API для раздела auth настроек. Пароль читается из stdin,
+    // чтобы не попасть в историю командной строки.
+    if len(os.Args) > 1 && os.Args[1] == "hash-password" {
+        fmt.Fprint(os.Stderr, "Пароль: ")
+        password, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+        password = strings.TrimRight(password, "\r\n")
+        if password == "" {
+            fmt.Fprintln(os.Stderr, "Пустой пароль")
+            os.Exit(1)
+        }
+        hash, err := auth.HashPassword(password)
+        if err != nil {
+            fmt.Fprintln(os.Stderr, err)
+            os.Exit(1)
+        }
+        fmt.Println(hash)
+        os.Exit(0)
+    }
+    if len(os.Args) > 1 && os.Args[1] == "new-token" {
+        token, hash, err := auth.NewToken()
+        if err != nil {
+            fmt.Fprintln(os.Stderr, err)
+            os.Exit(1)
+        }
+        fmt.Printf("Токен (передайте программе, на станции он не хранится): %s\n", token)
+        fmt.Printf("token_hash для настроек: %s\n", hash)
+        os.Exit(0)
+    }
+    
+    headless := flag.Bool("headless", false, "режим без рабочего стола: без клавиатуры и буфера обмена")
+    flag.Parse()
+    
     // Инициализация системы логирования
     logging.Init()
     
+    // Загрузка настроек станции
+    if err := config.LoadSettings(config.SETTINGS_FILE); err != nil {
+        log.Printf("Ошибка чтения настроек %s: %v", config.SETTINGS_FILE, err)
+    }
+    
+    // Вход в веб-интерфейс: с ошибкой в настройках сервер не должен открыться без пароля
+    if err := auth.Init(config.Get().Auth); err != nil {
+        log.Fatalf("Ошибка настройки входа: %v", err)
+    }
+    
+    if _, ok := config.Get().Template(); !ok {
+        log.Printf("Шаблон вывода %q не найден, используется формат вес:длина:ширина:высота", config.Get().OutputTemplate)
+    } else if err := measurement.ValidateTemplate(measurement.StationTemplate()); err != nil {
+        log.Printf("Ошибка в шаблоне вывода: %v", err)
+    }
+    
+    // Режим без рабочего стола включается флагом или в настройках
+    if *headless {
+        config.Get().Headless = true
+    }
+    if !config.Get().Headless && runtime.GOOS == "linux" && os.Getenv("DISPLAY") == "" && os.Getenv("WAYLAND_DISPLAY") == "" {
+        log.Println("Графический сеанс не найден: вставка и буфер обмена работать не будут, используйте -headless")
+    }
+    
+    // Подключение к брокеру MQTT (если настроено)
+    if err := mqtt.Init(config.Get().MQTT, config.Get().StationID); err != nil {
+        log.Printf("Ошибка настройки MQTT: %v", err)
+    }
+    
+    // Открытие истории измерений
+    if err := history.Init(config.Get().HistoryDir); err != nil {
+        log.Printf("Ошибка открытия истории измерений: %v", err)
+    } else {
+        defer history.Default().Close()
+    }
+    
     // Создание глобального состояния
     appState := &types.AppState{}
+    appState.Status.Headless = config.Get().Headless
+    
+    // Порты эмулятора весов не должны попасть в автопоиск устройств
+    output.ExcludeEmulatorPorts(config.Get().Outputs)
     
     // Инициализация Arduino
     fmt.Println("🔌 Поиск Arduino...")
@@ -55,6 +157,45 @@ func main() {
         defer scale.Connection.Close()
     }
     
+    // Настройка выходов результата. Эмулятор весов открывает виртуальный порт,
+    // поэтому выходы создаются после подключения настоящих весов.
+    if err := output.Init(config.Get().Outputs, config.Get().Headless); err != nil {
+        log.Printf("Ошибка настройки выходов: %v", err)
+    }
+    
+    // Сигналы оператору подсветкой Arduino и звуком в веб-интерфейсе
+    if err := feedback.Init(config.Get().Feedback, appState); err != nil {
+        log.Printf("Ошибка настройки сигналов оператору: %v", err)
+    }
+    
+    // Политика запуска измерений
+    if err := trigger.Init(config.Get().Trigger, appState); err != nil {
+        log.Printf("Ошибка в политике запуска: %v. Используется weight, web, modbus", err)
+        trigger.Init(config.TriggerConfig{Policy: []string{types.TriggerWeight, types.TriggerWeb, types.TriggerModbus}}, appState)
+    }
+    trigger.Start(config.Get().Trigger)
+    
+    // Сканер штрихкодов подключается после Arduino и весов, чтобы не занять их порты
+    go measurement.RunScanner(appState, func(raw string) {
+        if _, err := trigger.Scan(appState, raw); err == nil && trigger.Uses(types.TriggerBarcode) {
+            trigger.Fire(types.TriggerBarcode)
+        }
+    })
+    
+    // Живой вес для веб-интерфейса: отдельный опрос весов, пока основной цикл ждет объект
+    liveweight.Start(config.Get().LiveWeight, appState)
+    
+    // События подключения и отключения устройств для /api/v1/events
+    events.Watch(appState)
+    
+    // Публикация состояния устройств в MQTT
+    mqtt.Watch(appState)
+    
+    // Modbus TCP для ПЛК конвейера (если настроен)
+    if err := modbus.Start(config.Get().Modbus, appState); err != nil {
+        log.Printf("Ошибка запуска Modbus TCP: %v", err)
+    }
+    
     // Запуск веб-сервера
     go web.StartServer(appState)
     
@@ -73,59 +214,16 @@ func printStatus(state *types.AppState) {
     fmt.Printf("⚖️ Весы: %s (%s)\n", utils.BoolToString(state.Status.ScaleConnected), state.Status.ScalePort)
 }
 
-// Кроссплатформенная функция для симуляции нажатий клавиш
-func simulateKeyPress(result string) error {
-    // Копируем результат в буфер обмена
-    err := clipboard.WriteAll(result)
-    if err != nil {
-        return fmt.Errorf("ошибка буфера обмена: %v", err)
-    }
-
-    // Создаем клавиатурное событие с учетом ОС
-    kb, err := keybd_event.NewKeyBonding()
-    if err != nil {
-        return fmt.Errorf("ошибка создания клавиатурного события: %v", err)
-    }
-
-    // Устанавливаем правильный модификатор в зависимости от ОС
-    if runtime.GOOS == "darwin" { // macOS
-        kb.HasSuper(true)
-    } else { // Windows и Linux
-        kb.HasCTRL(true)
-    }
-    
-    kb.SetKeys(keybd_event.VK_V)
-    
-    // Задержка перед нажатием (важно для стабильности)
-    time.Sleep(200 * time.Millisecond)
-    
-    err = kb.Launching()
-    if err != nil {
-        return fmt.Errorf("ошибка симуляции Ctrl+V: %v", err)
-    }
-
-    // Создаем новое событие для Enter
-    kbEnter, err := keybd_event.NewKeyBonding()
-    if err != nil {
-        return fmt.Errorf("ошибка создания события Enter: %v", err)
-    }
-    
-    // Небольшая задержка между нажатиями
-    time.Sleep(100 * time.Millisecond)
-    
-    kbEnter.SetKeys(keybd_event.VK_ENTER)
-    err = kbEnter.Launching()
-    if err != nil {
-        return fmt.Errorf("ошибка симуляции Enter: %v", err)
-    }
-
-    return nil
-}
-
 func mainLoop(state *types.AppState) {
     var lastWeight float64 = -1 // Инициализируем значением, которое точно не может быть реальным весом
+    waitingBarcode := false
     const weightThreshold = config.WEIGHT_THRESHOLD  // Минимальное изменение веса для запуска измерения (в граммах)
     
+    // Кандидат в новый вес и число показаний подряд, в которых он не менялся
+    candidate, stable := -1.0, 0
+    stableReadings := max(config.Get().Trigger.StableReadings, 1)
+    useWeight := trigger.Uses(types.TriggerWeight)
+    
     fmt.Printf("🖥️ Система запущена на %s\n", runtime.GOOS)
     
     // Определяем режим работы
@@ -143,7 +241,10 @@ func mainLoop(state *types.AppState) {
 
         // Инициализируем Arduino только если он подключен
         if state.Status.ArduinoConnected {
+            // Порт Arduino делят веб-интерфейс и опрос кнопки — команду отправляем под его мьютексом
+            state.Arduino.Mutex.Lock()
             devices.SendCommandToArduino(state.Arduino, config.CMD_START)
+            state.Arduino.Mutex.Unlock()
             time.Sleep(2 * time.Second)
         }
 
@@ -154,9 +255,19 @@ func mainLoop(state *types.AppState) {
                 time.Sleep(1 * time.Second)
                 continue
             }
+            state.Status.CurrentWeight = weight
+            mqtt.PublishWeight(weight)
+            
+            // На паузе вес запоминается, но не запускает измерение: то, что лежит на весах
+            // во время обслуживания, не будет измерено и после возобновления
+            if trigger.Paused() {
+                lastWeight, candidate, stable = weight, -1, 0
+                time.Sleep(1 * time.Second)
+                continue
+            }
             
-            // Проверяем, что вес больше 0 (есть объект на весах)
-            if weight <= 0 {
+            // Проверяем, что вес больше 0 (есть объект на весах) и изменение веса запускает измерения
+            if weight <= 0 || !useWeight {
                 time.Sleep(1 * time.Second)
                 continue
             }
@@ -167,34 +278,43 @@ func mainLoop(state *types.AppState) {
                 continue
             }
             
-            // Обновляем последний вес
-            lastWeight = weight
-            state.Status.LastWeight = weight
+            // Вес должен продержаться stable_readings показаний подряд
+            if math.Abs(weight-candidate) < weightThreshold {
+                stable++
+            } else {
+                candidate, stable = weight, 1
+            }
+            if stable < stableReadings {
+                time.Sleep(1 * time.Second)
+                continue
+            }
+            
+            // Без штрихкода измерение отложено: вес остается "новым" до сканирования
+            if measurement.WaitingForBarcode() {
+                if !waitingBarcode {
+                    fmt.Println("📦 Ожидание штрихкода посылки...")
+                    feedback.Emit(feedback.EventWaiting)
+                    waitingBarcode = true
+                }
+                time.Sleep(1 * time.Second)
+                continue
+            }
+            waitingBarcode = false
             
             fmt.Printf("🔄 Обнаружено изменение веса: %.1f г (предыдущий: %.1f г)\n", weight, lastWeight)
-
-            var result string
             
-            if state.Status.ArduinoConnected {
-                // Полный режим: весы + Arduino
-                devices.SendCommandToArduino(state.Arduino, config.CMD_GET_DIMENSIONS)
-                length, width, height := devices.GetDimensionsFromArduino(state.Arduino)
-                result = fmt.Sprintf("%.0f:%d:%d:%d", weight, length, width, height)
-                state.Status.LastDimensions = result
-                fmt.Println("📋 Результат (полные измерения):", result)
-            } else {
-                // Режим только весов
-                result = fmt.Sprintf("%.0f", weight)
-                state.Status.LastDimensions = result
-                fmt.Println("📋 Результат (только вес):", result)
-            }
+            // Обновляем последний вес
+            lastWeight = weight
+            state.Status.LastWeight = weight
             
-            // Используем кроссплатформенную функцию для ввода
-            err = simulateKeyPress(result)
-            if err != nil {
-                log.Printf("Ошибка симуляции ввода: %v", err)
+            // Изменение веса — событие для политики запуска: измерение начнется сразу
+            // или после остальных событий правила (например, скана штрихкода)
+            m, err := trigger.Fire(types.TriggerWeight)
+            if err != nil || m == nil {
+                time.Sleep(1 * time.Second)
                 continue
             }
+            fmt.Println("📋 Результат:", measurement.Format(m))
 
             // Добавляем задержку после успешного измерения
             fmt.Println("✅ Измерение завершено. Ожидание следующего объекта...")
@@ -0,0 +1,125 @@
+package measurement
+
+import (
+    "errors"
+    "fmt"
+    "time"
+
+    "betelgeuze-measure-system-main/barcode"
+    "betelgeuze-measure-system-main/config"
+    "betelgeuze-measure-system-main/devices"
+    "betelgeuze-measure-system-main/logging"
+    "betelgeuze-measure-system-main/types"
+)
+
+// ErrNoBarcode — измерение требует штрихкод, а посылка еще не отсканирована
+var ErrNoBarcode = errors.New("нет штрихкода: отсканируйте посылку перед измерением")
+
+// SCANNER_RETRY — пауза перед повторным поиском отключенного сканера
+const SCANNER_RETRY = 5 * time.Second
+
+// scannerSettings возвращает настройки сканера или пустые, если он не настроен
+func scannerSettings() config.ScannerConfig {
+    if cfg := config.Get().Scanner; cfg != nil {
+        return *cfg
+    }
+    return config.ScannerConfig{}
+}
+
+func maxBarcodeAge(cfg config.ScannerConfig) time.Duration {
+    if cfg.MaxAgeSec > 0 {
+        return time.Duration(cfg.MaxAgeSec) * time.Second
+    }
+    return 5 * time.Minute
+}
+
+// Scan обрабатывает код со сканера или из веб-интерфейса: очищает, проверяет символику
+// и привязывает к текущему измерению или оставляет для следующего.
+// Последнее измерение меняется на месте, поэтому вызывается через trigger.Scan под блокировкой измерений.
+func Scan(state *types.AppState, raw string) (string, error) {
+    cfg := scannerSettings()
+    code := barcode.Normalize(raw, cfg.StripPrefix, cfg.StripSuffix)
+    if err := barcode.Validate(code, cfg.Symbologies); err != nil {
+        logging.Warn(fmt.Sprintf("Штрихкод отклонен: %v", err), "system")
+        return "", err
+    }
+
+    if cfg.Attach == "current" {
+        window := time.Duration(cfg.AttachWindowSec) * time.Second
+        if window == 0 {
+            window = 10 * time.Second
+        }
+        if m := state.Status.LastMeasurement; m != nil && m.Barcode == "" && time.Since(m.Timestamp) <= window {
+            m.Barcode = code
+            save(m)
+            logging.BroadcastLog(fmt.Sprintf("Штрихкод %s привязан к измерению %s", code, m.ID), "system")
+            return code, nil
+        }
+    }
+
+    barcode.SetPending(code)
+    state.Status.PendingBarcode = code
+    logging.BroadcastLog(fmt.Sprintf("Штрихкод %s ожидает измерения", code), "system")
+    return code, nil
+}
+
+// AttachBarcode привязывает к измерению ожидающий штрихкод
+func AttachBarcode(state *types.AppState, m *types.Measurement) {
+    if m.Barcode != "" {
+        return
+    }
+    m.Barcode = barcode.TakePending(maxBarcodeAge(scannerSettings()))
+    state.Status.PendingBarcode = ""
+}
+
+// WaitingForBarcode сообщает, что измерение запрещено до сканирования штрихкода
+func WaitingForBarcode() bool {
+    cfg := scannerSettings()
+    return cfg.RequireBarcode && barcode.Pending(maxBarcodeAge(cfg)) == ""
+}
+
+// RunScanner подключает сканер штрихкодов и читает коды, переподключаясь после отключения.
+// Порты Arduino и весов в автопоиске пропускаются. onScan получает каждый прочитанный код
+// до очистки и проверки.
+func RunScanner(state *types.AppState, onScan func(raw string)) {
+    cfg := scannerSettings()
+    if !cfg.Enabled {
+        return
+    }
+    for _, s := range cfg.Symbologies {
+        if !barcode.ValidSymbology(s) {
+            logging.Warn(fmt.Sprintf("Неизвестная символика штрихкода в настройках: %s", s), "system")
+        }
+    }
+
+    reported := false
+    for {
+        var exclude []string
+        if state.Arduino != nil {
+            exclude = append(exclude, state.Arduino.PortName)
+        }
+        if state.Scale != nil {
+            exclude = append(exclude, state.Scale.PortName)
+        }
+        scanner, err := devices.ConnectToScanner(cfg.Port, cfg.VID, cfg.PID, cfg.BaudRate, exclude)
+        if err != nil {
+            if !reported {
+                logging.Error(fmt.Sprintf("Сканер штрихкодов: %v", err), "system")
+                reported = true
+            }
+            time.Sleep(SCANNER_RETRY)
+            continue
+        }
+        reported = false
+        state.Scanner = scanner
+        state.Status.ScannerConnected = true
+        state.Status.ScannerPort = scanner.PortName
+        logging.BroadcastLog(fmt.Sprintf("Сканер штрихкодов подключен: %s", scanner.PortName), "system")
+
+        err = devices.ReadBarcodes(scanner, onScan)
+        scanner.Port.Close()
+        state.Status.ScannerConnected = false
+        logging.Warn(fmt.Sprintf("Сканер штрихкодов отключен: %v", err), "system")
+        time.Sleep(SCANNER_RETRY)
+    }
+}
\ No newline at end of file
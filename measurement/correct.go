@@ -0,0 +1,76 @@
+package measurement
+
+import (
+    "strconv"
+    "time"
+
+    "betelgeuze-measure-system-main/config"
+    "betelgeuze-measure-system-main/types"
+)
+
+// Changes — исправления оператора; nil — поле не меняется
+type Changes struct {
+    Weight  *float64 `json:"weight,omitempty"`
+    Length  *int     `json:"length,omitempty"`
+    Width   *int     `json:"width,omitempty"`
+    Height  *int     `json:"height,omitempty"`
+    Barcode *string  `json:"barcode,omitempty"`
+}
+
+// Correct применяет исправления к измерению как новую ревизию: исходные значения
+// сохраняются в Corrections вместе с именем оператора. Объем, объемный и оплачиваемый
+// вес пересчитываются. Возвращает false, если ни одно поле не изменилось.
+func Correct(m *types.Measurement, changes Changes, user string) bool {
+    now := time.Now().UTC()
+    var corrections []types.Correction
+    record := func(field, old, new string) {
+        corrections = append(corrections, types.Correction{Time: now, User: user, Field: field, Old: old, New: new})
+    }
+
+    if changes.Weight != nil && *changes.Weight != m.Weight {
+        record("weight", formatFloat(m.Weight), formatFloat(*changes.Weight))
+        m.Weight = *changes.Weight
+    }
+    dims := []struct {
+        field string
+        value *int
+        to    *int
+    }{
+        {"length", &m.Length, changes.Length},
+        {"width", &m.Width, changes.Width},
+        {"height", &m.Height, changes.Height},
+    }
+    for _, d := range dims {
+        if d.to != nil && *d.to != *d.value {
+            record(d.field, strconv.Itoa(*d.value), strconv.Itoa(*d.to))
+            *d.value = *d.to
+        }
+    }
+    if changes.Barcode != nil && *changes.Barcode != m.Barcode {
+        record("barcode", m.Barcode, *changes.Barcode)
+        m.Barcode = *changes.Barcode
+    }
+    if len(corrections) == 0 {
+        return false
+    }
+
+    m.Volume = m.Length * m.Width * m.Height
+    if profile, ok := config.Get().CarrierProfile(m.Carrier); ok {
+        ApplyCarrier(m, profile)
+    } else {
+        m.ChargeableWeight = m.Weight
+    }
+    m.Confidence = confidence(m)
+    m.Revision++
+    m.Corrections = append(m.Corrections, corrections...)
+    return true
+}
+
+func formatFloat(v float64) string {
+    return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// Save сохраняет новую версию измерения в историю
+func Save(m *types.Measurement) {
+    save(m)
+}
\ No newline at end of file
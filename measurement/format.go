@@ -0,0 +1,168 @@
+package measurement
+
+import (
+    "fmt"
+    "strconv"
+    "strings"
+    "time"
+
+    "betelgeuze-measure-system-main/config"
+    "betelgeuze-measure-system-main/types"
+)
+
+// fallbackTemplate используется, если шаблон станции не найден в настройках
+var fallbackTemplate = config.OutputTemplate{
+    Fields: []config.TemplateField{
+        {Field: "weight"}, {Field: "length"}, {Field: "width"}, {Field: "height"},
+    },
+    WeightOnlyFields: []config.TemplateField{{Field: "weight"}},
+    Separator:        ":",
+}
+
+// StationTemplate возвращает шаблон вывода, выбранный для станции
+func StationTemplate() config.OutputTemplate {
+    if tpl, ok := config.Get().Template(); ok {
+        return tpl
+    }
+    return fallbackTemplate
+}
+
+// Format форматирует измерение по шаблону станции. Используется всеми путями вывода.
+func Format(m *types.Measurement) string {
+    return FormatWith(m, StationTemplate())
+}
+
+// FormatWith форматирует измерение по указанному шаблону
+func FormatWith(m *types.Measurement, tpl config.OutputTemplate) string {
+    return tpl.Prefix + strings.Join(FormatFields(m, tpl), tpl.Separator) + tpl.Suffix
+}
+
+// FormatFields возвращает отформатированные значения полей шаблона без разделителей
+func FormatFields(m *types.Measurement, tpl config.OutputTemplate) []string {
+    fields := tpl.Fields
+    if m.ArduinoPort == "" && len(tpl.WeightOnlyFields) > 0 {
+        fields = tpl.WeightOnlyFields
+    }
+    values := make([]string, len(fields))
+    for i, f := range fields {
+        values[i] = formatField(m, f)
+    }
+    return values
+}
+
+// ValidateTemplate проверяет имена полей и единицы измерения шаблона
+func ValidateTemplate(tpl config.OutputTemplate) error {
+    for _, f := range append(append([]config.TemplateField{}, tpl.Fields...), tpl.WeightOnlyFields...) {
+        kind, ok := fieldKinds[f.Field]
+        if !ok {
+            return fmt.Errorf("неизвестное поле шаблона: %s", f.Field)
+        }
+        if f.Unit == "" {
+            continue
+        }
+        if kind == kindWeight && !ValidWeightUnit(f.Unit) || kind == kindLength && !ValidLengthUnit(f.Unit) ||
+            kind == kindVolume && !ValidLengthUnit(f.Unit) {
+            return fmt.Errorf("неверная единица %s для поля %s", f.Unit, f.Field)
+        }
+    }
+    return nil
+}
+
+const (
+    kindWeight = iota
+    kindLength
+    kindVolume
+    kindText
+)
+
+var fieldKinds = map[string]int{
+    "weight":            kindWeight,
+    "volumetric_weight": kindWeight,
+    "chargeable_weight": kindWeight,
+    "length":            kindLength,
+    "width":             kindLength,
+    "height":            kindLength,
+    "volume":            kindVolume,
+    "barcode":           kindText,
+    "id":                kindText,
+    "station_id":        kindText,
+    "carrier":           kindText,
+    "timestamp":         kindText,
+    "text":              kindText,
+}
+
+func formatField(m *types.Measurement, f config.TemplateField) string {
+    var value string
+    switch f.Field {
+    case "weight":
+        value = formatWeight(m.Weight, f)
+    case "volumetric_weight":
+        value = formatWeight(m.VolumetricWeight, f)
+    case "chargeable_weight":
+        value = formatWeight(m.ChargeableWeight, f)
+    case "length":
+        value = formatLength(float64(m.Length), f)
+    case "width":
+        value = formatLength(float64(m.Width), f)
+    case "height":
+        value = formatLength(float64(m.Height), f)
+    case "volume":
+        unit := f.Unit
+        if unit == "" {
+            unit = "cm"
+        }
+        v, _ := ConvertVolume(float64(m.Volume), unit)
+        value = strconv.FormatFloat(v, 'f', f.Precision, 64)
+    case "barcode":
+        value = m.Barcode
+    case "id":
+        value = m.ID
+    case "station_id":
+        value = m.StationID
+    case "carrier":
+        value = m.Carrier
+    case "timestamp":
+        layout := f.Layout
+        if layout == "" {
+            layout = "2006-01-02 15:04:05"
+        }
+        value = m.Timestamp.In(time.Local).Format(layout)
+    case "text":
+        value = f.Text
+    }
+    return pad(value, f) + f.Label
+}
+
+// formatWeight выводит вес в единице поля шаблона, а без нее — в weight_unit станции
+func formatWeight(grams float64, f config.TemplateField) string {
+    unit := f.Unit
+    if unit == "" {
+        unit = config.Get().WeightUnit
+    }
+    if !ValidWeightUnit(unit) {
+        unit = "g"
+    }
+    v, _ := ConvertWeight(grams, unit)
+    return strconv.FormatFloat(v, 'f', f.Precision, 64)
+}
+
+func formatLength(cm float64, f config.TemplateField) string {
+    unit := f.Unit
+    if unit == "" {
+        unit = "cm"
+    }
+    v, _ := ConvertLength(cm, unit)
+    return strconv.FormatFloat(v, 'f', f.Precision, 64)
+}
+
+func pad(value string, f config.TemplateField) string {
+    n := f.Width - len([]rune(value))
+    if n <= 0 {
+        return value
+    }
+    padChar := f.Pad
+    if padChar == "" {
+        padChar = " "
+    }
+    return strings.Repeat(padChar, n) + value
+}
\ No newline at end of file
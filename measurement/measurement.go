@@ -0,0 +1,136 @@
+package measurement
+
+import (
+    "crypto/rand"
+    "encoding/hex"
+    "fmt"
+    "time"
+
+    "betelgeuze-measure-system-main/config"
+    "betelgeuze-measure-system-main/devices"
+    "betelgeuze-measure-system-main/history"
+    "betelgeuze-measure-system-main/logging"
+    "betelgeuze-measure-system-main/types"
+)
+
+// NewID возвращает уникальный идентификатор измерения.
+// Идентификаторы упорядочены по времени создания, что удобно для истории.
+func NewID() string {
+    buf := make([]byte, 4)
+    rand.Read(buf)
+    return fmt.Sprintf("%s-%s", time.Now().UTC().Format("20060102T150405.000"), hex.EncodeToString(buf))
+}
+
+// New создает запись полного измерения (вес + габариты) из уже считанных значений
+func New(state *types.AppState, weight float64, length, width, height int, trigger string) *types.Measurement {
+    m := newRecord(state, weight, length, width, height, trigger)
+    if state.Status.ArduinoConnected && state.Arduino != nil {
+        m.ArduinoPort = state.Arduino.PortName
+    }
+    m.Confidence = confidence(m)
+    return m
+}
+
+// NewWeight создает запись измерения только веса
+func NewWeight(state *types.AppState, weight float64, trigger string) *types.Measurement {
+    m := newRecord(state, weight, 0, 0, 0, trigger)
+    m.Confidence = confidence(m)
+    return m
+}
+
+func newRecord(state *types.AppState, weight float64, length, width, height int, trigger string) *types.Measurement {
+    m := &types.Measurement{
+        ID:           NewID(),
+        Timestamp:    time.Now().UTC(),
+        StationID:    config.Get().StationID,
+        Weight:       weight,
+        Unit:         "g", // весы отдают граммы; weight_unit применяется только при выводе
+        Length:       length,
+        Width:        width,
+        Height:       height,
+        Volume:       length * width * height,
+        Trigger:      trigger,
+        OutputStatus: types.OutputPending,
+    }
+    if state.Scale != nil {
+        m.ScalePort = state.Scale.PortName
+    }
+    if profile, ok := config.Get().CarrierProfile(""); ok {
+        ApplyCarrier(m, profile)
+    } else {
+        m.ChargeableWeight = m.Weight
+    }
+    return m
+}
+
+// Take выполняет полное измерение: вес с весов и габариты с Arduino (если подключен)
+func Take(state *types.AppState, trigger string) (*types.Measurement, error) {
+    if !state.Status.ScaleConnected {
+        return nil, fmt.Errorf("весы не подключены")
+    }
+    if WaitingForBarcode() {
+        return nil, ErrNoBarcode
+    }
+    weight, err := devices.ReadWeight(state.Scale)
+    if err != nil {
+        return nil, fmt.Errorf("ошибка чтения веса: %v", err)
+    }
+    var m *types.Measurement
+    if state.Status.ArduinoConnected {
+        length, width, height := ReadDimensions(state)
+        m = New(state, weight, length, width, height, trigger)
+    } else {
+        m = NewWeight(state, weight, trigger)
+    }
+    AttachBarcode(state, m)
+    return m, nil
+}
+
+// ReadDimensions запрашивает габариты у Arduino. Без Arduino возвращает нули.
+func ReadDimensions(state *types.AppState) (int, int, int) {
+    if !state.Status.ArduinoConnected {
+        return 0, 0, 0
+    }
+    state.Arduino.Mutex.Lock()
+    defer state.Arduino.Mutex.Unlock()
+    devices.SendCommandToArduino(state.Arduino, config.CMD_GET_DIMENSIONS)
+    return devices.GetDimensionsFromArduino(state.Arduino)
+}
+
+// Record сохраняет измерение как последнее в статусе устройств и в истории
+func Record(state *types.AppState, m *types.Measurement) {
+    state.Status.LastWeight = m.Weight
+    state.Status.LastDimensions = Format(m)
+    state.Status.LastMeasurement = m
+    state.Status.MeasurementCount++
+    save(m)
+}
+
+// SetOutputStatus обновляет статус вывода измерения и сохраняет новую версию в историю
+func SetOutputStatus(m *types.Measurement, status string) {
+    m.OutputStatus = status
+    save(m)
+}
+
+func save(m *types.Measurement) {
+    if err := history.Save(m); err != nil {
+        logging.Error(fmt.Sprintf("Ошибка сохранения измерения %s в историю: %v", m.ID, err), "system")
+    }
+}
+
+// confidence — доля валидных (ненулевых) значений среди ожидаемых
+func confidence(m *types.Measurement) float64 {
+    expected, valid := 1, 0
+    if m.Weight > 0 {
+        valid++
+    }
+    if m.ArduinoPort != "" {
+        expected += 3
+        for _, v := range []int{m.Length, m.Width, m.Height} {
+            if v > 0 {
+                valid++
+            }
+        }
+    }
+    return float64(valid) / float64(expected)
+}
\ No newline at end of file
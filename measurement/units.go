@@ -0,0 +1,60 @@
+package measurement
+
+import (
+    "fmt"
+    "strings"
+)
+
+// Коэффициенты перевода из базовых единиц хранения (граммы и сантиметры)
+var weightUnits = map[string]float64{
+    "g":  1,
+    "kg": 1000,
+    "lb": 453.59237,
+    "oz": 28.349523125,
+}
+
+var lengthUnits = map[string]float64{
+    "cm": 1,
+    "mm": 0.1,
+    "m":  100,
+    "in": 2.54,
+}
+
+// ConvertWeight переводит вес из граммов в указанную единицу
+func ConvertWeight(grams float64, unit string) (float64, error) {
+    k, ok := weightUnits[strings.ToLower(unit)]
+    if !ok {
+        return 0, fmt.Errorf("неизвестная единица веса: %s", unit)
+    }
+    return grams / k, nil
+}
+
+// ConvertLength переводит длину из сантиметров в указанную единицу
+func ConvertLength(cm float64, unit string) (float64, error) {
+    k, ok := lengthUnits[strings.ToLower(unit)]
+    if !ok {
+        return 0, fmt.Errorf("неизвестная единица длины: %s", unit)
+    }
+    return cm / k, nil
+}
+
+// ConvertVolume переводит объем из кубических сантиметров в куб указанной единицы длины
+func ConvertVolume(cm3 float64, unit string) (float64, error) {
+    k, ok := lengthUnits[strings.ToLower(unit)]
+    if !ok {
+        return 0, fmt.Errorf("неизвестная единица длины: %s", unit)
+    }
+    return cm3 / (k * k * k), nil
+}
+
+// ValidWeightUnit проверяет, поддерживается ли единица веса
+func ValidWeightUnit(unit string) bool {
+    _, ok := weightUnits[strings.ToLower(unit)]
+    return ok
+}
+
+// ValidLengthUnit проверяет, поддерживается ли единица длины
+func ValidLengthUnit(unit string) bool {
+    _, ok := lengthUnits[strings.ToLower(unit)]
+    return ok
+}
\ No newline at end of file
@@ -0,0 +1,47 @@
+package measurement
+
+import (
+    "math"
+
+    "betelgeuze-measure-system-main/config"
+    "betelgeuze-measure-system-main/types"
+)
+
+// VolumetricWeight возвращает объемный вес в граммах: объем (см³) / делитель (см³/кг)
+func VolumetricWeight(volume int, profile config.CarrierProfile) float64 {
+    if profile.Divisor <= 0 || volume <= 0 {
+        return 0
+    }
+    return float64(volume) / profile.Divisor * 1000
+}
+
+// ChargeableWeight возвращает оплачиваемый вес в граммах: большее из фактического
+// и объемного, округленное по правилам перевозчика, но не меньше минимального
+func ChargeableWeight(weight, volumetric float64, profile config.CarrierProfile) float64 {
+    kg := math.Max(weight, volumetric) / 1000
+    if profile.RoundingStep > 0 {
+        steps := kg / profile.RoundingStep
+        // Гасим погрешность деления, чтобы 1.5 / 0.5 не округлилось вверх до 4 шагов
+        steps = math.Round(steps*1e9) / 1e9
+        switch profile.RoundingMode {
+        case "down":
+            steps = math.Floor(steps)
+        case "nearest":
+            steps = math.Round(steps)
+        default:
+            steps = math.Ceil(steps)
+        }
+        kg = steps * profile.RoundingStep
+    }
+    if kg < profile.MinBillableWeight {
+        kg = profile.MinBillableWeight
+    }
+    return math.Round(kg * 1000)
+}
+
+// ApplyCarrier заполняет объемный и оплачиваемый вес измерения по профилю перевозчика
+func ApplyCarrier(m *types.Measurement, profile config.CarrierProfile) {
+    m.Carrier = profile.Name
+    m.VolumetricWeight = math.Round(VolumetricWeight(m.Volume, profile))
+    m.ChargeableWeight = ChargeableWeight(m.Weight, m.VolumetricWeight, profile)
+}
\ No newline at end of file
@@ -0,0 +1,222 @@
+package modbus
+
+import (
+    "bufio"
+    "encoding/binary"
+    "errors"
+    "fmt"
+    "io"
+    "net"
+    "time"
+
+    "betelgeuze-measure-system-main/logging"
+)
+
+// Функции Modbus
+const (
+    fnReadCoils              = 0x01
+    fnReadDiscreteInputs     = 0x02
+    fnReadHoldingRegisters   = 0x03
+    fnReadInputRegisters     = 0x04
+    fnWriteSingleCoil        = 0x05
+    fnWriteSingleRegister    = 0x06
+    fnWriteMultipleCoils     = 0x0F
+    fnWriteMultipleRegisters = 0x10
+)
+
+// Коды исключений Modbus
+const (
+    exIllegalFunction = 0x01
+    exIllegalAddress  = 0x02
+    exIllegalValue    = 0x03
+    exDeviceFailure   = 0x04
+)
+
+// IDLE_TIMEOUT — время, после которого молчащее соединение ПЛК закрывается
+const IDLE_TIMEOUT = 2 * time.Minute
+
+// exception — ошибка обработки запроса, которая возвращается ПЛК кодом исключения
+type exception byte
+
+func (e exception) Error() string {
+    return fmt.Sprintf("исключение Modbus %d", byte(e))
+}
+
+// Handler отвечает на запросы к данным устройства
+type Handler interface {
+    ReadCoils(addr, count uint16) ([]bool, error)
+    ReadDiscreteInputs(addr, count uint16) ([]bool, error)
+    ReadRegisters(addr, count uint16) ([]uint16, error)
+    WriteCoil(addr uint16, value bool) error
+}
+
+// Server — Modbus TCP slave
+type Server struct {
+    unitID  byte // 0 — отвечать на любой идентификатор устройства
+    handler Handler
+}
+
+// NewServer создает сервер для обработчика
+func NewServer(unitID byte, handler Handler) *Server {
+    return &Server{unitID: unitID, handler: handler}
+}
+
+// Serve принимает подключения ПЛК, пока слушатель не будет закрыт
+func (s *Server) Serve(listener net.Listener) error {
+    for {
+        conn, err := listener.Accept()
+        if err != nil {
+            return err
+        }
+        go s.serveConn(conn)
+    }
+}
+
+func (s *Server) serveConn(conn net.Conn) {
+    defer conn.Close()
+    reader := bufio.NewReader(conn)
+    header := make([]byte, 7)
+    for {
+        conn.SetReadDeadline(time.Now().Add(IDLE_TIMEOUT))
+        // MBAP: идентификатор транзакции, протокол (0), длина, идентификатор устройства
+        if _, err := io.ReadFull(reader, header); err != nil {
+            return
+        }
+        length := binary.BigEndian.Uint16(header[4:])
+        if binary.BigEndian.Uint16(header[2:]) != 0 || length < 2 || length > 254 {
+            logging.Warn(fmt.Sprintf("Modbus: неверный заголовок от %s", conn.RemoteAddr()), "system")
+            return
+        }
+        pdu := make([]byte, length-1)
+        if _, err := io.ReadFull(reader, pdu); err != nil {
+            return
+        }
+        unit := header[6]
+        if s.unitID != 0 && unit != s.unitID {
+            continue // запрос другому устройству на шине
+        }
+
+        response := s.handle(pdu)
+        out := make([]byte, 7, 7+len(response))
+        copy(out, header[:4])
+        binary.BigEndian.PutUint16(out[4:], uint16(len(response)+1))
+        out[6] = unit
+        out = append(out, response...)
+        if _, err := conn.Write(out); err != nil {
+            return
+        }
+    }
+}
+
+// handle выполняет запрос и возвращает PDU ответа
+func (s *Server) handle(pdu []byte) []byte {
+    fn := pdu[0]
+    response, err := s.dispatch(fn, pdu[1:])
+    if err != nil {
+        code := byte(exDeviceFailure)
+        var ex exception
+        if errors.As(err, &ex) {
+            code = byte(ex)
+        } else {
+            logging.Error(fmt.Sprintf("Modbus: ошибка выполнения функции %d: %v", fn, err), "system")
+        }
+        return []byte{fn | 0x80, code}
+    }
+    return append([]byte{fn}, response...)
+}
+
+func (s *Server) dispatch(fn byte, data []byte) ([]byte, error) {
+    switch fn {
+    case fnReadCoils, fnReadDiscreteInputs:
+        addr, count, err := readRange(data, 2000)
+        if err != nil {
+            return nil, err
+        }
+        var bits []bool
+        if fn == fnReadCoils {
+            bits, err = s.handler.ReadCoils(addr, count)
+        } else {
+            bits, err = s.handler.ReadDiscreteInputs(addr, count)
+        }
+        if err != nil {
+            return nil, err
+        }
+        return packBits(bits), nil
+
+    case fnReadHoldingRegisters, fnReadInputRegisters:
+        addr, count, err := readRange(data, 125)
+        if err != nil {
+            return nil, err
+        }
+        regs, err := s.handler.ReadRegisters(addr, count)
+        if err != nil {
+            return nil, err
+        }
+        out := []byte{byte(len(regs) * 2)}
+        for _, r := range regs {
+            out = binary.BigEndian.AppendUint16(out, r)
+        }
+        return out, nil
+
+    case fnWriteSingleCoil:
+        if len(data) != 4 {
+            return nil, exception(exIllegalValue)
+        }
+        addr := binary.BigEndian.Uint16(data)
+        value := binary.BigEndian.Uint16(data[2:])
+        if value != 0xFF00 && value != 0x0000 {
+            return nil, exception(exIllegalValue)
+        }
+        if err := s.handler.WriteCoil(addr, value == 0xFF00); err != nil {
+            return nil, err
+        }
+        return data, nil
+
+    case fnWriteMultipleCoils:
+        if len(data) < 5 {
+            return nil, exception(exIllegalValue)
+        }
+        addr := binary.BigEndian.Uint16(data)
+        count := binary.BigEndian.Uint16(data[2:])
+        if count == 0 || int(data[4]) != (int(count)+7)/8 || len(data) != 5+int(data[4]) {
+            return nil, exception(exIllegalValue)
+        }
+        for i := uint16(0); i < count; i++ {
+            value := data[5+i/8]&(1<<(i%8)) != 0
+            if err := s.handler.WriteCoil(addr+i, value); err != nil {
+                return nil, err
+            }
+        }
+        return data[:4], nil
+
+    case fnWriteSingleRegister, fnWriteMultipleRegisters:
+        // Регистры только для чтения: управление станцией — через катушки
+        return nil, exception(exIllegalAddress)
+
+    default:
+        return nil, exception(exIllegalFunction)
+    }
+}
+
+func readRange(data []byte, max uint16) (uint16, uint16, error) {
+    if len(data) != 4 {
+        return 0, 0, exception(exIllegalValue)
+    }
+    addr := binary.BigEndian.Uint16(data)
+    count := binary.BigEndian.Uint16(data[2:])
+    if count == 0 || count > max {
+        return 0, 0, exception(exIllegalValue)
+    }
+    return addr, count, nil
+}
+
+func packBits(bits []bool) []byte {
+    out := make([]byte, 1+(len(bits)+7)/8)
+    out[0] = byte(len(out) - 1)
+    for i, bit := range bits {
+        if bit {
+            out[1+i/8] |= 1 << (i % 8)
+        }
+    }
+    return out
+}
\ No newline at end of file
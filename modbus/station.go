@@ -0,0 +1,227 @@
+package modbus
+
+import (
+    "fmt"
+    "math"
+    "net"
+    "sync"
+
+    "betelgeuze-measure-system-main/config"
+    "betelgeuze-measure-system-main/devices"
+    "betelgeuze-measure-system-main/liveweight"
+    "betelgeuze-measure-system-main/logging"
+    "betelgeuze-measure-system-main/trigger"
+    "betelgeuze-measure-system-main/types"
+)
+
+// DEFAULT_ADDRESS — адрес Modbus TCP по умолчанию (стандартный порт 502)
+const DEFAULT_ADDRESS = ":502"
+
+// Входные регистры и регистры хранения (функции 3 и 4 читают одну и ту же карту).
+// 32-битные значения занимают два регистра, старшее слово первым.
+const (
+    RegCurrentWeight    = 0  // 0-1: текущее показание весов, г
+    RegLastWeight       = 2  // 2-3: вес последнего измерения, г
+    RegLastLength       = 4  // длина последнего измерения, см
+    RegLastWidth        = 5  // ширина, см
+    RegLastHeight       = 6  // высота, см
+    RegLastVolume       = 7  // 7-8: объем, см³
+    RegMeasurementCount = 9  // счетчик измерений (по модулю 65536): изменился — есть новое измерение
+    RegState            = 10 // биты состояния, как дискретные входы
+    RegOutputStatus     = 11 // статус вывода последнего измерения (OUTPUT_STATUS_CODES)
+    RegChargeableWeight = 12 // 12-13: оплачиваемый вес, г
+    registerCount       = 14
+)
+
+// Катушки: запись 1 в Trigger запускает измерение, в Tare — тарирование весов,
+// Led включает и выключает подсветку Arduino.
+const (
+    CoilTrigger = 0
+    CoilTare    = 1
+    CoilLed     = 2
+    coilCount   = 3
+)
+
+// Дискретные входы и биты регистра состояния
+const (
+    StateScaleConnected   = 0
+    StateArduinoConnected = 1
+    StateMeasuring        = 2 // идет измерение, запущенное катушкой
+    StateTriggerFailed    = 3 // последнее измерение по катушке завершилось ошибкой
+    StateHeadless         = 4
+    StatePaused           = 5 // автоматические измерения приостановлены
+    stateCount            = 6
+)
+
+// OUTPUT_STATUS_CODES — числовые коды статуса вывода для регистра RegOutputStatus
+var OUTPUT_STATUS_CODES = map[string]uint16{
+    types.OutputNone:      0,
+    types.OutputPending:   1,
+    types.OutputSent:      2,
+    types.OutputQueued:    3,
+    types.OutputPartial:   4,
+    types.OutputFailed:    5,
+    types.OutputRejected:  6,
+    types.OutputAwaiting:  7,
+    types.OutputDiscarded: 8,
+}
+
+// Station отдает ПЛК данные станции и выполняет команды катушек
+type Station struct {
+    state *types.AppState
+
+    mu            sync.Mutex
+    measuring     bool
+    triggerFailed bool
+    led           bool
+}
+
+// NewStation создает обработчик Modbus для станции
+func NewStation(state *types.AppState) *Station {
+    return &Station{state: state}
+}
+
+// Start запускает Modbus TCP сервер, если он настроен
+func Start(cfg *config.ModbusConfig, state *types.AppState) error {
+    if cfg == nil || !cfg.Enabled {
+        return nil
+    }
+    address := cfg.Address
+    if address == "" {
+        address = DEFAULT_ADDRESS
+    }
+    if cfg.UnitID < 0 || cfg.UnitID > 247 {
+        return fmt.Errorf("modbus: неверный unit_id %d", cfg.UnitID)
+    }
+    listener, err := net.Listen("tcp", address)
+    if err != nil {
+        return fmt.Errorf("modbus: %v", err)
+    }
+    logging.BroadcastLog(fmt.Sprintf("Modbus TCP сервер запущен на %s", address), "system")
+    server := NewServer(byte(cfg.UnitID), NewStation(state))
+    go server.Serve(listener)
+    return nil
+}
+
+func (s *Station) ReadRegisters(addr, count uint16) ([]uint16, error) {
+    if int(addr)+int(count) > registerCount {
+        return nil, exception(exIllegalAddress)
+    }
+    regs := make([]uint16, registerCount)
+    status := s.state.Status
+    putUint32(regs, RegCurrentWeight, status.CurrentWeight)
+    if m := status.LastMeasurement; m != nil {
+        putUint32(regs, RegLastWeight, m.Weight)
+        regs[RegLastLength] = uint16(m.Length)
+        regs[RegLastWidth] = uint16(m.Width)
+        regs[RegLastHeight] = uint16(m.Height)
+        putUint32(regs, RegLastVolume, float64(m.Volume))
+        regs[RegOutputStatus] = OUTPUT_STATUS_CODES[m.OutputStatus]
+        putUint32(regs, RegChargeableWeight, m.ChargeableWeight)
+    }
+    regs[RegMeasurementCount] = uint16(status.MeasurementCount)
+    for i, bit := range s.stateBits() {
+        if bit {
+            regs[RegState] |= 1 << i
+        }
+    }
+    return regs[addr : addr+count], nil
+}
+
+func (s *Station) ReadDiscreteInputs(addr, count uint16) ([]bool, error) {
+    if int(addr)+int(count) > stateCount {
+        return nil, exception(exIllegalAddress)
+    }
+    return s.stateBits()[addr : addr+count], nil
+}
+
+func (s *Station) ReadCoils(addr, count uint16) ([]bool, error) {
+    if int(addr)+int(count) > coilCount {
+        return nil, exception(exIllegalAddress)
+    }
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    coils := []bool{s.measuring, false, s.led}
+    return coils[addr : addr+count], nil
+}
+
+func (s *Station) WriteCoil(addr uint16, value bool) error {
+    switch addr {
+    case CoilTrigger:
+        if value {
+            return s.trigger()
+        }
+        return nil
+    case CoilTare:
+        if !value {
+            return nil
+        }
+        if !s.state.Status.ScaleConnected {
+            return exception(exDeviceFailure)
+        }
+        if err := liveweight.Tare(s.state); err != nil {
+            return err
+        }
+        logging.BroadcastLog("Modbus: тарирование весов", "scale")
+        return nil
+    case CoilLed:
+        if !s.state.Status.ArduinoConnected {
+            return exception(exDeviceFailure)
+        }
+        command := "led_off"
+        if value {
+            command = "led_on"
+        }
+        devices.ExecuteArduinoCommand(s.state.Arduino, command)
+        s.mu.Lock()
+        s.led = value
+        s.mu.Unlock()
+        return nil
+    default:
+        return exception(exIllegalAddress)
+    }
+}
+
+// trigger запускает измерение в фоне: ПЛК ждет смены счетчика измерений
+// или сброса катушки Trigger, а не ответа на запись.
+func (s *Station) trigger() error {
+    if !s.state.Status.ScaleConnected {
+        return exception(exDeviceFailure)
+    }
+    s.mu.Lock()
+    if s.measuring {
+        s.mu.Unlock()
+        return nil
+    }
+    s.measuring = true
+    s.mu.Unlock()
+
+    go func() {
+        _, err := trigger.Fire(types.TriggerModbus)
+        s.mu.Lock()
+        s.measuring = false
+        s.triggerFailed = err != nil
+        s.mu.Unlock()
+    }()
+    return nil
+}
+
+func (s *Station) stateBits() []bool {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    bits := make([]bool, stateCount)
+    bits[StateScaleConnected] = s.state.Status.ScaleConnected
+    bits[StateArduinoConnected] = s.state.Status.ArduinoConnected
+    bits[StateMeasuring] = s.measuring
+    bits[StateTriggerFailed] = s.triggerFailed
+    bits[StateHeadless] = s.state.Status.Headless
+    bits[StatePaused] = s.state.Status.Mode == types.ModePaused
+    return bits
+}
+
+// putUint32 записывает неотрицательное значение в два регистра, старшее слово первым
+func putUint32(regs []uint16, addr int, value float64) {
+    v := uint32(math.Min(math.Max(math.Round(value), 0), math.MaxUint32))
+    regs[addr] = uint16(v >> 16)
+    regs[addr+1] = uint16(v)
+}
\ No newline at end of file
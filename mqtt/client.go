@@ -0,0 +1,445 @@
+package mqtt
+
+import (
+    "bufio"
+    "context"
+    "crypto/tls"
+    "encoding/binary"
+    "errors"
+    "fmt"
+    "io"
+    "net"
+    "net/url"
+    "sync"
+    "time"
+)
+
+// Версии протокола
+const (
+    ProtocolV311 = 4 // MQTT 3.1.1
+    ProtocolV5   = 5 // MQTT 5.0
+)
+
+// Типы пакетов MQTT
+const (
+    packetConnect    = 1
+    packetConnack    = 2
+    packetPublish    = 3
+    packetPuback     = 4
+    packetPubrec     = 5
+    packetPubrel     = 6
+    packetPubcomp    = 7
+    packetPingreq    = 12
+    packetPingresp   = 13
+    packetDisconnect = 14
+)
+
+// ACK_TIMEOUT — время ожидания подтверждения от брокера
+const ACK_TIMEOUT = 10 * time.Second
+
+// ErrRejected — брокер MQTT 5 ответил на публикацию кодом причины ошибки (0x80 и выше)
+var ErrRejected = errors.New("брокер отклонил публикацию")
+
+// ack — подтверждение публикации: тип пакета и код причины (MQTT 5; в 3.1.1 всегда 0)
+type ack struct {
+    kind   byte
+    reason byte
+}
+
+// Will — сообщение "последней воли", которое брокер опубликует при обрыве соединения
+type Will struct {
+    Topic   string
+    Payload []byte
+    QoS     byte
+    Retain  bool
+}
+
+// Options — параметры подключения клиента
+type Options struct {
+    Broker          string // tcp://host:1883, ssl://host:8883
+    ClientID        string
+    Username        string
+    Password        string
+    ProtocolVersion byte
+    KeepAlive       time.Duration
+    TLS             *tls.Config
+    Will            *Will
+
+    // Dial позволяет подменить сетевое подключение, например на брокер-заглушку в памяти
+    Dial func(ctx context.Context) (net.Conn, error)
+}
+
+// Client — минимальный клиент MQTT 3.1.1/5 только для публикации
+type Client struct {
+    opts Options
+    conn net.Conn
+
+    writeMu  sync.Mutex
+    mu       sync.Mutex
+    nextID   uint16
+    pending  map[uint16]chan ack // ожидание PUBACK/PUBREC/PUBCOMP по ID пакета
+    closed   chan struct{}
+    closeErr error
+    once     sync.Once
+}
+
+// Connect подключается к брокеру и выполняет рукопожатие CONNECT/CONNACK
+func Connect(ctx context.Context, opts Options) (*Client, error) {
+    if opts.ProtocolVersion == 0 {
+        opts.ProtocolVersion = ProtocolV311
+    }
+    if opts.ProtocolVersion != ProtocolV311 && opts.ProtocolVersion != ProtocolV5 {
+        return nil, fmt.Errorf("неподдерживаемая версия MQTT: %d", opts.ProtocolVersion)
+    }
+    if opts.KeepAlive == 0 {
+        opts.KeepAlive = 30 * time.Second
+    }
+
+    conn, err := dial(ctx, opts)
+    if err != nil {
+        return nil, err
+    }
+    c := &Client{
+        opts:    opts,
+        conn:    conn,
+        pending: make(map[uint16]chan ack),
+        closed:  make(chan struct{}),
+    }
+
+    if deadline, ok := ctx.Deadline(); ok {
+        conn.SetDeadline(deadline)
+    }
+    if err := c.writePacket(packetConnect<<4, c.connectBody()); err != nil {
+        conn.Close()
+        return nil, err
+    }
+    reader := bufio.NewReader(conn)
+    header, body, err := readPacket(reader)
+    if err != nil {
+        conn.Close()
+        return nil, fmt.Errorf("нет ответа CONNACK: %v", err)
+    }
+    if header>>4 != packetConnack || len(body) < 2 {
+        conn.Close()
+        return nil, errors.New("брокер ответил не CONNACK")
+    }
+    if body[1] != 0 {
+        conn.Close()
+        return nil, fmt.Errorf("брокер отклонил подключение, код %d", body[1])
+    }
+    conn.SetDeadline(time.Time{})
+
+    go c.readLoop(reader)
+    go c.keepAlive()
+    return c, nil
+}
+
+func dial(ctx context.Context, opts Options) (net.Conn, error) {
+    if opts.Dial != nil {
+        return opts.Dial(ctx)
+    }
+    u, err := url.Parse(opts.Broker)
+    if err != nil {
+        return nil, fmt.Errorf("неверный адрес брокера: %v", err)
+    }
+    var d net.Dialer
+    switch u.Scheme {
+    case "tcp", "mqtt", "":
+        host := u.Host
+        if u.Port() == "" {
+            host = net.JoinHostPort(u.Hostname(), "1883")
+        }
+        return d.DialContext(ctx, "tcp", host)
+    case "ssl", "tls", "mqtts":
+        host := u.Host
+        if u.Port() == "" {
+            host = net.JoinHostPort(u.Hostname(), "8883")
+        }
+        cfg := opts.TLS
+        if cfg == nil {
+            cfg = &tls.Config{}
+        }
+        if cfg.ServerName == "" {
+            cfg = cfg.Clone()
+            cfg.ServerName = u.Hostname()
+        }
+        td := tls.Dialer{NetDialer: &d, Config: cfg}
+        return td.DialContext(ctx, "tcp", host)
+    default:
+        return nil, fmt.Errorf("неизвестная схема адреса брокера: %s", u.Scheme)
+    }
+}
+
+func (c *Client) connectBody() []byte {
+    var b []byte
+    b = appendString(b, "MQTT")
+    b = append(b, c.opts.ProtocolVersion)
+
+    var flags byte = 0x02 // clean session
+    if w := c.opts.Will; w != nil {
+        flags |= 0x04 | (w.QoS&0x03)<<3
+        if w.Retain {
+            flags |= 0x20
+        }
+    }
+    if c.opts.Username != "" {
+        flags |= 0x80
+    }
+    if c.opts.Password != "" {
+        flags |= 0x40
+    }
+    b = append(b, flags)
+    b = binary.BigEndian.AppendUint16(b, uint16(c.opts.KeepAlive/time.Second))
+    if c.opts.ProtocolVersion == ProtocolV5 {
+        b = append(b, 0) // свойства CONNECT
+    }
+
+    b = appendString(b, c.opts.ClientID)
+    if w := c.opts.Will; w != nil {
+        if c.opts.ProtocolVersion == ProtocolV5 {
+            b = append(b, 0) // свойства will
+        }
+        b = appendString(b, w.Topic)
+        b = appendBytes(b, w.Payload)
+    }
+    if c.opts.Username != "" {
+        b = appendString(b, c.opts.Username)
+    }
+    if c.opts.Password != "" {
+        b = appendString(b, c.opts.Password)
+    }
+    return b
+}
+
+// Publish публикует сообщение. Для QoS 1 и 2 ждет подтверждения брокера;
+// код причины MQTT 5 от 0x80 и выше возвращается как ErrRejected.
+func (c *Client) Publish(ctx context.Context, topic string, payload []byte, qos byte, retain bool) error {
+    if qos > 2 {
+        return fmt.Errorf("неверный QoS: %d", qos)
+    }
+    header := byte(packetPublish<<4) | qos<<1
+    if retain {
+        header |= 0x01
+    }
+
+    var id uint16
+    var acks chan ack
+    if qos > 0 {
+        id, acks = c.register()
+        defer c.unregister(id)
+    }
+
+    var b []byte
+    b = appendString(b, topic)
+    if qos > 0 {
+        b = binary.BigEndian.AppendUint16(b, id)
+    }
+    if c.opts.ProtocolVersion == ProtocolV5 {
+        b = append(b, 0) // свойства PUBLISH
+    }
+    b = append(b, payload...)
+    if err := c.writePacket(header, b); err != nil {
+        return err
+    }
+    if qos == 0 {
+        return nil
+    }
+
+    timeout := time.NewTimer(ACK_TIMEOUT)
+    defer timeout.Stop()
+    for {
+        select {
+        case a := <-acks:
+            // После PUBREC с ошибкой сообщение брокеру не передано: PUBREL не отправляется
+            if a.reason >= 0x80 {
+                return fmt.Errorf("%w, код 0x%02X", ErrRejected, a.reason)
+            }
+            switch {
+            case qos == 1 && a.kind == packetPuback:
+                return nil
+            case qos == 2 && a.kind == packetPubrec:
+                // Вторая фаза QoS 2: PUBREL → PUBCOMP
+                if err := c.writePacket(packetPubrel<<4|0x02, binary.BigEndian.AppendUint16(nil, id)); err != nil {
+                    return err
+                }
+            case qos == 2 && a.kind == packetPubcomp:
+                return nil
+            }
+        case <-timeout.C:
+            return errors.New("брокер не подтвердил публикацию")
+        case <-c.closed:
+            return c.err()
+        case <-ctx.Done():
+            return ctx.Err()
+        }
+    }
+}
+
+func (c *Client) register() (uint16, chan ack) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    for {
+        c.nextID++
+        if c.nextID == 0 {
+            continue
+        }
+        if _, busy := c.pending[c.nextID]; !busy {
+            break
+        }
+    }
+    ch := make(chan ack, 2)
+    c.pending[c.nextID] = ch
+    return c.nextID, ch
+}
+
+func (c *Client) unregister(id uint16) {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    delete(c.pending, id)
+}
+
+func (c *Client) readLoop(reader *bufio.Reader) {
+    for {
+        header, body, err := readPacket(reader)
+        if err != nil {
+            c.shutdown(fmt.Errorf("соединение с брокером потеряно: %v", err))
+            return
+        }
+        kind := header >> 4
+        switch kind {
+        case packetPuback, packetPubrec, packetPubcomp:
+            if len(body) < 2 {
+                continue
+            }
+            id := binary.BigEndian.Uint16(body)
+            var reason byte
+            if len(body) > 2 {
+                // MQTT 5: код причины после ID пакета; без него — 0 (успех)
+                reason = body[2]
+            }
+            c.mu.Lock()
+            ch := c.pending[id]
+            c.mu.Unlock()
+            if ch != nil {
+                select {
+                case ch <- ack{kind: kind, reason: reason}:
+                default:
+                }
+            }
+        case packetPingresp:
+        case packetDisconnect:
+            c.shutdown(errors.New("брокер закрыл соединение"))
+            return
+        }
+    }
+}
+
+func (c *Client) keepAlive() {
+    ticker := time.NewTicker(c.opts.KeepAlive / 2)
+    defer ticker.Stop()
+    for {
+        select {
+        case <-ticker.C:
+            if err := c.writePacket(packetPingreq<<4, nil); err != nil {
+                c.shutdown(err)
+                return
+            }
+        case <-c.closed:
+            return
+        }
+    }
+}
+
+// Done закрывается, когда соединение с брокером разорвано
+func (c *Client) Done() <-chan struct{} {
+    return c.closed
+}
+
+func (c *Client) err() error {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+    return c.closeErr
+}
+
+func (c *Client) shutdown(err error) {
+    c.once.Do(func() {
+        c.mu.Lock()
+        c.closeErr = err
+        c.mu.Unlock()
+        c.conn.Close()
+        close(c.closed)
+    })
+}
+
+// Disconnect корректно завершает сессию (брокер не публикует will)
+func (c *Client) Disconnect() {
+    c.writePacket(packetDisconnect<<4, nil)
+    c.shutdown(errors.New("клиент отключен"))
+}
+
+func (c *Client) writePacket(header byte, body []byte) error {
+    packet := []byte{header}
+    packet = appendVarint(packet, len(body))
+    packet = append(packet, body...)
+
+    c.writeMu.Lock()
+    defer c.writeMu.Unlock()
+    c.conn.SetWriteDeadline(time.Now().Add(ACK_TIMEOUT))
+    _, err := c.conn.Write(packet)
+    return err
+}
+
+func readPacket(r *bufio.Reader) (byte, []byte, error) {
+    header, err := r.ReadByte()
+    if err != nil {
+        return 0, nil, err
+    }
+    length, err := readVarint(r)
+    if err != nil {
+        return 0, nil, err
+    }
+    body := make([]byte, length)
+    if _, err := io.ReadFull(r, body); err != nil {
+        return 0, nil, err
+    }
+    return header, body, nil
+}
+
+func appendString(b []byte, s string) []byte {
+    return appendBytes(b, []byte(s))
+}
+
+func appendBytes(b []byte, data []byte) []byte {
+    b = binary.BigEndian.AppendUint16(b, uint16(len(data)))
+    return append(b, data...)
+}
+
+func appendVarint(b []byte, n int) []byte {
+    for {
+        digit := byte(n % 128)
+        n /= 128
+        if n > 0 {
+            digit |= 0x80
+        }
+        b = append(b, digit)
+        if n == 0 {
+            return b
+        }
+    }
+}
+
+func readVarint(r io.ByteReader) (int, error) {
+    value, multiplier := 0, 1
+    for i := 0; i < 4; i++ {
+        digit, err := r.ReadByte()
+        if err != nil {
+            return 0, err
+        }
+        value += int(digit&0x7F) * multiplier
+        if digit&0x80 == 0 {
+            return value, nil
+        }
+        multiplier *= 128
+    }
+    return 0, errors.New("неверная длина пакета")
+}
\ No newline at end of file
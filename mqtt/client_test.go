@@ -0,0 +1,315 @@
+package mqtt
+
+import (
+    "bufio"
+    "bytes"
+    "context"
+    "encoding/binary"
+    "errors"
+    "net"
+    "testing"
+    "time"
+
+    "betelgeuze-measure-system-main/config"
+)
+
+// broker — брокер-заглушка на другом конце net.Pipe
+type broker struct {
+    t    *testing.T
+    conn net.Conn
+    r    *bufio.Reader
+}
+
+func newBroker(t *testing.T) (*broker, func(ctx context.Context) (net.Conn, error)) {
+    client, server := net.Pipe()
+    b := &broker{t: t, conn: server, r: bufio.NewReader(server)}
+    t.Cleanup(func() { server.Close() })
+    return b, func(ctx context.Context) (net.Conn, error) { return client, nil }
+}
+
+func (b *broker) read() (byte, []byte) {
+    b.t.Helper()
+    b.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+    for {
+        header, body, err := readPacket(b.r)
+        if err != nil {
+            b.t.Fatalf("брокер: чтение пакета: %v", err)
+        }
+        // PINGREQ может прийти в любой момент
+        if header>>4 != packetPingreq {
+            return header, body
+        }
+    }
+}
+
+func (b *broker) write(header byte, body ...byte) {
+    b.t.Helper()
+    packet := appendVarint([]byte{header}, len(body))
+    if _, err := b.conn.Write(append(packet, body...)); err != nil {
+        b.t.Fatalf("брокер: запись пакета: %v", err)
+    }
+}
+
+// accept читает CONNECT и отвечает CONNACK
+func (b *broker) accept() connectPacket {
+    b.t.Helper()
+    header, body := b.read()
+    if header>>4 != packetConnect {
+        b.t.Fatalf("ожидался CONNECT, получен пакет %d", header>>4)
+    }
+    b.write(packetConnack<<4, 0, 0)
+    return parseConnect(b.t, body)
+}
+
+type connectPacket struct {
+    version     byte
+    flags       byte
+    keepAlive   uint16
+    properties  []byte // длина свойств CONNECT и will (MQTT 5)
+    clientID    string
+    willTopic   string
+    willPayload string
+    username    string
+    password    string
+}
+
+func parseConnect(t *testing.T, body []byte) connectPacket {
+    t.Helper()
+    var p connectPacket
+    str := func() string {
+        n := int(binary.BigEndian.Uint16(body))
+        s := string(body[2 : 2+n])
+        body = body[2+n:]
+        return s
+    }
+    if name := str(); name != "MQTT" {
+        t.Fatalf("имя протокола %q", name)
+    }
+    p.version, p.flags = body[0], body[1]
+    p.keepAlive = binary.BigEndian.Uint16(body[2:])
+    body = body[4:]
+    if p.version == ProtocolV5 {
+        p.properties = append(p.properties, body[0])
+        body = body[1:]
+    }
+    p.clientID = str()
+    if p.flags&0x04 != 0 {
+        if p.version == ProtocolV5 {
+            p.properties = append(p.properties, body[0])
+            body = body[1:]
+        }
+        p.willTopic, p.willPayload = str(), str()
+    }
+    if p.flags&0x80 != 0 {
+        p.username = str()
+    }
+    if p.flags&0x40 != 0 {
+        p.password = str()
+    }
+    if len(body) != 0 {
+        t.Fatalf("лишние байты в CONNECT: %v", body)
+    }
+    return p
+}
+
+func connect(t *testing.T, opts Options) (*Client, *broker) {
+    t.Helper()
+    b, dial := newBroker(t)
+    opts.Dial = dial
+    result := make(chan error, 1)
+    var c *Client
+    go func() {
+        var err error
+        c, err = Connect(context.Background(), opts)
+        result <- err
+    }()
+    b.accept()
+    if err := <-result; err != nil {
+        t.Fatalf("Connect: %v", err)
+    }
+    t.Cleanup(func() { c.shutdown(errors.New("тест завершен")) })
+    return c, b
+}
+
+func TestConnectFlags(t *testing.T) {
+    for _, version := range []byte{ProtocolV311, ProtocolV5} {
+        b, dial := newBroker(t)
+        opts := Options{
+            ClientID:        "station-1",
+            Username:        "user",
+            Password:        "secret",
+            ProtocolVersion: version,
+            KeepAlive:       20 * time.Second,
+            Will:            &Will{Topic: "st/status", Payload: []byte("offline"), QoS: 1, Retain: true},
+            Dial:            dial,
+        }
+        done := make(chan error, 1)
+        go func() {
+            c, err := Connect(context.Background(), opts)
+            if err == nil {
+                c.shutdown(errors.New("тест завершен"))
+            }
+            done <- err
+        }()
+        p := b.accept()
+        if err := <-done; err != nil {
+            t.Fatalf("v%d: Connect: %v", version, err)
+        }
+
+        // clean session 0x02, will 0x04, will QoS 1 0x08, will retain 0x20, пароль 0x40, пользователь 0x80
+        if want := byte(0x02 | 0x04 | 0x08 | 0x20 | 0x40 | 0x80); p.flags != want {
+            t.Errorf("v%d: флаги CONNECT %08b, ожидалось %08b", version, p.flags, want)
+        }
+        if p.version != version || p.keepAlive != 20 || p.clientID != "station-1" {
+            t.Errorf("v%d: версия %d, keep alive %d, client id %q", version, p.version, p.keepAlive, p.clientID)
+        }
+        if p.willTopic != "st/status" || p.willPayload != "offline" || p.username != "user" || p.password != "secret" {
+            t.Errorf("v%d: will %q/%q, пользователь %q/%q", version, p.willTopic, p.willPayload, p.username, p.password)
+        }
+        // MQTT 5: пустые свойства CONNECT и will
+        wantProps := []byte(nil)
+        if version == ProtocolV5 {
+            wantProps = []byte{0, 0}
+        }
+        if !bytes.Equal(p.properties, wantProps) {
+            t.Errorf("v%d: свойства %v, ожидалось %v", version, p.properties, wantProps)
+        }
+    }
+}
+
+func TestConnectWithoutWillAndCredentials(t *testing.T) {
+    b, dial := newBroker(t)
+    go Connect(context.Background(), Options{ClientID: "x", Dial: dial})
+    if p := b.accept(); p.flags != 0x02 {
+        t.Errorf("флаги CONNECT %08b, ожидался только clean session", p.flags)
+    }
+}
+
+func TestPublishV5Properties(t *testing.T) {
+    c, b := connect(t, Options{ProtocolVersion: ProtocolV5})
+    go c.Publish(context.Background(), "t", []byte("data"), 0, true)
+    header, body := b.read()
+    if header != packetPublish<<4|0x01 {
+        t.Errorf("заголовок PUBLISH 0x%02X", header)
+    }
+    // топик, длина свойств 0, данные
+    if want := []byte{0, 1, 't', 0, 'd', 'a', 't', 'a'}; !bytes.Equal(body, want) {
+        t.Errorf("PUBLISH %v, ожидалось %v", body, want)
+    }
+}
+
+func TestPublishQoS1(t *testing.T) {
+    c, b := connect(t, Options{})
+    result := make(chan error, 1)
+    go func() { result <- c.Publish(context.Background(), "t", []byte("x"), 1, false) }()
+
+    header, body := b.read()
+    if header != packetPublish<<4|0x02 {
+        t.Fatalf("заголовок PUBLISH 0x%02X", header)
+    }
+    id := body[3:5] // после топика "t"
+    b.write(packetPuback<<4, id...)
+    if err := <-result; err != nil {
+        t.Fatalf("Publish: %v", err)
+    }
+}
+
+func TestPublishQoS2(t *testing.T) {
+    c, b := connect(t, Options{})
+    result := make(chan error, 1)
+    go func() { result <- c.Publish(context.Background(), "t", []byte("x"), 2, false) }()
+
+    header, body := b.read()
+    if header != packetPublish<<4|0x04 {
+        t.Fatalf("заголовок PUBLISH 0x%02X", header)
+    }
+    id := body[3:5]
+    b.write(packetPubrec<<4, id...)
+    header, body = b.read()
+    if header != packetPubrel<<4|0x02 || !bytes.Equal(body, id) {
+        t.Fatalf("ожидался PUBREL %v, получено 0x%02X %v", id, header, body)
+    }
+    select {
+    case err := <-result:
+        t.Fatalf("Publish завершился до PUBCOMP: %v", err)
+    default:
+    }
+    b.write(packetPubcomp<<4, id...)
+    if err := <-result; err != nil {
+        t.Fatalf("Publish: %v", err)
+    }
+}
+
+func TestPublishV5ReasonCodes(t *testing.T) {
+    c, b := connect(t, Options{ProtocolVersion: ProtocolV5})
+
+    // PUBACK с кодом 0x87 (не авторизован) — ошибка
+    result := make(chan error, 1)
+    go func() { result <- c.Publish(context.Background(), "t", nil, 1, false) }()
+    _, body := b.read()
+    b.write(packetPuback<<4, body[3], body[4], 0x87, 0)
+    if err := <-result; !errors.Is(err, ErrRejected) {
+        t.Errorf("PUBACK 0x87: ожидалась ErrRejected, получено %v", err)
+    }
+
+    // PUBACK с кодом 0x10 (нет подписчиков) — успех
+    go func() { result <- c.Publish(context.Background(), "t", nil, 1, false) }()
+    _, body = b.read()
+    b.write(packetPuback<<4, body[3], body[4], 0x10, 0)
+    if err := <-result; err != nil {
+        t.Errorf("PUBACK 0x10: %v", err)
+    }
+
+    // PUBREC с ошибкой — без PUBREL
+    go func() { result <- c.Publish(context.Background(), "t", nil, 2, false) }()
+    _, body = b.read()
+    b.write(packetPubrec<<4, body[3], body[4], 0x97, 0)
+    if err := <-result; !errors.Is(err, ErrRejected) {
+        t.Errorf("PUBREC 0x97: ожидалась ErrRejected, получено %v", err)
+    }
+    b.conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+    for {
+        header, _, err := readPacket(b.r)
+        if err != nil {
+            break
+        }
+        if header>>4 == packetPubrel {
+            t.Fatal("PUBREL отправлен после PUBREC с ошибкой")
+        }
+    }
+}
+
+func TestPublisherReconnects(t *testing.T) {
+    p, err := NewPublisher(config.MQTTConfig{Broker: "tcp://stub", QoS: 1}, "st1")
+    if err != nil {
+        t.Fatal(err)
+    }
+    brokers := make(chan *broker, 2)
+    p.SetDial(func(ctx context.Context) (net.Conn, error) {
+        b, dial := newBroker(t)
+        brokers <- b
+        return dial(ctx)
+    })
+    go p.run()
+
+    for i := 0; i < 2; i++ {
+        var b *broker
+        select {
+        case b = <-brokers:
+        case <-time.After(5 * time.Second):
+            t.Fatalf("подключение %d не выполнено", i+1)
+        }
+        if c := b.accept(); c.willTopic != "betelgeuze/st1/status" || c.willPayload != STATUS_OFFLINE {
+            t.Errorf("will %q/%q", c.willTopic, c.willPayload)
+        }
+        // После подключения публикуется online
+        header, body := b.read()
+        if header>>4 != packetPublish || !bytes.HasSuffix(body, []byte(STATUS_ONLINE)) {
+            t.Fatalf("ожидался PUBLISH online, получено 0x%02X %q", header, body)
+        }
+        topicLen := int(binary.BigEndian.Uint16(body))
+        b.write(packetPuback<<4, body[2+topicLen:4+topicLen]...)
+        // Брокер обрывает соединение: публикатор должен подключиться снова
+        b.conn.Close()
+    }
+}
\ No newline at end of file
@@ -0,0 +1,366 @@
+package mqtt
+
+import (
+    "context"
+    "crypto/tls"
+    "crypto/x509"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net"
+    "os"
+    "strings"
+    "sync"
+    "time"
+
+    "betelgeuze-measure-system-main/config"
+    "betelgeuze-measure-system-main/logging"
+    "betelgeuze-measure-system-main/types"
+)
+
+// Сообщения в топике <prefix>/status
+const (
+    STATUS_ONLINE  = "online"
+    STATUS_OFFLINE = "offline"
+)
+
+// MAX_RECONNECT_DELAY — наибольшая пауза между попытками подключения к брокеру
+const MAX_RECONNECT_DELAY = time.Minute
+
+// Publisher держит соединение с брокером и публикует события станции
+type Publisher struct {
+    opts           Options
+    prefix         string
+    stationID      string
+    qos            byte
+    weightInterval time.Duration
+    healthInterval time.Duration
+
+    mu         sync.Mutex
+    client     *Client
+    lastWeight time.Time
+    devices    map[string]devicePayload // последнее состояние устройств для повтора после переподключения
+}
+
+type weightPayload struct {
+    StationID string    `json:"station_id"`
+    Weight    float64   `json:"weight"`
+    Unit      string    `json:"unit"`
+    Timestamp time.Time `json:"timestamp"`
+}
+
+type devicePayload struct {
+    StationID string    `json:"station_id"`
+    Device    string    `json:"device"`
+    Connected bool      `json:"connected"`
+    Port      string    `json:"port"`
+    Timestamp time.Time `json:"timestamp"`
+}
+
+type healthPayload struct {
+    StationID        string    `json:"station_id"`
+    ArduinoConnected bool      `json:"arduino_connected"`
+    ArduinoPort      string    `json:"arduino_port"`
+    ScaleConnected   bool      `json:"scale_connected"`
+    ScalePort        string    `json:"scale_port"`
+    LastWeight       float64   `json:"last_weight"`
+    LastMeasurement  string    `json:"last_measurement,omitempty"`
+    LastConfidence   float64   `json:"last_confidence,omitempty"`
+    Headless         bool      `json:"headless"`
+    Timestamp        time.Time `json:"timestamp"`
+}
+
+var defaultPublisher *Publisher
+
+// Init настраивает публикацию в MQTT и запускает подключение к брокеру в фоне.
+// Без настройки mqtt публикация отключена, и функции пакета ничего не делают.
+func Init(cfg *config.MQTTConfig, stationID string) error {
+    if cfg == nil || cfg.Broker == "" {
+        return nil
+    }
+    p, err := NewPublisher(*cfg, stationID)
+    if err != nil {
+        return err
+    }
+    defaultPublisher = p
+    go p.run()
+    return nil
+}
+
+// Default возвращает публикатор станции или nil, если MQTT не настроен
+func Default() *Publisher {
+    return defaultPublisher
+}
+
+// NewPublisher проверяет настройки и готовит параметры подключения
+func NewPublisher(cfg config.MQTTConfig, stationID string) (*Publisher, error) {
+    if cfg.QoS < 0 || cfg.QoS > 2 {
+        return nil, fmt.Errorf("mqtt: неверный qos %d", cfg.QoS)
+    }
+    if cfg.ProtocolVersion == 0 {
+        cfg.ProtocolVersion = ProtocolV311
+    }
+    if cfg.ProtocolVersion != ProtocolV311 && cfg.ProtocolVersion != ProtocolV5 {
+        return nil, fmt.Errorf("mqtt: неподдерживаемая версия протокола %d", cfg.ProtocolVersion)
+    }
+    prefix := strings.TrimSuffix(cfg.TopicPrefix, "/")
+    if prefix == "" {
+        prefix = "betelgeuze/" + stationID
+    }
+    clientID := cfg.ClientID
+    if clientID == "" {
+        clientID = "betelgeuze-" + stationID
+    }
+
+    opts := Options{
+        Broker:          cfg.Broker,
+        ClientID:        clientID,
+        Username:        cfg.Username,
+        Password:        cfg.Password,
+        ProtocolVersion: byte(cfg.ProtocolVersion),
+        KeepAlive:       time.Duration(cfg.KeepAliveSec) * time.Second,
+        Will: &Will{
+            Topic:   prefix + "/status",
+            Payload: []byte(STATUS_OFFLINE),
+            QoS:     byte(cfg.QoS),
+            Retain:  true,
+        },
+    }
+    if cfg.TLS != nil {
+        tlsConfig, err := loadTLS(cfg.TLS)
+        if err != nil {
+            return nil, err
+        }
+        opts.TLS = tlsConfig
+    }
+
+    p := &Publisher{
+        opts:           opts,
+        prefix:         prefix,
+        stationID:      stationID,
+        qos:            byte(cfg.QoS),
+        weightInterval: time.Duration(cfg.WeightIntervalMs) * time.Millisecond,
+        healthInterval: time.Duration(cfg.HealthIntervalSec) * time.Second,
+        devices:        make(map[string]devicePayload),
+    }
+    if p.weightInterval == 0 {
+        p.weightInterval = time.Second
+    }
+    if p.healthInterval == 0 {
+        p.healthInterval = 30 * time.Second
+    }
+    return p, nil
+}
+
+func loadTLS(cfg *config.MQTTTLSConfig) (*tls.Config, error) {
+    tlsConfig := &tls.Config{InsecureSkipVerify: cfg.InsecureSkipVerify}
+    if cfg.CAFile != "" {
+        pem, err := os.ReadFile(cfg.CAFile)
+        if err != nil {
+            return nil, fmt.Errorf("mqtt: %v", err)
+        }
+        pool := x509.NewCertPool()
+        if !pool.AppendCertsFromPEM(pem) {
+            return nil, fmt.Errorf("mqtt: в %s нет сертификатов", cfg.CAFile)
+        }
+        tlsConfig.RootCAs = pool
+    }
+    if cfg.CertFile != "" || cfg.KeyFile != "" {
+        cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+        if err != nil {
+            return nil, fmt.Errorf("mqtt: клиентский сертификат: %v", err)
+        }
+        tlsConfig.Certificates = []tls.Certificate{cert}
+    }
+    return tlsConfig, nil
+}
+
+// SetDial подменяет сетевое подключение, например на брокер-заглушку в памяти
+func (p *Publisher) SetDial(dial func(ctx context.Context) (net.Conn, error)) {
+    p.opts.Dial = dial
+}
+
+// Topic возвращает полное имя топика станции
+func (p *Publisher) Topic(name string) string {
+    return p.prefix + "/" + name
+}
+
+// run поддерживает соединение: переподключается с растущей паузой после обрыва
+func (p *Publisher) run() {
+    delay := time.Second
+    for {
+        ctx, cancel := context.WithTimeout(context.Background(), ACK_TIMEOUT)
+        client, err := Connect(ctx, p.opts)
+        cancel()
+        if err != nil {
+            logging.Error(fmt.Sprintf("MQTT: не удалось подключиться к %s: %v", p.opts.Broker, err), "system")
+            time.Sleep(delay)
+            delay = min(delay*2, MAX_RECONNECT_DELAY)
+            continue
+        }
+        delay = time.Second
+        logging.BroadcastLog(fmt.Sprintf("MQTT: подключено к %s", p.opts.Broker), "system")
+
+        p.mu.Lock()
+        p.client = client
+        p.mu.Unlock()
+        p.announce(client)
+
+        <-client.Done()
+        p.mu.Lock()
+        p.client = nil
+        p.mu.Unlock()
+        logging.Error(fmt.Sprintf("MQTT: %v", client.err()), "system")
+    }
+}
+
+// announce публикует "online" и последнее известное состояние устройств
+func (p *Publisher) announce(client *Client) {
+    ctx, cancel := context.WithTimeout(context.Background(), ACK_TIMEOUT)
+    defer cancel()
+    if err := client.Publish(ctx, p.Topic("status"), []byte(STATUS_ONLINE), p.qos, true); err != nil {
+        logging.Error(fmt.Sprintf("MQTT: ошибка публикации статуса: %v", err), "system")
+        return
+    }
+    p.mu.Lock()
+    devices := make([]devicePayload, 0, len(p.devices))
+    for _, d := range p.devices {
+        devices = append(devices, d)
+    }
+    p.mu.Unlock()
+    for _, d := range devices {
+        p.publishJSON(ctx, "device/"+d.Device, d, p.qos, true)
+    }
+}
+
+// Publish публикует сообщение в топик; без соединения с брокером возвращает ошибку
+func (p *Publisher) Publish(ctx context.Context, topic string, payload []byte, qos byte, retain bool) error {
+    p.mu.Lock()
+    client := p.client
+    p.mu.Unlock()
+    if client == nil {
+        return errors.New("нет соединения с брокером MQTT")
+    }
+    return client.Publish(ctx, topic, payload, qos, retain)
+}
+
+func (p *Publisher) publishJSON(ctx context.Context, name string, v any, qos byte, retain bool) error {
+    data, err := json.Marshal(v)
+    if err != nil {
+        return err
+    }
+    return p.Publish(ctx, p.Topic(name), data, qos, retain)
+}
+
+// PublishMeasurement публикует готовое измерение; пустой topic — <prefix>/measurement
+func (p *Publisher) PublishMeasurement(ctx context.Context, topic string, payload []byte) error {
+    if topic == "" {
+        topic = p.Topic("measurement")
+    }
+    return p.Publish(ctx, topic, payload, p.qos, false)
+}
+
+// PublishWeight публикует живой вес с QoS 0 не чаще weight_interval_ms
+func (p *Publisher) PublishWeight(weight float64) {
+    p.mu.Lock()
+    if time.Since(p.lastWeight) < p.weightInterval {
+        p.mu.Unlock()
+        return
+    }
+    p.lastWeight = time.Now()
+    p.mu.Unlock()
+
+    // Публикация в фоне, чтобы медленный брокер не задерживал цикл измерений
+    go func() {
+        ctx, cancel := context.WithTimeout(context.Background(), ACK_TIMEOUT)
+        defer cancel()
+        p.publishJSON(ctx, "weight", weightPayload{
+            StationID: p.stationID,
+            Weight:    weight,
+            Unit:      "g",
+            Timestamp: time.Now(),
+        }, 0, false)
+    }()
+}
+
+// PublishDevice публикует подключение или отключение устройства (сообщение сохраняется брокером)
+func (p *Publisher) PublishDevice(device string, connected bool, port string) {
+    d := devicePayload{
+        StationID: p.stationID,
+        Device:    device,
+        Connected: connected,
+        Port:      port,
+        Timestamp: time.Now(),
+    }
+    p.mu.Lock()
+    p.devices[device] = d
+    p.mu.Unlock()
+
+    ctx, cancel := context.WithTimeout(context.Background(), ACK_TIMEOUT)
+    defer cancel()
+    if err := p.publishJSON(ctx, "device/"+device, d, p.qos, true); err != nil {
+        logging.Warn(fmt.Sprintf("MQTT: состояние %s не опубликовано: %v", device, err), "system")
+    }
+}
+
+// Watch следит за состоянием станции: публикует подключение и отключение устройств
+// сразу после изменения, а сводку о здоровье датчиков — раз в health_interval_sec.
+func (p *Publisher) Watch(state *types.AppState) {
+    arduino, scale := !state.Status.ArduinoConnected, !state.Status.ScaleConnected
+    health := time.NewTicker(p.healthInterval)
+    poll := time.NewTicker(time.Second)
+    defer health.Stop()
+    defer poll.Stop()
+
+    for {
+        status := state.Status
+        if status.ArduinoConnected != arduino {
+            arduino = status.ArduinoConnected
+            p.PublishDevice("arduino", arduino, status.ArduinoPort)
+        }
+        if status.ScaleConnected != scale {
+            scale = status.ScaleConnected
+            p.PublishDevice("scale", scale, status.ScalePort)
+        }
+
+        select {
+        case <-health.C:
+            p.publishHealth(status)
+        case <-poll.C:
+        }
+    }
+}
+
+func (p *Publisher) publishHealth(status types.DeviceStatus) {
+    h := healthPayload{
+        StationID:        p.stationID,
+        ArduinoConnected: status.ArduinoConnected,
+        ArduinoPort:      status.ArduinoPort,
+        ScaleConnected:   status.ScaleConnected,
+        ScalePort:        status.ScalePort,
+        LastWeight:       status.LastWeight,
+        Headless:         status.Headless,
+        Timestamp:        time.Now(),
+    }
+    if m := status.LastMeasurement; m != nil {
+        h.LastMeasurement = m.ID
+        h.LastConfidence = m.Confidence
+    }
+    ctx, cancel := context.WithTimeout(context.Background(), ACK_TIMEOUT)
+    defer cancel()
+    p.publishJSON(ctx, "health", h, p.qos, true)
+}
+
+// PublishWeight публикует живой вес, если MQTT настроен
+func PublishWeight(weight float64) {
+    if p := defaultPublisher; p != nil {
+        p.PublishWeight(weight)
+    }
+}
+
+// Watch запускает слежение за состоянием станции, если MQTT настроен
+func Watch(state *types.AppState) {
+    if p := defaultPublisher; p != nil {
+        go p.Watch(state)
+    }
+}
\ No newline at end of file
@@ -0,0 +1,391 @@
+package outbox
+
+import (
+    "context"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "math/rand"
+    "os"
+    "path/filepath"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+
+    "betelgeuze-measure-system-main/logging"
+)
+
+// Очереди доставки
+const (
+    QueuePending = "pending"
+    QueueDead    = "dead"
+)
+
+const (
+    DEFAULT_MAX_ATTEMPTS = 10
+    BASE_RETRY_DELAY     = 2 * time.Second
+    MAX_RETRY_DELAY      = 10 * time.Minute
+    POLL_INTERVAL        = 1 * time.Second
+)
+
+// ErrDead — попытки доставки исчерпаны, запись перенесена в очередь мертвых писем
+var ErrDead = errors.New("доставка перенесена в мертвые письма")
+
+// ErrNotFound — записи с таким id нет в очереди
+var ErrNotFound = errors.New("доставка не найдена")
+
+// Item — одна доставка в очереди. Хранится отдельным JSON-файлом,
+// поэтому переживает перезапуск программы и сбой питания.
+type Item struct {
+    ID            string          `json:"id"`
+    Key           string          `json:"key"` // ключ идемпотентности
+    MeasurementID string          `json:"measurement_id"`
+    Payload       json.RawMessage `json:"payload"`
+    Attempts      int             `json:"attempts"`
+    CreatedAt     time.Time       `json:"created_at"`
+    NextAttempt   time.Time       `json:"next_attempt"`
+    LastError     string          `json:"last_error,omitempty"`
+    Queue         string          `json:"queue"`
+}
+
+// SendFunc выполняет одну попытку доставки; отмена ctx прерывает попытку
+type SendFunc func(ctx context.Context, item *Item) error
+
+// DeliveredFunc вызывается, когда фоновая повторная доставка завершилась (успешно или ушла
+// в очередь мертвых писем). О результате Attempt узнает сам вызывающий, для него DeliveredFunc не вызывается.
+type DeliveredFunc func(item *Item, err error)
+
+// Queue — очередь доставки на диске с повторами по экспоненциальной задержке.
+// После MaxAttempts неудачных попыток запись переносится в очередь мертвых писем (dead).
+type Queue struct {
+    Name        string
+    dir         string
+    maxAttempts int
+    send        SendFunc
+    onDone      DeliveredFunc
+
+    mu       sync.Mutex
+    inflight map[string]bool
+    wake     chan struct{}
+}
+
+var (
+    queues      = make(map[string]*Queue)
+    queuesMutex sync.RWMutex
+)
+
+// Open открывает (или создает) очередь в каталоге dir и регистрирует ее по имени
+func Open(name, dir string, maxAttempts int, send SendFunc, onDone DeliveredFunc) (*Queue, error) {
+    if maxAttempts <= 0 {
+        maxAttempts = DEFAULT_MAX_ATTEMPTS
+    }
+    for _, sub := range []string{QueuePending, QueueDead} {
+        if err := os.MkdirAll(filepath.Join(dir, sub), 0755); err != nil {
+            return nil, err
+        }
+    }
+    q := &Queue{
+        Name:        name,
+        dir:         dir,
+        maxAttempts: maxAttempts,
+        send:        send,
+        onDone:      onDone,
+        inflight:    make(map[string]bool),
+        wake:        make(chan struct{}, 1),
+    }
+    queuesMutex.Lock()
+    queues[name] = q
+    queuesMutex.Unlock()
+    return q, nil
+}
+
+// Get возвращает зарегистрированную очередь по имени
+func Get(name string) *Queue {
+    queuesMutex.RLock()
+    defer queuesMutex.RUnlock()
+    return queues[name]
+}
+
+// All возвращает все зарегистрированные очереди, отсортированные по имени
+func All() []*Queue {
+    queuesMutex.RLock()
+    defer queuesMutex.RUnlock()
+    list := make([]*Queue, 0, len(queues))
+    for _, q := range queues {
+        list = append(list, q)
+    }
+    sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+    return list
+}
+
+func (q *Queue) path(queue, id string) string {
+    return filepath.Join(q.dir, queue, id+".json")
+}
+
+// write атомарно сохраняет запись: через временный файл и переименование
+func (q *Queue) write(item *Item) error {
+    data, err := json.Marshal(item)
+    if err != nil {
+        return err
+    }
+    target := q.path(item.Queue, item.ID)
+    tmp := target + ".tmp"
+    f, err := os.Create(tmp)
+    if err != nil {
+        return err
+    }
+    if _, err := f.Write(data); err != nil {
+        f.Close()
+        os.Remove(tmp)
+        return err
+    }
+    if err := f.Sync(); err != nil {
+        f.Close()
+        os.Remove(tmp)
+        return err
+    }
+    if err := f.Close(); err != nil {
+        os.Remove(tmp)
+        return err
+    }
+    return os.Rename(tmp, target)
+}
+
+func (q *Queue) read(queue, id string) (*Item, error) {
+    data, err := os.ReadFile(q.path(queue, id))
+    if err != nil {
+        return nil, err
+    }
+    var item Item
+    if err := json.Unmarshal(data, &item); err != nil {
+        return nil, err
+    }
+    item.Queue = queue
+    return &item, nil
+}
+
+// find ищет запись по id среди записей очереди. id из запроса попадает в путь к файлу,
+// только если такая запись есть в списке: "../../betelgeuze" не выйдет за каталог очереди.
+func (q *Queue) find(queue, id string) (*Item, error) {
+    if id == "" || filepath.Base(id) != id || strings.Contains(id, "..") {
+        return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+    }
+    items, err := q.List(queue)
+    if err != nil {
+        return nil, err
+    }
+    for _, item := range items {
+        if item.ID == id {
+            return item, nil
+        }
+    }
+    return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+}
+
+// Enqueue сохраняет доставку в очередь. Повторная постановка с тем же ID заменяет запись.
+func (q *Queue) Enqueue(item *Item) error {
+    if item.CreatedAt.IsZero() {
+        item.CreatedAt = time.Now().UTC()
+    }
+    if item.NextAttempt.IsZero() {
+        item.NextAttempt = item.CreatedAt
+    }
+    item.Queue = QueuePending
+    return q.write(item)
+}
+
+// Attempt выполняет попытку доставки записи сразу, не дожидаясь фонового обработчика.
+// Возвращает ошибку попытки; при ошибке (в том числе при отмене ctx) запись остается в очереди,
+// а после последней попытки переносится в мертвые письма и ошибка оборачивает ErrDead.
+func (q *Queue) Attempt(ctx context.Context, id string) error {
+    return q.tryAttempt(ctx, id, false)
+}
+
+// tryAttempt выполняет попытку, если запись уже не доставляется; notify — сообщить результат в onDone
+func (q *Queue) tryAttempt(ctx context.Context, id string, notify bool) error {
+    q.mu.Lock()
+    if q.inflight[id] {
+        q.mu.Unlock()
+        return errors.New("доставка уже выполняется")
+    }
+    q.inflight[id] = true
+    q.mu.Unlock()
+    defer func() {
+        q.mu.Lock()
+        delete(q.inflight, id)
+        q.mu.Unlock()
+    }()
+
+    item, err := q.read(QueuePending, id)
+    if err != nil {
+        return err
+    }
+    return q.attempt(ctx, item, notify)
+}
+
+// attempt отправляет запись и переносит ее по результату
+func (q *Queue) attempt(ctx context.Context, item *Item, notify bool) error {
+    err := q.send(ctx, item)
+    item.Attempts++
+    if err == nil {
+        os.Remove(q.path(QueuePending, item.ID))
+        if notify && q.onDone != nil {
+            q.onDone(item, nil)
+        }
+        return nil
+    }
+
+    item.LastError = err.Error()
+    if item.Attempts >= q.maxAttempts {
+        item.Queue = QueueDead
+        if werr := q.write(item); werr != nil {
+            return werr
+        }
+        os.Remove(q.path(QueuePending, item.ID))
+        logging.Error(fmt.Sprintf("Очередь %s: доставка %s перенесена в мертвые письма после %d попыток: %v",
+            q.Name, item.ID, item.Attempts, err), "system")
+        if notify && q.onDone != nil {
+            q.onDone(item, err)
+        }
+        return fmt.Errorf("%w: %v", ErrDead, err)
+    }
+
+    item.NextAttempt = time.Now().UTC().Add(backoff(item.Attempts))
+    if werr := q.write(item); werr != nil {
+        return werr
+    }
+    return err
+}
+
+// backoff — экспоненциальная задержка со случайным разбросом до 20%
+func backoff(attempts int) time.Duration {
+    delay := BASE_RETRY_DELAY
+    for i := 1; i < attempts && delay < MAX_RETRY_DELAY; i++ {
+        delay *= 2
+    }
+    if delay > MAX_RETRY_DELAY {
+        delay = MAX_RETRY_DELAY
+    }
+    return delay + time.Duration(rand.Int63n(int64(delay)/5+1))
+}
+
+// Start запускает фоновый обработчик очереди
+func (q *Queue) Start() {
+    go func() {
+        ticker := time.NewTicker(POLL_INTERVAL)
+        defer ticker.Stop()
+        for {
+            q.processDue()
+            select {
+            case <-ticker.C:
+            case <-q.wake:
+            }
+        }
+    }()
+}
+
+func (q *Queue) processDue() {
+    items, err := q.List(QueuePending)
+    if err != nil {
+        return
+    }
+    now := time.Now().UTC()
+    for _, item := range items {
+        if item.NextAttempt.After(now) {
+            continue
+        }
+        q.tryAttempt(context.Background(), item.ID, true)
+    }
+}
+
+// List возвращает записи очереди (pending или dead), старые первыми
+func (q *Queue) List(queue string) ([]*Item, error) {
+    entries, err := os.ReadDir(filepath.Join(q.dir, queue))
+    if err != nil {
+        return nil, err
+    }
+    var items []*Item
+    for _, e := range entries {
+        name := e.Name()
+        if e.IsDir() || !strings.HasSuffix(name, ".json") {
+            continue
+        }
+        item, err := q.read(queue, strings.TrimSuffix(name, ".json"))
+        if err != nil {
+            continue
+        }
+        items = append(items, item)
+    }
+    sort.Slice(items, func(i, j int) bool { return items[i].CreatedAt.Before(items[j].CreatedAt) })
+    return items, nil
+}
+
+// Retry возвращает запись из мертвых писем (или откладываемую) в очередь с немедленной попыткой.
+// Пустой id — повторить все мертвые письма.
+func (q *Queue) Retry(id string) (int, error) {
+    var ids []string
+    if id == "" {
+        dead, err := q.List(QueueDead)
+        if err != nil {
+            return 0, err
+        }
+        for _, item := range dead {
+            ids = append(ids, item.ID)
+        }
+    } else {
+        ids = []string{id}
+    }
+
+    count := 0
+    for _, id := range ids {
+        item, err := q.find(QueueDead, id)
+        fromDead := err == nil
+        if !fromDead {
+            if item, err = q.find(QueuePending, id); err != nil {
+                return count, err
+            }
+        }
+        item.Attempts = 0
+        item.NextAttempt = time.Now().UTC()
+        item.Queue = QueuePending
+        if err := q.write(item); err != nil {
+            return count, err
+        }
+        if fromDead {
+            os.Remove(q.path(QueueDead, id))
+        }
+        count++
+    }
+
+    select {
+    case q.wake <- struct{}{}:
+    default:
+    }
+    return count, nil
+}
+
+// Purge удаляет запись из очереди. Пустой id — очистить всю очередь.
+func (q *Queue) Purge(queue, id string) (int, error) {
+    if queue != QueuePending && queue != QueueDead {
+        return 0, fmt.Errorf("неизвестная очередь: %s", queue)
+    }
+    if id != "" {
+        item, err := q.find(queue, id)
+        if err != nil {
+            return 0, err
+        }
+        if err := os.Remove(q.path(queue, item.ID)); err != nil {
+            return 0, err
+        }
+        return 1, nil
+    }
+    items, err := q.List(queue)
+    if err != nil {
+        return 0, err
+    }
+    for _, item := range items {
+        os.Remove(q.path(queue, item.ID))
+    }
+    return len(items), nil
+}
\ No newline at end of file
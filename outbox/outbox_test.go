@@ -0,0 +1,175 @@
+package outbox
+
+import (
+    "context"
+    "errors"
+    "os"
+    "path/filepath"
+    "testing"
+    "time"
+)
+
+// openTest открывает очередь в отдельном каталоге; send возвращает ошибку, пока fail == true
+func openTest(t *testing.T, maxAttempts int, fail *bool) (*Queue, string, *[]string) {
+    dir := t.TempDir()
+    var done []string
+    send := func(ctx context.Context, item *Item) error {
+        if *fail {
+            return errors.New("сервер недоступен")
+        }
+        return nil
+    }
+    q, err := Open(t.Name(), filepath.Join(dir, "queue"), maxAttempts, send, func(item *Item, err error) {
+        done = append(done, item.ID)
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+    return q, dir, &done
+}
+
+func ids(t *testing.T, q *Queue, queue string) []string {
+    items, err := q.List(queue)
+    if err != nil {
+        t.Fatal(err)
+    }
+    var list []string
+    for _, item := range items {
+        list = append(list, item.ID)
+    }
+    return list
+}
+
+func TestEnqueueAndAttempt(t *testing.T) {
+    fail := false
+    q, _, done := openTest(t, 3, &fail)
+    if err := q.Enqueue(&Item{ID: "m-1", Key: "m-1", Payload: []byte(`{}`)}); err != nil {
+        t.Fatal(err)
+    }
+    if got := ids(t, q, QueuePending); len(got) != 1 || got[0] != "m-1" {
+        t.Fatalf("pending: %v", got)
+    }
+    if err := q.Attempt(context.Background(), "m-1"); err != nil {
+        t.Fatal(err)
+    }
+    if got := ids(t, q, QueuePending); len(got) != 0 {
+        t.Errorf("после доставки в очереди осталось: %v", got)
+    }
+    // О результате Attempt знает вызывающий, onDone — только для фоновых повторов
+    if len(*done) != 0 {
+        t.Errorf("onDone вызван для Attempt: %v", *done)
+    }
+}
+
+func TestFailedAttemptBacksOffThenGoesDead(t *testing.T) {
+    fail := true
+    q, _, done := openTest(t, 2, &fail)
+    q.Enqueue(&Item{ID: "m-1"})
+
+    before := time.Now().UTC()
+    err := q.Attempt(context.Background(), "m-1")
+    if err == nil || errors.Is(err, ErrDead) {
+        t.Fatalf("первая попытка: %v", err)
+    }
+    items, _ := q.List(QueuePending)
+    if len(items) != 1 || items[0].Attempts != 1 || items[0].LastError == "" {
+        t.Fatalf("запись после ошибки: %+v", items)
+    }
+    if wait := items[0].NextAttempt.Sub(before); wait < BASE_RETRY_DELAY {
+        t.Errorf("следующая попытка через %v, ожидалось не меньше %v", wait, BASE_RETRY_DELAY)
+    }
+
+    // Фоновая попытка исчерпывает лимит: запись уходит в мертвые письма, onDone получает ошибку
+    if err := q.tryAttempt(context.Background(), "m-1", true); !errors.Is(err, ErrDead) {
+        t.Fatalf("последняя попытка: %v, ожидалась ErrDead", err)
+    }
+    if got := ids(t, q, QueueDead); len(got) != 1 || got[0] != "m-1" {
+        t.Errorf("dead: %v", got)
+    }
+    if got := ids(t, q, QueuePending); len(got) != 0 {
+        t.Errorf("pending: %v", got)
+    }
+    if len(*done) != 1 {
+        t.Errorf("onDone вызван %d раз, ожидался 1", len(*done))
+    }
+}
+
+func TestBackoff(t *testing.T) {
+    for _, tc := range []struct {
+        attempts int
+        base     time.Duration
+    }{
+        {1, BASE_RETRY_DELAY},
+        {2, 2 * BASE_RETRY_DELAY},
+        {4, 8 * BASE_RETRY_DELAY},
+        {100, MAX_RETRY_DELAY},
+    } {
+        for i := 0; i < 20; i++ {
+            d := backoff(tc.attempts)
+            if d < tc.base || d > tc.base+tc.base/5 {
+                t.Errorf("backoff(%d) = %v, ожидалось от %v до %v", tc.attempts, d, tc.base, tc.base+tc.base/5)
+                break
+            }
+        }
+    }
+}
+
+func TestRetryAndPurge(t *testing.T) {
+    fail := true
+    q, _, _ := openTest(t, 1, &fail)
+    for _, id := range []string{"m-1", "m-2"} {
+        q.Enqueue(&Item{ID: id})
+        q.Attempt(context.Background(), id)
+    }
+    if got := ids(t, q, QueueDead); len(got) != 2 {
+        t.Fatalf("dead: %v", got)
+    }
+
+    if n, err := q.Retry("m-1"); err != nil || n != 1 {
+        t.Fatalf("Retry: %d, %v", n, err)
+    }
+    items, _ := q.List(QueuePending)
+    if len(items) != 1 || items[0].ID != "m-1" || items[0].Attempts != 0 {
+        t.Fatalf("pending после Retry: %+v", items)
+    }
+    if _, err := q.Retry("m-3"); !errors.Is(err, ErrNotFound) {
+        t.Errorf("Retry неизвестной записи: %v", err)
+    }
+
+    if n, err := q.Purge(QueueDead, ""); err != nil || n != 1 {
+        t.Errorf("Purge dead: %d, %v", n, err)
+    }
+    if n, err := q.Purge(QueuePending, "m-1"); err != nil || n != 1 {
+        t.Errorf("Purge m-1: %d, %v", n, err)
+    }
+    if _, err := q.Purge("other", ""); err == nil {
+        t.Error("Purge неизвестной очереди должен вернуть ошибку")
+    }
+}
+
+// id из запроса не должен выводить за каталог очереди
+func TestPathTraversalRejected(t *testing.T) {
+    fail := true
+    q, dir, _ := openTest(t, 1, &fail)
+    secret := filepath.Join(dir, "betelgeuze.json")
+    if err := os.WriteFile(secret, []byte(`{"auth":{}}`), 0644); err != nil {
+        t.Fatal(err)
+    }
+    q.Enqueue(&Item{ID: "m-1"})
+    q.Attempt(context.Background(), "m-1")
+
+    for _, id := range []string{"../../betelgeuze", "../dead/m-1", "..", "a/../m-1", `..\..\betelgeuze`} {
+        if _, err := q.Purge(QueueDead, id); !errors.Is(err, ErrNotFound) {
+            t.Errorf("Purge(%q): %v, ожидалась ErrNotFound", id, err)
+        }
+        if _, err := q.Retry(id); !errors.Is(err, ErrNotFound) {
+            t.Errorf("Retry(%q): %v, ожидалась ErrNotFound", id, err)
+        }
+    }
+    if _, err := os.Stat(secret); err != nil {
+        t.Errorf("файл вне очереди удален: %v", err)
+    }
+    if got := ids(t, q, QueueDead); len(got) != 1 {
+        t.Errorf("dead: %v", got)
+    }
+}
\ No newline at end of file
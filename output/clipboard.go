@@ -0,0 +1,142 @@
+package output
+
+import (
+    "sync"
+    "time"
+
+    "betelgeuze-measure-system-main/logging"
+
+    "github.com/atotto/clipboard"
+)
+
+// DEFAULT_RESTORE_DELAY — пауза перед возвратом прежнего содержимого буфера обмена
+const DEFAULT_RESTORE_DELAY = 1000 * time.Millisecond
+
+// clipboardGuard сохраняет содержимое буфера обмена оператора на время вывода измерения.
+// Если два измерения идут подряд, сохраняется содержимое до первого из них,
+// а восстановление выполняется один раз — после последнего.
+type clipboardGuard struct {
+    mu         sync.Mutex
+    original   string
+    saved      bool
+    generation int
+    timer      *time.Timer
+}
+
+var guard = &clipboardGuard{}
+
+// begin запоминает текущее содержимое буфера, если оно еще не сохранено,
+// и отменяет запланированное восстановление
+func (g *clipboardGuard) begin() {
+    g.mu.Lock()
+    defer g.mu.Unlock()
+
+    if g.timer != nil {
+        g.timer.Stop()
+        g.timer = nil
+    }
+    g.generation++
+    if g.saved {
+        return
+    }
+    text, err := clipboard.ReadAll()
+    if err != nil {
+        // Пустой буфер или не текст — восстанавливать нечего
+        return
+    }
+    g.original = text
+    g.saved = true
+}
+
+// restoreLater возвращает сохраненное содержимое через delay, если за это время
+// не началось новое измерение и оператор сам ничего не скопировал
+func (g *clipboardGuard) restoreLater(written string, delay time.Duration) {
+    g.mu.Lock()
+    defer g.mu.Unlock()
+
+    if !g.saved {
+        return
+    }
+    generation := g.generation
+    g.timer = time.AfterFunc(delay, func() {
+        g.mu.Lock()
+        defer g.mu.Unlock()
+
+        if generation != g.generation || !g.saved {
+            return
+        }
+        g.saved = false
+        g.timer = nil
+
+        current, err := clipboard.ReadAll()
+        if err != nil || current != written {
+            // Оператор успел скопировать что-то свое — не затираем
+            return
+        }
+        if err := clipboard.WriteAll(g.original); err != nil {
+            logging.Warn("Не удалось восстановить буфер обмена: "+err.Error(), "system")
+        }
+        g.original = ""
+    })
+}
+
+// restoreNow отменяет отложенное восстановление и сразу возвращает прежнее содержимое
+func (g *clipboardGuard) restoreNow() {
+    g.mu.Lock()
+    defer g.mu.Unlock()
+
+    if g.timer != nil {
+        g.timer.Stop()
+        g.timer = nil
+    }
+    g.generation++
+    if !g.saved {
+        return
+    }
+    g.saved = false
+    clipboard.WriteAll(g.original)
+    g.original = ""
+}
+
+// clipboardRestore — настройка восстановления буфера для конкретного выхода
+type clipboardRestore struct {
+    enabled bool
+    delay   time.Duration
+}
+
+func newClipboardRestore(enabled *bool, defaultEnabled bool, delayMs int) clipboardRestore {
+    r := clipboardRestore{enabled: defaultEnabled, delay: DEFAULT_RESTORE_DELAY}
+    if enabled != nil {
+        r.enabled = *enabled
+    }
+    if delayMs > 0 {
+        r.delay = time.Duration(delayMs) * time.Millisecond
+    }
+    return r
+}
+
+// write помещает текст в буфер обмена, при необходимости сохранив прежнее содержимое
+func (r clipboardRestore) write(text string) error {
+    if r.enabled {
+        guard.begin()
+    }
+    if err := clipboard.WriteAll(text); err != nil {
+        if r.enabled {
+            guard.restoreNow()
+        }
+        return err
+    }
+    return nil
+}
+
+// done планирует восстановление после успешного вывода или возвращает буфер сразу при ошибке
+func (r clipboardRestore) done(text string, err error) {
+    if !r.enabled {
+        return
+    }
+    if err != nil {
+        guard.restoreNow()
+        return
+    }
+    guard.restoreLater(text, r.delay)
+}
\ No newline at end of file
@@ -0,0 +1,114 @@
+package output
+
+import (
+    "context"
+    "fmt"
+    "io"
+    "sync"
+    "time"
+
+    "betelgeuze-measure-system-main/config"
+    "betelgeuze-measure-system-main/devices"
+    "betelgeuze-measure-system-main/logging"
+    "betelgeuze-measure-system-main/types"
+
+    "go.bug.st/serial"
+)
+
+// DEFAULT_EMULATOR_PATH — ссылка на виртуальный порт эмулятора весов, если path не указан
+const DEFAULT_EMULATOR_PATH = "/tmp/betelgeuze-scale"
+
+// scaleEmulatorSink изображает весы Massa-K на виртуальном последовательном порту:
+// старые программы опрашивают его командами 0x48/0x4A и получают вес последнего измерения.
+// На Linux создается псевдотерминал со ссылкой path, на других системах используется
+// одна сторона пары виртуальных COM-портов (например, com0com), указанная в port.
+type scaleEmulatorSink struct {
+    name string
+    port io.ReadWriteCloser
+
+    mu     sync.Mutex
+    weight float64
+}
+
+// ExcludeEmulatorPorts исключает виртуальные порты эмуляторов весов из автопоиска устройств,
+// чтобы поиск весов не нашел вместо них сам эмулятор, а Arduino и сканер не заняли порт старой программы.
+// Вызывается до подключения устройств.
+func ExcludeEmulatorPorts(configs []config.SinkConfig) {
+    for _, cfg := range configs {
+        if cfg.Type != "scale" {
+            continue
+        }
+        if cfg.Address != "" {
+            devices.ExcludePorts(cfg.Address, cfg.Peer)
+        } else if cfg.Path != "" {
+            devices.ExcludePorts(cfg.Path)
+        } else {
+            devices.ExcludePorts(DEFAULT_EMULATOR_PATH)
+        }
+    }
+}
+
+func newScaleEmulatorSink(name string, cfg config.SinkConfig) (*scaleEmulatorSink, error) {
+    var port io.ReadWriteCloser
+    var err error
+    if cfg.Address != "" {
+        // Пара виртуальных COM-портов: открываем свою сторону с настройками Massa-K
+        port, err = serial.Open(cfg.Address, &serial.Mode{
+            BaudRate: 4800,
+            DataBits: 8,
+            Parity:   serial.EvenParity,
+            StopBits: serial.OneStopBit,
+        })
+    } else {
+        path := cfg.Path
+        if path == "" {
+            path = DEFAULT_EMULATOR_PATH
+        }
+        port, err = openPty(path)
+    }
+    if err != nil {
+        return nil, fmt.Errorf("выход %s: %v", name, err)
+    }
+
+    s := &scaleEmulatorSink{name: name, port: port}
+    go s.serve()
+    return s, nil
+}
+
+func (s *scaleEmulatorSink) Name() string { return s.name }
+
+func (s *scaleEmulatorSink) Write(ctx context.Context, m *types.Measurement, text string) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.weight = m.Weight
+    return nil
+}
+
+// serve отвечает на запросы программы, открывшей виртуальный порт
+func (s *scaleEmulatorSink) serve() {
+    buf := make([]byte, 64)
+    for {
+        n, err := s.port.Read(buf)
+        if err != nil {
+            // Пока другая сторона псевдотерминала не открыта, чтение возвращает ошибку
+            time.Sleep(500 * time.Millisecond)
+            continue
+        }
+        for _, cmd := range buf[:n] {
+            var reply []byte
+            switch cmd {
+            case config.CMD_SCALE_IDENT:
+                reply = devices.SCALE_IDENT_REPLY
+            case config.CMD_SCALE_WEIGHT:
+                s.mu.Lock()
+                reply = devices.EncodeWeight(s.weight)
+                s.mu.Unlock()
+            default:
+                continue
+            }
+            if _, err := s.port.Write(reply); err != nil {
+                logging.Error(fmt.Sprintf("Эмулятор весов %s: ошибка ответа: %v", s.name, err), "scale")
+            }
+        }
+    }
+}
\ No newline at end of file
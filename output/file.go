@@ -0,0 +1,74 @@
+package output
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "os"
+    "path/filepath"
+    "sync"
+
+    "betelgeuze-measure-system-main/types"
+)
+
+// render возвращает строку для текстовых выходов: по шаблону или JSON
+func render(m *types.Measurement, text, format string) (string, error) {
+    if format == "json" {
+        data, err := json.Marshal(m)
+        if err != nil {
+            return "", err
+        }
+        return string(data), nil
+    }
+    return text, nil
+}
+
+// fileSink дописывает каждое измерение отдельной строкой в файл
+type fileSink struct {
+    name   string
+    path   string
+    format string
+    mu     sync.Mutex
+}
+
+func (s *fileSink) Name() string { return s.name }
+
+func (s *fileSink) Write(ctx context.Context, m *types.Measurement, text string) error {
+    line, err := render(m, text, s.format)
+    if err != nil {
+        return err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if dir := filepath.Dir(s.path); dir != "" {
+        os.MkdirAll(dir, 0755)
+    }
+    f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+    if err != nil {
+        return err
+    }
+    if _, err := f.WriteString(line + "\n"); err != nil {
+        f.Close()
+        return err
+    }
+    return f.Close()
+}
+
+// stdoutSink печатает измерение в консоль
+type stdoutSink struct {
+    name   string
+    format string
+}
+
+func (s *stdoutSink) Name() string { return s.name }
+
+func (s *stdoutSink) Write(ctx context.Context, m *types.Measurement, text string) error {
+    line, err := render(m, text, s.format)
+    if err != nil {
+        return err
+    }
+    _, err = fmt.Println(line)
+    return err
+}
\ No newline at end of file
@@ -0,0 +1,348 @@
+package output
+
+import (
+    "context"
+    "fmt"
+    "runtime"
+    "strings"
+    "sync"
+    "time"
+    "unicode"
+    "unicode/utf8"
+
+    "betelgeuze-measure-system-main/config"
+    "betelgeuze-measure-system-main/measurement"
+    "betelgeuze-measure-system-main/types"
+
+    "github.com/micmonay/keybd_event"
+)
+
+// desktopMutex не дает двум клавиатурным выходам печатать одновременно
+var desktopMutex sync.Mutex
+
+// clipboardSink только копирует результат в буфер обмена
+type clipboardSink struct {
+    name    string
+    restore clipboardRestore
+}
+
+func (s *clipboardSink) Name() string { return s.name }
+
+func (s *clipboardSink) Write(ctx context.Context, m *types.Measurement, text string) error {
+    if err := s.restore.write(text); err != nil {
+        return fmt.Errorf("ошибка буфера обмена: %v", err)
+    }
+    s.restore.done(text, nil)
+    return nil
+}
+
+// keyboardSettings дополняет настройки клавиатуры значениями по умолчанию
+func keyboardSettings(cfg *config.KeyboardConfig, preDelay, keyDelay, terminatorDelay int) config.KeyboardConfig {
+    var k config.KeyboardConfig
+    if cfg != nil {
+        k = *cfg
+    }
+    if k.Terminator == "" {
+        k.Terminator = "{ENTER}"
+    }
+    if k.PreDelayMs <= 0 {
+        k.PreDelayMs = preDelay
+    }
+    if k.KeyDelayMs <= 0 {
+        k.KeyDelayMs = keyDelay
+    }
+    if k.TerminatorDelayMs <= 0 {
+        k.TerminatorDelayMs = terminatorDelay
+    }
+    return k
+}
+
+// validateKeyboard проверяет, что разделители и терминатор можно напечатать
+func validateKeyboard(cfg *config.KeyboardConfig) error {
+    if cfg == nil {
+        return nil
+    }
+    for _, spec := range append(append([]string{}, cfg.FieldSeparators...), cfg.Terminator) {
+        if _, err := parseKeys(spec); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// pasteSink копирует результат в буфер обмена и вставляет его в активное окно (Ctrl+V + терминатор)
+type pasteSink struct {
+    name     string
+    keyboard config.KeyboardConfig
+    restore  clipboardRestore
+}
+
+func (s *pasteSink) Name() string { return s.name }
+
+func (s *pasteSink) Write(ctx context.Context, m *types.Measurement, text string) error {
+    terminator, err := parseKeys(s.keyboard.Terminator)
+    if err != nil {
+        return err
+    }
+
+    desktopMutex.Lock()
+    defer desktopMutex.Unlock()
+
+    // Копируем результат в буфер обмена, сохранив то, что скопировал оператор
+    err = s.restore.write(text)
+    if err != nil {
+        return fmt.Errorf("ошибка буфера обмена: %v", err)
+    }
+    err = s.paste(ctx, terminator)
+    s.restore.done(text, err)
+    return err
+}
+
+// paste нажимает Ctrl+V (Cmd+V на macOS) и клавиши терминатора
+func (s *pasteSink) paste(ctx context.Context, terminator []keyStroke) error {
+    // Создаем клавиатурное событие с учетом ОС
+    kb, err := keybd_event.NewKeyBonding()
+    if err != nil {
+        return fmt.Errorf("ошибка создания клавиатурного события: %v", err)
+    }
+
+    // Устанавливаем правильный модификатор в зависимости от ОС
+    if runtime.GOOS == "darwin" { // macOS
+        kb.HasSuper(true)
+    } else { // Windows и Linux
+        kb.HasCTRL(true)
+    }
+    
+    kb.SetKeys(keybd_event.VK_V)
+    
+    // Задержка перед нажатием (важно для стабильности)
+    sleepMs(s.keyboard.PreDelayMs)
+    
+    err = kb.Launching()
+    if err != nil {
+        return fmt.Errorf("ошибка симуляции Ctrl+V: %v", err)
+    }
+
+    if len(terminator) == 0 {
+        return nil
+    }
+    sleepMs(s.keyboard.TerminatorDelayMs)
+    return pressKeys(ctx, terminator, s.keyboard.KeyDelayMs)
+}
+
+// typeSink печатает результат посимвольно, не трогая буфер обмена.
+// Подходит для терминальных WMS, которые не принимают Ctrl+V.
+type typeSink struct {
+    name     string
+    keyboard config.KeyboardConfig
+}
+
+func (s *typeSink) Name() string { return s.name }
+
+func (s *typeSink) Write(ctx context.Context, m *types.Measurement, text string) error {
+    keys, err := s.sequence(m, text)
+    if err != nil {
+        return err
+    }
+    terminator, err := parseKeys(s.keyboard.Terminator)
+    if err != nil {
+        return err
+    }
+
+    desktopMutex.Lock()
+    defer desktopMutex.Unlock()
+
+    sleepMs(s.keyboard.PreDelayMs)
+    if err := pressKeys(ctx, keys, s.keyboard.KeyDelayMs); err != nil {
+        return err
+    }
+    if len(terminator) == 0 {
+        return nil
+    }
+    sleepMs(s.keyboard.TerminatorDelayMs)
+    return pressKeys(ctx, terminator, s.keyboard.KeyDelayMs)
+}
+
+// sequence строит последовательность нажатий: весь результат целиком
+// или поля шаблона с собственными разделителями
+func (s *typeSink) sequence(m *types.Measurement, text string) ([]keyStroke, error) {
+    separators := s.keyboard.FieldSeparators
+    if len(separators) == 0 {
+        return literalKeys(text)
+    }
+
+    tpl := measurement.StationTemplate()
+    var keys []keyStroke
+    prefix, err := literalKeys(tpl.Prefix)
+    if err != nil {
+        return nil, err
+    }
+    keys = append(keys, prefix...)
+
+    fields := measurement.FormatFields(m, tpl)
+    for i, field := range fields {
+        fieldKeys, err := literalKeys(field)
+        if err != nil {
+            return nil, err
+        }
+        keys = append(keys, fieldKeys...)
+        if i == len(fields)-1 {
+            break
+        }
+        sep := separators[len(separators)-1]
+        if i < len(separators) {
+            sep = separators[i]
+        }
+        sepKeys, err := parseKeys(sep)
+        if err != nil {
+            return nil, err
+        }
+        keys = append(keys, sepKeys...)
+    }
+
+    suffix, err := literalKeys(tpl.Suffix)
+    if err != nil {
+        return nil, err
+    }
+    return append(keys, suffix...), nil
+}
+
+// keyStroke — клавиша и необходимость Shift для символа. Символ char вводится как Unicode
+// в обход раскладки: клавиши пунктуации на русской раскладке печатают другие символы.
+type keyStroke struct {
+    key   int
+    shift bool
+    char  rune
+}
+
+// controlKeys — пробельные символы, которые печатаются одинаково на любой раскладке
+var controlKeys = map[rune]keyStroke{
+    ' ':  {key: keybd_event.VK_SPACE},
+    '\t': {key: keybd_event.VK_TAB},
+    '\n': {key: keybd_event.VK_ENTER},
+}
+
+var digits = []int{
+    keybd_event.VK_0, keybd_event.VK_1, keybd_event.VK_2, keybd_event.VK_3, keybd_event.VK_4,
+    keybd_event.VK_5, keybd_event.VK_6, keybd_event.VK_7, keybd_event.VK_8, keybd_event.VK_9,
+}
+
+var letters = []int{
+    keybd_event.VK_A, keybd_event.VK_B, keybd_event.VK_C, keybd_event.VK_D, keybd_event.VK_E,
+    keybd_event.VK_F, keybd_event.VK_G, keybd_event.VK_H, keybd_event.VK_I, keybd_event.VK_J,
+    keybd_event.VK_K, keybd_event.VK_L, keybd_event.VK_M, keybd_event.VK_N, keybd_event.VK_O,
+    keybd_event.VK_P, keybd_event.VK_Q, keybd_event.VK_R, keybd_event.VK_S, keybd_event.VK_T,
+    keybd_event.VK_U, keybd_event.VK_V, keybd_event.VK_W, keybd_event.VK_X, keybd_event.VK_Y,
+    keybd_event.VK_Z,
+}
+
+// strokeFor возвращает клавишу для символа: цифры, латиница и пробельные символы
+// нажимаются клавишами, остальные печатные символы вводятся как Unicode
+func strokeFor(r rune) (keyStroke, error) {
+    switch {
+    case r >= '0' && r <= '9':
+        return keyStroke{key: digits[r-'0']}, nil
+    case r >= 'a' && r <= 'z':
+        return keyStroke{key: letters[r-'a']}, nil
+    case r >= 'A' && r <= 'Z':
+        return keyStroke{key: letters[r-'A'], shift: true}, nil
+    }
+    if k, ok := controlKeys[r]; ok {
+        return k, nil
+    }
+    if unicode.IsPrint(r) {
+        return keyStroke{char: r}, nil
+    }
+    return keyStroke{}, fmt.Errorf("символ %q нельзя напечатать с клавиатуры", r)
+}
+
+// namedKeys — клавиши, которые можно указать в разделителях и терминаторе
+var namedKeys = map[string]keyStroke{
+    "{TAB}":   {key: keybd_event.VK_TAB},
+    "{ENTER}": {key: keybd_event.VK_ENTER},
+    "{SPACE}": {key: keybd_event.VK_SPACE},
+}
+
+// literalKeys переводит текст в нажатия без разбора {КЛАВИШ}
+func literalKeys(text string) ([]keyStroke, error) {
+    var keys []keyStroke
+    for _, r := range text {
+        k, err := strokeFor(r)
+        if err != nil {
+            return nil, err
+        }
+        keys = append(keys, k)
+    }
+    return keys, nil
+}
+
+// parseKeys переводит строку вида "{TAB}{TAB}" или ";" в нажатия. "{NONE}" — пустая последовательность.
+func parseKeys(spec string) ([]keyStroke, error) {
+    var keys []keyStroke
+    for len(spec) > 0 {
+        if strings.HasPrefix(spec, "{NONE}") {
+            spec = spec[len("{NONE}"):]
+            continue
+        }
+        matched := false
+        for name, k := range namedKeys {
+            if strings.HasPrefix(spec, name) {
+                keys = append(keys, k)
+                spec = spec[len(name):]
+                matched = true
+                break
+            }
+        }
+        if matched {
+            continue
+        }
+        r, size := utf8.DecodeRuneInString(spec)
+        k, err := strokeFor(r)
+        if err != nil {
+            return nil, err
+        }
+        keys = append(keys, k)
+        spec = spec[size:]
+    }
+    return keys, nil
+}
+
+// pressKeys нажимает клавиши по очереди с паузой между нажатиями.
+// Клавиатурное устройство создается один раз на всю последовательность.
+func pressKeys(ctx context.Context, keys []keyStroke, delayMs int) error {
+    var kb *keybd_event.KeyBonding
+    for i, k := range keys {
+        if err := ctx.Err(); err != nil {
+            return err
+        }
+        if i > 0 {
+            sleepMs(delayMs)
+        }
+        if k.char != 0 {
+            if err := typeUnicode(k.char); err != nil {
+                return fmt.Errorf("ошибка ввода символа %q: %v", k.char, err)
+            }
+            continue
+        }
+        if kb == nil {
+            bonding, err := keybd_event.NewKeyBonding()
+            if err != nil {
+                return fmt.Errorf("ошибка создания клавиатурного события: %v", err)
+            }
+            kb = &bonding
+        }
+        kb.Clear()
+        kb.HasSHIFT(k.shift)
+        kb.SetKeys(k.key)
+        if err := kb.Launching(); err != nil {
+            return fmt.Errorf("ошибка симуляции нажатия: %v", err)
+        }
+    }
+    return nil
+}
+
+func sleepMs(ms int) {
+    if ms > 0 {
+        time.Sleep(time.Duration(ms) * time.Millisecond)
+    }
+}
\ No newline at end of file
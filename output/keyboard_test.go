@@ -0,0 +1,26 @@
+package output
+
+import "testing"
+
+// Пунктуация вводится как Unicode: на русской раскладке клавиши '.', ',' и ':' печатают другие символы
+func TestLiteralKeysTypePunctuationAsUnicode(t *testing.T) {
+    keys, err := literalKeys("1.5,A:б")
+    if err != nil {
+        t.Fatal(err)
+    }
+    want := []rune{0, '.', 0, ',', 0, ':', 'б'}
+    if len(keys) != len(want) {
+        t.Fatalf("нажатий %d, ожидалось %d", len(keys), len(want))
+    }
+    for i, k := range keys {
+        if k.char != want[i] {
+            t.Errorf("символ %d: char %q, ожидался %q", i, k.char, want[i])
+        }
+    }
+    if !keys[4].shift {
+        t.Error("заглавная буква должна нажиматься с Shift")
+    }
+    if _, err := literalKeys("\x07"); err == nil {
+        t.Error("управляющий символ не должен печататься")
+    }
+}
\ No newline at end of file
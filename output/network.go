@@ -0,0 +1,184 @@
+package output
+
+import (
+    "bytes"
+    "context"
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "net"
+    "net/http"
+    "path/filepath"
+    "time"
+
+    "betelgeuze-measure-system-main/config"
+    "betelgeuze-measure-system-main/mqtt"
+    "betelgeuze-measure-system-main/outbox"
+    "betelgeuze-measure-system-main/types"
+)
+
+// WEBHOOK_TIMEOUT — время ожидания ответа сервера на одну попытку доставки
+const WEBHOOK_TIMEOUT = 10 * time.Second
+
+// webhookSink отправляет измерение POST-запросом с JSON-телом через очередь на диске:
+// если сервер недоступен, доставка повторяется в фоне с экспоненциальной задержкой.
+type webhookSink struct {
+    name    string
+    url     string
+    headers map[string]string
+    secret  string
+    queue   *outbox.Queue
+    client  *http.Client
+}
+
+// webhookPayload — тело запроса webhook
+type webhookPayload struct {
+    Measurement *types.Measurement `json:"measurement"`
+    Text        string             `json:"text"`
+}
+
+func newWebhookSink(name string, cfg config.SinkConfig) (*webhookSink, error) {
+    s := &webhookSink{
+        name:    name,
+        url:     cfg.URL,
+        headers: cfg.Headers,
+        secret:  cfg.Secret,
+        client:  &http.Client{Timeout: WEBHOOK_TIMEOUT},
+    }
+    dir := filepath.Join(config.Get().HistoryDir, "outbox", name)
+    queue, err := outbox.Open(name, dir, cfg.MaxAttempts, s.post, s.delivered)
+    if err != nil {
+        return nil, fmt.Errorf("выход %s: очередь доставки: %v", name, err)
+    }
+    s.queue = queue
+    queue.Start()
+    return s, nil
+}
+
+func (s *webhookSink) Name() string { return s.name }
+
+func (s *webhookSink) Write(ctx context.Context, m *types.Measurement, text string) error {
+    body, err := json.Marshal(webhookPayload{Measurement: m, Text: text})
+    if err != nil {
+        return err
+    }
+    item := &outbox.Item{
+        ID:            m.ID,
+        Key:           m.ID,
+        MeasurementID: m.ID,
+        Payload:       body,
+        // Первую попытку делает Write; фоновый обработчик берет запись только после нее
+        NextAttempt: time.Now().UTC().Add(outbox.BASE_RETRY_DELAY),
+    }
+    if err := s.queue.Enqueue(item); err != nil {
+        return fmt.Errorf("не удалось поставить в очередь: %v", err)
+    }
+    // Результат первой попытки записывает в измерение конвейер; delivered сообщает только о фоновых повторах
+    // Попытка прерывается вместе с ctx конвейера; прерванная попытка остается в очереди повторов
+    if err := s.queue.Attempt(ctx, item.ID); errors.Is(err, outbox.ErrDead) {
+        return err
+    } else if err != nil {
+        return queuedError{err}
+    }
+    return nil
+}
+
+// queueing отмечает выход с очередью повторов (см. queueingSink)
+func (s *webhookSink) queueing() {}
+
+// post выполняет одну попытку доставки записи очереди
+func (s *webhookSink) post(ctx context.Context, item *outbox.Item) error {
+    req, err := http.NewRequestWithContext(ctx, "POST", s.url, bytes.NewReader(item.Payload))
+    if err != nil {
+        return err
+    }
+    req.Header.Set("Content-Type", "application/json")
+    req.Header.Set("Idempotency-Key", item.Key)
+    if s.secret != "" {
+        mac := hmac.New(sha256.New, []byte(s.secret))
+        mac.Write(item.Payload)
+        req.Header.Set("X-Betelgeuze-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+    }
+    for k, v := range s.headers {
+        req.Header.Set(k, v)
+    }
+
+    resp, err := s.client.Do(req)
+    if err != nil {
+        return err
+    }
+    defer resp.Body.Close()
+    io.Copy(io.Discard, resp.Body)
+    if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+        return fmt.Errorf("сервер ответил %s", resp.Status)
+    }
+    return nil
+}
+
+// delivered отмечает в истории результат фоновой доставки
+func (s *webhookSink) delivered(item *outbox.Item, err error) {
+    status := types.OutputSent
+    errText := ""
+    if err != nil {
+        status = types.OutputFailed
+        errText = err.Error()
+    }
+    updateOutputResult(item.MeasurementID, s.name, status, errText)
+}
+
+// tcpSink открывает соединение на каждое измерение и отправляет одну строку
+type tcpSink struct {
+    name    string
+    address string
+    format  string
+}
+
+func (s *tcpSink) Name() string { return s.name }
+
+func (s *tcpSink) Write(ctx context.Context, m *types.Measurement, text string) error {
+    line, err := render(m, text, s.format)
+    if err != nil {
+        return err
+    }
+    var d net.Dialer
+    conn, err := d.DialContext(ctx, "tcp", s.address)
+    if err != nil {
+        return err
+    }
+    defer conn.Close()
+    if deadline, ok := ctx.Deadline(); ok {
+        conn.SetDeadline(deadline)
+    }
+    _, err = conn.Write([]byte(line + "\n"))
+    return err
+}
+
+// mqttSink публикует измерение через общее соединение станции с брокером MQTT.
+// По умолчанию публикуется JSON, format "text" — строка по шаблону.
+type mqttSink struct {
+    name   string
+    topic  string
+    format string
+}
+
+func (s *mqttSink) Name() string { return s.name }
+
+func (s *mqttSink) Write(ctx context.Context, m *types.Measurement, text string) error {
+    publisher := mqtt.Default()
+    if publisher == nil {
+        return fmt.Errorf("MQTT не настроен: добавьте раздел mqtt в %s", config.SETTINGS_FILE)
+    }
+    format := s.format
+    if format == "" {
+        format = "json"
+    }
+    payload, err := render(m, text, format)
+    if err != nil {
+        return err
+    }
+    return publisher.PublishMeasurement(ctx, s.topic, []byte(payload))
+}
\ No newline at end of file
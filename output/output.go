@@ -0,0 +1,303 @@
+package output
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "slices"
+    "strings"
+    "sync"
+    "time"
+
+    "betelgeuze-measure-system-main/config"
+    "betelgeuze-measure-system-main/history"
+    "betelgeuze-measure-system-main/logging"
+    "betelgeuze-measure-system-main/measurement"
+    "betelgeuze-measure-system-main/types"
+)
+
+// DEFAULT_SINK_TIMEOUT — время, отведенное одному выходу на доставку измерения
+const DEFAULT_SINK_TIMEOUT = 10 * time.Second
+
+// Sink — выход, в который доставляется результат измерения.
+// text — строка, отформатированная по шаблону станции.
+type Sink interface {
+    Name() string
+    Write(ctx context.Context, m *types.Measurement, text string) error
+}
+
+// queueingSink — выход, который ставит измерение в очередь повторов до первой попытки
+// и прерывает попытку по отмене ctx. По таймауту конвейер дожидается его ответа:
+// прерванная попытка оставляет измерение в очереди, и результат — queued, а не failed.
+type queueingSink interface {
+    Sink
+    queueing()
+}
+
+// route — выход вместе с условиями его срабатывания
+type route struct {
+    sink     Sink
+    triggers []string
+    timeout  time.Duration
+}
+
+// accepts проверяет источник измерения. Правило политики из нескольких событий записывается
+// в Trigger через "+" (barcode+weight): выход принимает измерение, если подходит любое из них.
+func (r route) accepts(m *types.Measurement) bool {
+    if len(r.triggers) == 0 {
+        return true
+    }
+    for _, source := range strings.Split(m.Trigger, "+") {
+        if slices.Contains(r.triggers, source) {
+            return true
+        }
+    }
+    return false
+}
+
+// Pipeline рассылает измерение во все подходящие выходы параллельно.
+// Ошибка или зависание одного выхода не мешает остальным.
+type Pipeline struct {
+    routes []route
+}
+
+// IsDesktop сообщает, нужен ли выходу рабочий стол (клавиатура или буфер обмена)
+func IsDesktop(sinkType string) bool {
+    return sinkType == "paste" || sinkType == "clipboard" || sinkType == "type"
+}
+
+// NewPipeline создает конвейер выходов по настройкам.
+// В режиме headless выходы, которым нужен рабочий стол, пропускаются.
+// Выход с ошибкой в настройке пропускается, остальные работают: конвейер возвращается
+// вместе с ошибками пропущенных выходов.
+func NewPipeline(configs []config.SinkConfig, headless bool) (*Pipeline, error) {
+    p := &Pipeline{}
+    var errs []error
+    for _, cfg := range configs {
+        if headless && IsDesktop(cfg.Type) {
+            logging.Warn(fmt.Sprintf("Режим без рабочего стола: выход %s отключен", cfg.Type), "system")
+            continue
+        }
+        sink, err := NewSink(cfg)
+        if err != nil {
+            logging.Error(fmt.Sprintf("Выход отключен: %v", err), "system")
+            errs = append(errs, err)
+            continue
+        }
+        timeout := DEFAULT_SINK_TIMEOUT
+        if cfg.TimeoutMs > 0 {
+            timeout = time.Duration(cfg.TimeoutMs) * time.Millisecond
+        }
+        p.routes = append(p.routes, route{sink: sink, triggers: cfg.Triggers, timeout: timeout})
+    }
+    return p, errors.Join(errs...)
+}
+
+// NewSink создает выход по его настройке
+func NewSink(cfg config.SinkConfig) (Sink, error) {
+    name := cfg.Name
+    if name == "" {
+        name = cfg.Type
+    }
+    if cfg.Type == "paste" || cfg.Type == "type" {
+        if err := validateKeyboard(cfg.Keyboard); err != nil {
+            return nil, fmt.Errorf("выход %s: %v", name, err)
+        }
+    }
+
+    switch cfg.Type {
+    case "paste":
+        // Задержки по умолчанию совпадают с прежними: 200 мс перед Ctrl+V и 100 мс перед Enter
+        return &pasteSink{
+            name:     name,
+            keyboard: keyboardSettings(cfg.Keyboard, 200, 20, 100),
+            restore:  newClipboardRestore(cfg.RestoreClipboard, true, cfg.RestoreDelayMs),
+        }, nil
+    case "clipboard":
+        return &clipboardSink{name: name, restore: newClipboardRestore(cfg.RestoreClipboard, false, cfg.RestoreDelayMs)}, nil
+    case "type":
+        return &typeSink{name: name, keyboard: keyboardSettings(cfg.Keyboard, 200, 20, 100)}, nil
+    case "file":
+        if cfg.Path == "" {
+            return nil, fmt.Errorf("выход %s: не указан path", name)
+        }
+        return &fileSink{name: name, path: cfg.Path, format: cfg.Format}, nil
+    case "stdout":
+        return &stdoutSink{name: name, format: cfg.Format}, nil
+    case "webhook":
+        if cfg.URL == "" {
+            return nil, fmt.Errorf("выход %s: не указан url", name)
+        }
+        return newWebhookSink(name, cfg)
+    case "scale":
+        return newScaleEmulatorSink(name, cfg)
+    case "mqtt":
+        return &mqttSink{name: name, topic: cfg.Topic, format: cfg.Format}, nil
+    case "tcp":
+        if cfg.Address == "" {
+            return nil, fmt.Errorf("выход %s: не указан address", name)
+        }
+        return &tcpSink{name: name, address: cfg.Address, format: cfg.Format}, nil
+    default:
+        return nil, fmt.Errorf("неизвестный тип выхода: %s", cfg.Type)
+    }
+}
+
+// Deliver отправляет измерение во все подходящие выходы и ждет результата от каждого
+func (p *Pipeline) Deliver(m *types.Measurement) []types.OutputResult {
+    text := measurement.Format(m)
+
+    var selected []route
+    for _, r := range p.routes {
+        if r.accepts(m) {
+            selected = append(selected, r)
+        }
+    }
+    if len(selected) == 0 && len(p.routes) > 0 {
+        logging.Warn(fmt.Sprintf("Измерение %s (источник %s) не подходит ни одному выходу, сохранено только в историю", m.ID, m.Trigger), "system")
+    }
+
+    results := make([]types.OutputResult, len(selected))
+    var wg sync.WaitGroup
+    for i, r := range selected {
+        wg.Add(1)
+        go func(i int, r route) {
+            defer wg.Done()
+            results[i] = deliverOne(r, m, text)
+        }(i, r)
+    }
+    wg.Wait()
+    return results
+}
+
+func deliverOne(r route, m *types.Measurement, text string) types.OutputResult {
+    ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+    defer cancel()
+
+    done := make(chan error, 1)
+    go func() {
+        defer func() {
+            if rec := recover(); rec != nil {
+                done <- fmt.Errorf("паника в выходе: %v", rec)
+            }
+        }()
+        done <- r.sink.Write(ctx, m, text)
+    }()
+
+    var err error
+    select {
+    case err = <-done:
+    case <-ctx.Done():
+        if _, ok := r.sink.(queueingSink); ok {
+            err = <-done
+        } else {
+            err = fmt.Errorf("превышено время ожидания (%v)", r.timeout)
+        }
+    }
+
+    result := types.OutputResult{Sink: r.sink.Name(), Status: types.OutputSent, Time: time.Now().UTC()}
+    var queued queuedError
+    if errors.As(err, &queued) {
+        result.Status = types.OutputQueued
+        result.Error = queued.Error()
+        logging.Warn(fmt.Sprintf("Выход %s: %s поставлено в очередь повторов: %v", r.sink.Name(), m.ID, queued.err), "system")
+    } else if err != nil {
+        result.Status = types.OutputFailed
+        result.Error = err.Error()
+        logging.Error(fmt.Sprintf("Выход %s: ошибка доставки %s: %v", r.sink.Name(), m.ID, err), "system")
+    } else {
+        logging.BroadcastLog(fmt.Sprintf("Выход %s: измерение %s доставлено", r.sink.Name(), m.ID), "system")
+    }
+    return result
+}
+
+// queuedError — выход не доставил измерение сразу, но сохранил его для повторной отправки
+type queuedError struct {
+    err error
+}
+
+func (e queuedError) Error() string {
+    return "в очереди повторов: " + e.err.Error()
+}
+
+// updateOutputResult обновляет в истории результат выхода для измерения,
+// когда доставка завершилась позже основного вывода
+func updateOutputResult(measurementID, sink, status, errText string) {
+    store := history.Default()
+    if store == nil {
+        return
+    }
+    m, err := store.Get(measurementID)
+    if err != nil || m == nil {
+        return
+    }
+    found := false
+    for i := range m.Outputs {
+        if m.Outputs[i].Sink == sink {
+            m.Outputs[i].Status = status
+            m.Outputs[i].Error = errText
+            m.Outputs[i].Time = time.Now().UTC()
+            found = true
+        }
+    }
+    if !found {
+        // Конвейер еще не записал результаты выходов — не затираем их версией без выходов
+        return
+    }
+    measurement.SetOutputStatus(m, OverallStatus(m.Outputs))
+}
+
+// OverallStatus сводит результаты выходов в общий статус вывода измерения
+func OverallStatus(results []types.OutputResult) string {
+    if len(results) == 0 {
+        return types.OutputNone
+    }
+    failed, queued := 0, 0
+    for _, r := range results {
+        switch r.Status {
+        case types.OutputSent:
+        case types.OutputQueued:
+            queued++
+        default:
+            failed++
+        }
+    }
+    switch {
+    case failed == 0 && queued > 0:
+        return types.OutputQueued
+    case failed == 0:
+        return types.OutputSent
+    case failed == len(results):
+        return types.OutputFailed
+    default:
+        return types.OutputPartial
+    }
+}
+
+// pipeline — конвейер выходов станции
+var pipeline = &Pipeline{}
+
+// Init создает конвейер выходов станции по настройкам. Выходы с ошибкой в настройке
+// пропускаются, остальные запускаются; ошибка сообщает о пропущенных.
+func Init(configs []config.SinkConfig, headless bool) error {
+    p, err := NewPipeline(configs, headless)
+    if len(p.routes) == 0 {
+        if headless && err == nil {
+            logging.Warn("Режим без рабочего стола: не настроено ни одного сетевого или файлового выхода, измерения сохраняются только в историю", "system")
+        } else if len(configs) > 0 {
+            logging.Error("Ни один выход не запущен, измерения сохраняются только в историю", "system")
+        }
+    }
+    pipeline = p
+    return err
+}
+
+// Deliver отправляет измерение в выходы станции, записывает результаты в измерение
+// и сохраняет его новую версию в историю. Возвращает общий статус вывода.
+func Deliver(m *types.Measurement) string {
+    results := pipeline.Deliver(m)
+    m.Outputs = results
+    status := OverallStatus(results)
+    measurement.SetOutputStatus(m, status)
+    return status
+}
\ No newline at end of file
@@ -0,0 +1,134 @@
+package output
+
+import (
+    "bufio"
+    "encoding/json"
+    "io"
+    "net/http"
+    "net/http/httptest"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+
+    "betelgeuze-measure-system-main/config"
+    "betelgeuze-measure-system-main/history"
+    "betelgeuze-measure-system-main/outbox"
+    "betelgeuze-measure-system-main/types"
+)
+
+func TestCombinedTriggerReachesSink(t *testing.T) {
+    config.LoadSettings(filepath.Join(t.TempDir(), "missing.json"))
+    path := filepath.Join(t.TempDir(), "results.txt")
+    p, err := NewPipeline([]config.SinkConfig{{Type: "file", Path: path, Triggers: []string{"weight"}}}, false)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    m := &types.Measurement{ID: "m-1", Weight: 1200, Unit: "g", Trigger: "barcode+weight"}
+    results := p.Deliver(m)
+    if len(results) != 1 || results[0].Status != types.OutputSent {
+        t.Fatalf("результаты доставки: %+v", results)
+    }
+    data, err := os.ReadFile(path)
+    if err != nil || strings.TrimSpace(string(data)) == "" {
+        t.Fatalf("файл выхода не записан: %q, %v", data, err)
+    }
+
+    if results := p.Deliver(&types.Measurement{ID: "m-2", Trigger: "web"}); len(results) != 0 {
+        t.Errorf("измерение из web не должно попасть в выход: %+v", results)
+    }
+}
+
+// Выход с ошибкой в настройке пропускается, остальные выходы продолжают получать измерения
+func TestInvalidSinkSkipped(t *testing.T) {
+    config.LoadSettings(filepath.Join(t.TempDir(), "missing.json"))
+    path := filepath.Join(t.TempDir(), "results.txt")
+    err := Init([]config.SinkConfig{
+        {Type: "nosuchsink"},
+        {Type: "file", Path: path},
+    }, false)
+    if err == nil {
+        t.Fatal("ошибка в настройке выхода не возвращена")
+    }
+
+    m := &types.Measurement{ID: "m-1", Weight: 1200, Unit: "g", Trigger: "weight"}
+    if status := Deliver(m); status != types.OutputSent || len(m.Outputs) != 1 {
+        t.Fatalf("статус вывода %q, результаты: %+v", status, m.Outputs)
+    }
+    if data, err := os.ReadFile(path); err != nil || strings.TrimSpace(string(data)) == "" {
+        t.Fatalf("файл выхода не записан: %q, %v", data, err)
+    }
+}
+
+// Первая попытка webhook записывается в измерение конвейером: обработчик фоновой доставки
+// не должен сохранить промежуточную версию без результатов выходов
+func TestWebhookFirstAttemptSavedOnce(t *testing.T) {
+    dir := t.TempDir()
+    config.LoadSettings(filepath.Join(dir, "missing.json"))
+    config.Get().HistoryDir = dir
+    if err := history.Init(dir); err != nil {
+        t.Fatal(err)
+    }
+    defer history.Default().Close()
+
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+    defer srv.Close()
+    if err := Init([]config.SinkConfig{{Type: "webhook", Name: "wms", URL: srv.URL}}, false); err != nil {
+        t.Fatal(err)
+    }
+
+    m := &types.Measurement{ID: "m-1", Weight: 1200, Unit: "g", Trigger: "weight"}
+    history.Save(m)
+    if status := Deliver(m); status != types.OutputSent {
+        t.Fatalf("статус вывода %q, ожидался sent", status)
+    }
+
+    f, err := os.Open(filepath.Join(dir, history.JOURNAL_FILE))
+    if err != nil {
+        t.Fatal(err)
+    }
+    defer f.Close()
+    scanner := bufio.NewScanner(f)
+    for scanner.Scan() {
+        _, data, _ := strings.Cut(scanner.Text(), " ")
+        var version types.Measurement
+        if err := json.Unmarshal([]byte(data), &version); err != nil {
+            t.Fatalf("строка журнала %q: %v", scanner.Text(), err)
+        }
+        if version.OutputStatus == types.OutputNone {
+            t.Errorf("в истории сохранена версия без результатов выходов: %+v", version)
+        }
+    }
+}
+
+// Таймаут выхода во время первой попытки webhook: попытка прерывается, измерение
+// остается в очереди повторов, и результат выхода — queued, а не failed
+func TestWebhookTimeoutLeavesQueued(t *testing.T) {
+    dir := t.TempDir()
+    config.LoadSettings(filepath.Join(dir, "missing.json"))
+    config.Get().HistoryDir = dir
+
+    // Сервер отвечает только после разрыва соединения клиентом
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        io.Copy(io.Discard, r.Body)
+        <-r.Context().Done()
+    }))
+    defer srv.Close()
+    p, err := NewPipeline([]config.SinkConfig{{Type: "webhook", Name: "slow", URL: srv.URL, TimeoutMs: 100}}, false)
+    if err != nil {
+        t.Fatal(err)
+    }
+
+    results := p.Deliver(&types.Measurement{ID: "m-1", Weight: 1200, Unit: "g", Trigger: "weight"})
+    if len(results) != 1 || results[0].Status != types.OutputQueued {
+        t.Fatalf("результаты доставки: %+v", results)
+    }
+    pending, err := outbox.Get("slow").List(outbox.QueuePending)
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(pending) != 1 || pending[0].ID != "m-1" || pending[0].Attempts != 1 {
+        t.Fatalf("очередь повторов: %+v", pending)
+    }
+}
\ No newline at end of file
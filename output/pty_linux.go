@@ -0,0 +1,60 @@
+package output
+
+import (
+    "fmt"
+    "io"
+    "os"
+    "syscall"
+    "unsafe"
+)
+
+// openPty создает псевдотерминал в режиме raw и ссылку path на его подчиненную сторону,
+// которую старая программа открывает как обычный COM-порт
+func openPty(path string) (io.ReadWriteCloser, error) {
+    master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|syscall.O_NOCTTY, 0)
+    if err != nil {
+        return nil, err
+    }
+    var unlock int32
+    if err := ioctl(master.Fd(), syscall.TIOCSPTLCK, uintptr(unsafe.Pointer(&unlock))); err != nil {
+        master.Close()
+        return nil, fmt.Errorf("unlockpt: %v", err)
+    }
+    var number uint32
+    if err := ioctl(master.Fd(), syscall.TIOCGPTN, uintptr(unsafe.Pointer(&number))); err != nil {
+        master.Close()
+        return nil, fmt.Errorf("ptsname: %v", err)
+    }
+    slavePath := fmt.Sprintf("/dev/pts/%d", number)
+
+    // Без raw-режима терминал буферизует байты до перевода строки и возвращает эхо
+    slave, err := os.OpenFile(slavePath, os.O_RDWR|syscall.O_NOCTTY, 0)
+    if err != nil {
+        master.Close()
+        return nil, err
+    }
+    var termios syscall.Termios
+    if err := ioctl(slave.Fd(), syscall.TCGETS, uintptr(unsafe.Pointer(&termios))); err == nil {
+        termios.Iflag &^= syscall.IGNBRK | syscall.BRKINT | syscall.PARMRK | syscall.ISTRIP | syscall.INLCR | syscall.IGNCR | syscall.ICRNL | syscall.IXON
+        termios.Oflag &^= syscall.OPOST
+        termios.Lflag &^= syscall.ECHO | syscall.ECHONL | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+        termios.Cflag &^= syscall.CSIZE | syscall.PARENB
+        termios.Cflag |= syscall.CS8
+        ioctl(slave.Fd(), syscall.TCSETS, uintptr(unsafe.Pointer(&termios)))
+    }
+    slave.Close()
+
+    os.Remove(path)
+    if err := os.Symlink(slavePath, path); err != nil {
+        master.Close()
+        return nil, err
+    }
+    return master, nil
+}
+
+func ioctl(fd, request, arg uintptr) error {
+    if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, request, arg); errno != 0 {
+        return errno
+    }
+    return nil
+}
\ No newline at end of file
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package output
+
+import (
+    "errors"
+    "io"
+)
+
+// openPty: псевдотерминалы поддерживаются только на Linux
+func openPty(path string) (io.ReadWriteCloser, error) {
+    return nil, errors.New("виртуальный порт создается только на Linux, укажите address — сторону пары виртуальных COM-портов")
+}
\ No newline at end of file
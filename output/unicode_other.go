@@ -0,0 +1,34 @@
+//go:build !windows
+
+package output
+
+import (
+    "errors"
+    "os"
+    "os/exec"
+    "runtime"
+    "strings"
+)
+
+// typeUnicode вводит символ в обход раскладки: на Linux через xdotool (X11) или wtype (Wayland),
+// на macOS через System Events
+func typeUnicode(r rune) error {
+    text := string(r)
+    switch runtime.GOOS {
+    case "darwin":
+        script := `tell application "System Events" to keystroke "` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(text) + `"`
+        return exec.Command("osascript", "-e", script).Run()
+    case "linux":
+        if os.Getenv("WAYLAND_DISPLAY") != "" {
+            if _, err := exec.LookPath("wtype"); err == nil {
+                return exec.Command("wtype", "--", text).Run()
+            }
+        }
+        if _, err := exec.LookPath("xdotool"); err == nil {
+            return exec.Command("xdotool", "type", "--", text).Run()
+        }
+        return errors.New("для ввода символов вне цифр и латиницы установите xdotool (X11) или wtype (Wayland)")
+    default:
+        return errors.New("ввод символов вне цифр и латиницы не поддерживается на " + runtime.GOOS)
+    }
+}
\ No newline at end of file
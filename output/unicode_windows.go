@@ -0,0 +1,52 @@
+package output
+
+import (
+    "syscall"
+    "unicode/utf16"
+    "unsafe"
+)
+
+var (
+    user32        = syscall.NewLazyDLL("user32.dll")
+    procSendInput = user32.NewProc("SendInput")
+)
+
+const (
+    INPUT_KEYBOARD    = 1
+    KEYEVENTF_KEYUP   = 0x0002
+    KEYEVENTF_UNICODE = 0x0004
+)
+
+// keybdInput — KEYBDINPUT. Из-за поля ULONG_PTR структура выравнивается по размеру указателя,
+// поэтому в INPUT она начинается со смещения 8 на 64-битной Windows и 4 на 32-битной.
+type keybdInput struct {
+    vk        uint16
+    scan      uint16
+    flags     uint32
+    time      uint32
+    extraInfo uintptr
+}
+
+// keyboardInput — INPUT с KEYBDINPUT. Объединение в INPUT имеет размер MOUSEINPUT, который на 8 байт
+// больше KEYBDINPUT на обеих архитектурах: всего 40 байт на amd64 и 28 на 386.
+type keyboardInput struct {
+    inputType uint32
+    ki        keybdInput
+    _         [8]byte
+}
+
+// typeUnicode вводит символ через SendInput с KEYEVENTF_UNICODE: Windows передает окну
+// сам символ, а не клавишу, поэтому результат не зависит от раскладки
+func typeUnicode(r rune) error {
+    var inputs []keyboardInput
+    for _, unit := range utf16.Encode([]rune{r}) {
+        inputs = append(inputs,
+            keyboardInput{inputType: INPUT_KEYBOARD, ki: keybdInput{scan: unit, flags: KEYEVENTF_UNICODE}},
+            keyboardInput{inputType: INPUT_KEYBOARD, ki: keybdInput{scan: unit, flags: KEYEVENTF_UNICODE | KEYEVENTF_KEYUP}})
+    }
+    sent, _, err := procSendInput.Call(uintptr(len(inputs)), uintptr(unsafe.Pointer(&inputs[0])), unsafe.Sizeof(inputs[0]))
+    if int(sent) != len(inputs) {
+        return err
+    }
+    return nil
+}
\ No newline at end of file
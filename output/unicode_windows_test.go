@@ -0,0 +1,27 @@
+package output
+
+import (
+    "runtime"
+    "testing"
+    "unsafe"
+)
+
+// SendInput отклоняет вызов с ERROR_INVALID_PARAMETER, если размер INPUT не совпадает с системным
+func TestKeyboardInputLayout(t *testing.T) {
+    var size, offset uintptr
+    switch runtime.GOARCH {
+    case "amd64", "arm64":
+        size, offset = 40, 8
+    case "386", "arm":
+        size, offset = 28, 4
+    default:
+        t.Skip("неизвестная архитектура " + runtime.GOARCH)
+    }
+    var in keyboardInput
+    if got := unsafe.Sizeof(in); got != size {
+        t.Errorf("размер INPUT %d, ожидался %d", got, size)
+    }
+    if got := unsafe.Offsetof(in.ki); got != offset {
+        t.Errorf("смещение KEYBDINPUT %d, ожидалось %d", got, offset)
+    }
+}
\ No newline at end of file
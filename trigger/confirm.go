@@ -0,0 +1,102 @@
+package trigger
+
+import (
+    "errors"
+    "fmt"
+    "strings"
+
+    "betelgeuze-measure-system-main/events"
+    "betelgeuze-measure-system-main/history"
+    "betelgeuze-measure-system-main/logging"
+    "betelgeuze-measure-system-main/measurement"
+    "betelgeuze-measure-system-main/types"
+    "betelgeuze-measure-system-main/validation"
+)
+
+// ErrNotFound — измерения нет в истории
+var ErrNotFound = errors.New("измерение не найдено")
+
+// ErrNotAwaiting — измерение уже отправлено или отменено
+var ErrNotAwaiting = errors.New("измерение не ждет подтверждения")
+
+// Approve применяет исправления оператора и отправляет измерение в выходы.
+// Подтвердить можно измерение, которое ждет подтверждения или было отклонено проверкой;
+// после исправлений проверка выполняется заново.
+func Approve(id string, changes measurement.Changes, user string) (*types.Measurement, error) {
+    e := defaultEngine
+    if e == nil {
+        return nil, errors.New("политика запуска не настроена")
+    }
+    // Не пересекаемся с текущим измерением: выходы получают результаты по одному
+    e.measuring.Lock()
+    defer e.measuring.Unlock()
+
+    m, err := pending(id)
+    if err != nil {
+        return nil, err
+    }
+    if measurement.Correct(m, changes, user) {
+        logging.BroadcastLog(fmt.Sprintf("Измерение %s исправлено оператором %s (ревизия %d): %s", m.ID, user, m.Revision, measurement.Format(m)), "system")
+    }
+    if !validation.Validate(e.state, m) {
+        measurement.Save(m)
+        e.updateLast(m)
+        events.Publish(events.MeasurementUpdated, m)
+        reasons := strings.Join(m.Violations, "; ")
+        return m, fmt.Errorf("%w: %s", ErrRejected, reasons)
+    }
+    m.OutputStatus = types.OutputPending
+    e.updateLast(m)
+    logging.BroadcastLog(fmt.Sprintf("Измерение %s подтверждено оператором %s", m.ID, user), "system")
+    deliver(m)
+    events.Publish(events.MeasurementUpdated, m)
+    return m, nil
+}
+
+// Discard отменяет отправку измерения. Запись остается в истории со статусом discarded.
+func Discard(id string, user string) (*types.Measurement, error) {
+    e := defaultEngine
+    if e == nil {
+        return nil, errors.New("политика запуска не настроена")
+    }
+    e.measuring.Lock()
+    defer e.measuring.Unlock()
+
+    m, err := pending(id)
+    if err != nil {
+        return nil, err
+    }
+    measurement.SetOutputStatus(m, types.OutputDiscarded)
+    e.updateLast(m)
+    logging.BroadcastLog(fmt.Sprintf("Измерение %s отменено оператором %s", m.ID, user), "system")
+    events.Publish(events.MeasurementUpdated, m)
+    return m, nil
+}
+
+// pending читает из истории измерение, которое еще можно подтвердить или отменить
+func pending(id string) (*types.Measurement, error) {
+    store := history.Default()
+    if store == nil {
+        return nil, errors.New("история измерений недоступна")
+    }
+    m, err := store.Get(id)
+    if err != nil {
+        return nil, err
+    }
+    if m == nil {
+        return nil, ErrNotFound
+    }
+    if m.OutputStatus != types.OutputAwaiting && m.OutputStatus != types.OutputRejected {
+        return nil, fmt.Errorf("%w (статус %s)", ErrNotAwaiting, m.OutputStatus)
+    }
+    return m, nil
+}
+
+// updateLast заменяет последнее измерение в статусе устройств его новой ревизией
+func (e *Engine) updateLast(m *types.Measurement) {
+    if last := e.state.Status.LastMeasurement; last != nil && last.ID == m.ID {
+        e.state.Status.LastWeight = m.Weight
+        e.state.Status.LastDimensions = measurement.Format(m)
+        e.state.Status.LastMeasurement = m
+    }
+}
\ No newline at end of file
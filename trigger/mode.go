@@ -0,0 +1,127 @@
+package trigger
+
+import (
+    "errors"
+    "fmt"
+    "sync"
+    "time"
+
+    "betelgeuze-measure-system-main/events"
+    "betelgeuze-measure-system-main/logging"
+    "betelgeuze-measure-system-main/types"
+)
+
+// ErrPaused — автоматические измерения приостановлены
+var ErrPaused = errors.New("автоматические измерения приостановлены")
+
+// HOLD_DURATION — на сколько приостанавливаются измерения после ручной команды или калибровки
+const HOLD_DURATION = 30 * time.Second
+
+// modeState — режим автоматических измерений. Пауза оператора и временная
+// пауза на время ручных команд независимы: ручная команда не снимает паузу оператора.
+type modeState struct {
+    mu         sync.Mutex
+    state      *types.AppState
+    mode       string
+    holdUntil  time.Time
+    holdReason string
+    timer      *time.Timer
+}
+
+var mode = &modeState{mode: types.ModeAuto}
+
+// initMode привязывает режим к статусу устройств
+func initMode(state *types.AppState) {
+    mode.mu.Lock()
+    defer mode.mu.Unlock()
+    mode.state = state
+    mode.publish()
+}
+
+// publish обновляет режим в статусе устройств и сообщает о смене событием state.changed.
+// Вызывается под блокировкой.
+func (s *modeState) publish() {
+    if s.state == nil {
+        return
+    }
+    current, reason := s.mode, ""
+    switch {
+    case s.mode == types.ModePaused:
+        reason = "пауза оператора"
+    case time.Now().Before(s.holdUntil):
+        current, reason = types.ModePaused, s.holdReason
+    }
+    if s.state.Status.Mode == current && s.state.Status.ModeReason == reason {
+        return
+    }
+    initial := s.state.Status.Mode == ""
+    s.state.Status.Mode = current
+    s.state.Status.ModeReason = reason
+    if initial {
+        return
+    }
+    events.Publish(events.StateChanged, map[string]string{"mode": current, "reason": reason})
+}
+
+// SetMode переключает режим автоматических измерений: auto, paused или single
+func SetMode(m string) error {
+    switch m {
+    case types.ModeAuto, types.ModePaused, types.ModeSingle:
+    default:
+        return fmt.Errorf("неизвестный режим: %s", m)
+    }
+    mode.mu.Lock()
+    mode.mode = m
+    mode.publish()
+    mode.mu.Unlock()
+
+    messages := map[string]string{
+        types.ModeAuto:   "Автоматические измерения возобновлены",
+        types.ModePaused: "Автоматические измерения приостановлены",
+        types.ModeSingle: "Будет измерен только следующий объект",
+    }
+    logging.BroadcastLog(messages[m], "system")
+    return nil
+}
+
+// Hold приостанавливает автоматические измерения на HOLD_DURATION, например на время
+// калибровки или ручных команд из веб-интерфейса. Повторный вызов продлевает паузу.
+func Hold(reason string) {
+    mode.mu.Lock()
+    defer mode.mu.Unlock()
+    if time.Now().After(mode.holdUntil) {
+        logging.BroadcastLog(fmt.Sprintf("Автоматические измерения приостановлены на %v: %s", HOLD_DURATION, reason), "system")
+    }
+    mode.holdUntil = time.Now().Add(HOLD_DURATION)
+    mode.holdReason = reason
+    mode.publish()
+    if mode.timer != nil {
+        mode.timer.Stop()
+    }
+    mode.timer = time.AfterFunc(HOLD_DURATION, func() {
+        mode.mu.Lock()
+        defer mode.mu.Unlock()
+        mode.publish()
+    })
+}
+
+// Paused сообщает, приостановлены ли автоматические измерения
+func Paused() bool {
+    mode.mu.Lock()
+    defer mode.mu.Unlock()
+    return mode.mode == types.ModePaused || time.Now().Before(mode.holdUntil)
+}
+
+// completed переводит режим single в паузу после измерения
+func completed() {
+    mode.mu.Lock()
+    single := mode.mode == types.ModeSingle
+    if single {
+        mode.mode = types.ModePaused
+        mode.publish()
+    }
+    mode.mu.Unlock()
+    if single {
+        logging.BroadcastLog("Объект измерен, автоматические измерения приостановлены", "system")
+    }
+}
\ No newline at end of file
@@ -0,0 +1,269 @@
+package trigger
+
+import (
+    "errors"
+    "fmt"
+    "strings"
+    "sync"
+    "time"
+
+    "betelgeuze-measure-system-main/config"
+    "betelgeuze-measure-system-main/devices"
+    "betelgeuze-measure-system-main/events"
+    "betelgeuze-measure-system-main/feedback"
+    "betelgeuze-measure-system-main/logging"
+    "betelgeuze-measure-system-main/measurement"
+    "betelgeuze-measure-system-main/output"
+    "betelgeuze-measure-system-main/types"
+    "betelgeuze-measure-system-main/validation"
+)
+
+// SOURCES — источники, из которых собирается политика запуска
+var SOURCES = []string{types.TriggerWeight, types.TriggerButton, types.TriggerBarcode, types.TriggerWeb, types.TriggerModbus}
+
+// ErrNotAllowed — источник не входит в политику запуска станции
+var ErrNotAllowed = errors.New("источник запуска не разрешен политикой станции")
+
+// ErrRejected — измерение выполнено, но не прошло проверку и не отправлено в выходы
+var ErrRejected = errors.New("измерение отклонено проверкой")
+
+// Engine собирает события источников и запускает измерение, когда выполнено
+// одно из правил политики. Измерения выполняются по одному.
+type Engine struct {
+    state  *types.AppState
+    rules  [][]string
+    window time.Duration
+
+    mu     sync.Mutex
+    events map[string]time.Time // время последнего события каждого источника после прошлого измерения
+
+    measuring sync.Mutex
+}
+
+var defaultEngine *Engine
+
+// Init разбирает политику запуска станции
+func Init(cfg config.TriggerConfig, state *types.AppState) error {
+    initMode(state)
+    e, err := NewEngine(cfg, state)
+    if err != nil {
+        return err
+    }
+    defaultEngine = e
+    return nil
+}
+
+// NewEngine создает обработчик политики запуска
+func NewEngine(cfg config.TriggerConfig, state *types.AppState) (*Engine, error) {
+    e := &Engine{
+        state:  state,
+        window: time.Duration(cfg.WindowSec) * time.Second,
+        events: make(map[string]time.Time),
+    }
+    if e.window == 0 {
+        e.window = time.Minute
+    }
+    for _, rule := range cfg.Policy {
+        var sources []string
+        for _, source := range strings.Split(rule, "+") {
+            source = strings.TrimSpace(source)
+            if !validSource(source) {
+                return nil, fmt.Errorf("неизвестный источник запуска %q в правиле %q", source, rule)
+            }
+            sources = append(sources, source)
+        }
+        e.rules = append(e.rules, sources)
+    }
+    if len(e.rules) == 0 {
+        return nil, errors.New("политика запуска пуста")
+    }
+    return e, nil
+}
+
+func validSource(source string) bool {
+    for _, s := range SOURCES {
+        if s == source {
+            return true
+        }
+    }
+    return false
+}
+
+// Uses сообщает, участвует ли источник хотя бы в одном правиле
+func (e *Engine) Uses(source string) bool {
+    for _, rule := range e.rules {
+        for _, s := range rule {
+            if s == source {
+                return true
+            }
+        }
+    }
+    return false
+}
+
+// Fire регистрирует событие источника. Если событие завершило правило политики,
+// выполняет измерение и возвращает его; если правило ждет других событий — nil без ошибки.
+func (e *Engine) Fire(source string) (*types.Measurement, error) {
+    if !e.Uses(source) {
+        return nil, ErrNotAllowed
+    }
+    // Пауза касается только автоматических источников: кнопка веб-интерфейса работает всегда
+    automatic := source != types.TriggerWeb
+    if automatic && Paused() {
+        return nil, ErrPaused
+    }
+
+    e.mu.Lock()
+    now := time.Now()
+    e.events[source] = now
+    var matched []string
+    for _, rule := range e.rules {
+        if e.complete(rule, source, now) {
+            matched = rule
+            break
+        }
+    }
+    if matched == nil {
+        e.mu.Unlock()
+        logging.BroadcastLog(fmt.Sprintf("Событие запуска %s принято, ожидание остальных условий", source), "system")
+        feedback.Emit(feedback.EventWaiting)
+        return nil, nil
+    }
+    e.events = make(map[string]time.Time)
+    e.mu.Unlock()
+
+    m, err := e.measure(strings.Join(matched, "+"))
+    // Отклоненное измерение (m вместе с ErrRejected) не завершает объект: его нужно измерить заново
+    if automatic && m != nil && err == nil {
+        completed()
+    }
+    return m, err
+}
+
+// complete проверяет, что правило содержит источник и все его события свежие
+func (e *Engine) complete(rule []string, source string, now time.Time) bool {
+    contains := false
+    for _, s := range rule {
+        at, ok := e.events[s]
+        if !ok || now.Sub(at) > e.window {
+            return false
+        }
+        if s == source {
+            contains = true
+        }
+    }
+    return contains
+}
+
+// measure выполняет измерение, сохраняет его и отправляет в выходы
+func (e *Engine) measure(trigger string) (*types.Measurement, error) {
+    e.measuring.Lock()
+    defer e.measuring.Unlock()
+
+    m, err := measurement.Take(e.state, trigger)
+    if err != nil {
+        logging.Error(fmt.Sprintf("Измерение (%s) не выполнено: %v", trigger, err), "system")
+        feedback.Emit(feedback.EventError)
+        events.Publish(events.MeasurementFailed, map[string]string{"trigger": trigger, "error": err.Error()})
+        return nil, err
+    }
+    if !validation.Validate(e.state, m) {
+        measurement.Record(e.state, m)
+        reasons := strings.Join(m.Violations, "; ")
+        logging.Warn(fmt.Sprintf("Измерение %s (%s) отклонено: %s", m.ID, measurement.Format(m), reasons), "system")
+        feedback.Emit(feedback.EventRejected)
+        events.Publish(events.MeasurementCompleted, m)
+        return m, fmt.Errorf("%w: %s", ErrRejected, reasons)
+    }
+    if config.Get().ConfirmBeforeSend {
+        m.OutputStatus = types.OutputAwaiting
+        measurement.Record(e.state, m)
+        logging.BroadcastLog(fmt.Sprintf("Измерение (%s): %s — ждет подтверждения оператора", trigger, measurement.Format(m)), "system")
+        feedback.Emit(feedback.EventWaiting)
+        events.Publish(events.MeasurementCompleted, m)
+        return m, nil
+    }
+    measurement.Record(e.state, m)
+    logging.BroadcastLog(fmt.Sprintf("Измерение (%s): %s", trigger, measurement.Format(m)), "system")
+    deliver(m)
+    events.Publish(events.MeasurementCompleted, m)
+    return m, nil
+}
+
+// deliver отправляет измерение в выходы и подает сигнал оператору.
+// Ошибка вывода не повод измерять тот же объект заново: измерение уже в истории.
+func deliver(m *types.Measurement) {
+    if status := output.Deliver(m); status == types.OutputFailed || status == types.OutputPartial {
+        logging.Warn(fmt.Sprintf("Измерение %s доставлено не во все выходы (%s)", m.ID, status), "system")
+        feedback.Emit(feedback.EventDeliveryFailed)
+    } else {
+        feedback.Emit(feedback.EventSuccess)
+    }
+}
+
+// WatchButton опрашивает кнопку Arduino и сообщает о нажатиях
+func (e *Engine) WatchButton(interval time.Duration) {
+    if interval == 0 {
+        interval = 300 * time.Millisecond
+    }
+    reported := false
+    for {
+        time.Sleep(interval)
+        if !e.state.Status.ArduinoConnected || e.state.Arduino == nil {
+            continue
+        }
+        presses, err := devices.ReadButtonPresses(e.state.Arduino)
+        if err != nil {
+            // Старая прошивка не знает команду 0x87 — сообщаем один раз
+            if !reported {
+                logging.Warn(fmt.Sprintf("Кнопка Arduino: %v", err), "arduino")
+                reported = true
+            }
+            continue
+        }
+        reported = false
+        if presses > 0 {
+            logging.BroadcastLog("Нажата кнопка Arduino", "arduino")
+            e.Fire(types.TriggerButton)
+        }
+    }
+}
+
+// Start запускает опрос кнопки, если она входит в политику
+func Start(cfg config.TriggerConfig) {
+    if e := defaultEngine; e != nil && e.Uses(types.TriggerButton) {
+        go e.WatchButton(time.Duration(cfg.ButtonPollMs) * time.Millisecond)
+    }
+}
+
+// Uses сообщает, участвует ли источник в политике станции
+func Uses(source string) bool {
+    return defaultEngine != nil && defaultEngine.Uses(source)
+}
+
+// Scan принимает штрихкод под блокировкой измерений: с "attach": "current" код дописывается
+// к последнему измерению только после того, как оно сохранено и отправлено в выходы
+func Scan(state *types.AppState, raw string) (string, error) {
+    if e := defaultEngine; e != nil {
+        e.measuring.Lock()
+        defer e.measuring.Unlock()
+    }
+    return measurement.Scan(state, raw)
+}
+
+// Record сохраняет измерение, снятое в обход политики (только вес), не пересекаясь с текущим измерением
+func Record(state *types.AppState, m *types.Measurement) {
+    if e := defaultEngine; e != nil {
+        e.measuring.Lock()
+        defer e.measuring.Unlock()
+    }
+    measurement.Record(state, m)
+}
+
+// Fire передает событие в политику запуска станции
+func Fire(source string) (*types.Measurement, error) {
+    if defaultEngine == nil {
+        return nil, errors.New("политика запуска не настроена")
+    }
+    return defaultEngine.Fire(source)
+}
\ No newline at end of file
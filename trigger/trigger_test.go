@@ -0,0 +1,226 @@
+package trigger
+
+import (
+    "encoding/json"
+    "errors"
+    "path/filepath"
+    "testing"
+    "time"
+
+    "betelgeuze-measure-system-main/config"
+    "betelgeuze-measure-system-main/devices"
+    "betelgeuze-measure-system-main/events"
+    "betelgeuze-measure-system-main/history"
+    "betelgeuze-measure-system-main/types"
+)
+
+// fakeScale отвечает на запрос веса заданным весом
+type fakeScale struct {
+    weight float64
+}
+
+func (s *fakeScale) Write(p []byte) (int, error) { return len(p), nil }
+func (s *fakeScale) Read(p []byte) (int, error)  { return copy(p, devices.EncodeWeight(s.weight)), nil }
+func (s *fakeScale) Close() error                { return nil }
+
+// setup настраивает станцию с весами без Arduino, историей во временном каталоге и политикой policy
+func setup(t *testing.T, weight float64, policy ...string) *Engine {
+    t.Helper()
+    dir := t.TempDir()
+    config.LoadSettings(filepath.Join(dir, "missing.json"))
+    if err := history.Init(dir); err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(func() { history.Default().Close() })
+
+    state := &types.AppState{Scale: &types.ScalePort{Connection: &fakeScale{weight: weight}, PortName: "scale"}}
+    state.Status.ScaleConnected = true
+    if err := Init(config.TriggerConfig{Policy: policy}, state); err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(func() {
+        SetMode(types.ModeAuto)
+        mode.mu.Lock()
+        mode.holdUntil = time.Time{}
+        mode.mu.Unlock()
+    })
+    return defaultEngine
+}
+
+func TestNewEngine(t *testing.T) {
+    config.LoadSettings(filepath.Join(t.TempDir(), "missing.json"))
+    e, err := NewEngine(config.TriggerConfig{Policy: []string{"weight", " barcode + button "}}, &types.AppState{})
+    if err != nil {
+        t.Fatal(err)
+    }
+    if e.window != time.Minute {
+        t.Errorf("окно по умолчанию %s, ожидалась минута", e.window)
+    }
+    if len(e.rules) != 2 || len(e.rules[1]) != 2 || e.rules[1][0] != "barcode" || e.rules[1][1] != "button" {
+        t.Errorf("правила: %q", e.rules)
+    }
+    for _, source := range []string{"weight", "barcode", "button"} {
+        if !e.Uses(source) {
+            t.Errorf("источник %s не входит в политику", source)
+        }
+    }
+    if e.Uses("modbus") {
+        t.Error("modbus входит в политику")
+    }
+
+    for _, policy := range [][]string{nil, {"weight+laser"}, {""}} {
+        if _, err := NewEngine(config.TriggerConfig{Policy: policy}, &types.AppState{}); err == nil {
+            t.Errorf("политика %q принята", policy)
+        }
+    }
+}
+
+func TestFireCombinedRule(t *testing.T) {
+    e := setup(t, 1200, "barcode+weight", "web")
+
+    if _, err := e.Fire(types.TriggerButton); !errors.Is(err, ErrNotAllowed) {
+        t.Fatalf("источник не из политики: %v", err)
+    }
+    m, err := e.Fire(types.TriggerWeight)
+    if m != nil || err != nil {
+        t.Fatalf("правило без штрихкода выполнено: %+v, %v", m, err)
+    }
+    m, err = e.Fire(types.TriggerBarcode)
+    if err != nil || m == nil {
+        t.Fatalf("правило не выполнено: %v", err)
+    }
+    if m.Trigger != "barcode+weight" || m.Weight != 1200 || m.OutputStatus == types.OutputRejected {
+        t.Errorf("измерение: %+v", m)
+    }
+    if saved, err := history.Default().Get(m.ID); err != nil || saved == nil {
+        t.Errorf("измерение не сохранено в историю: %v", err)
+    }
+
+    // После измерения события сброшены: одного штрихкода снова мало
+    if m, err := e.Fire(types.TriggerBarcode); m != nil || err != nil {
+        t.Errorf("старое событие веса использовано повторно: %+v, %v", m, err)
+    }
+    // Правило из одного источника выполняется сразу
+    if m, err := e.Fire(types.TriggerWeb); err != nil || m == nil || m.Trigger != "web" {
+        t.Errorf("web: %+v, %v", m, err)
+    }
+}
+
+func TestFireWindowExpires(t *testing.T) {
+    e := setup(t, 1200, "barcode+weight")
+    if _, err := e.Fire(types.TriggerWeight); err != nil {
+        t.Fatal(err)
+    }
+    e.mu.Lock()
+    e.events[types.TriggerWeight] = time.Now().Add(-e.window - time.Second)
+    e.mu.Unlock()
+    if m, err := e.Fire(types.TriggerBarcode); m != nil || err != nil {
+        t.Fatalf("событие старше окна использовано: %+v, %v", m, err)
+    }
+    // Свежее событие веса завершает правило со штрихкодом, который еще в окне
+    if m, err := e.Fire(types.TriggerWeight); err != nil || m == nil {
+        t.Fatalf("правило не выполнено: %v", err)
+    }
+}
+
+func TestSetMode(t *testing.T) {
+    e := setup(t, 1200, "weight", "web")
+    if err := SetMode("sometimes"); err == nil {
+        t.Error("неизвестный режим принят")
+    }
+
+    if err := SetMode(types.ModePaused); err != nil {
+        t.Fatal(err)
+    }
+    if e.state.Status.Mode != types.ModePaused || e.state.Status.ModeReason == "" {
+        t.Errorf("статус режима: %q (%q)", e.state.Status.Mode, e.state.Status.ModeReason)
+    }
+    if _, err := e.Fire(types.TriggerWeight); !errors.Is(err, ErrPaused) {
+        t.Errorf("на паузе автоматический источник: %v", err)
+    }
+    // Кнопка веб-интерфейса работает и на паузе
+    if m, err := e.Fire(types.TriggerWeb); err != nil || m == nil {
+        t.Errorf("на паузе web: %+v, %v", m, err)
+    }
+
+    // Single: одно автоматическое измерение, затем пауза
+    if err := SetMode(types.ModeSingle); err != nil {
+        t.Fatal(err)
+    }
+    if m, err := e.Fire(types.TriggerWeight); err != nil || m == nil {
+        t.Fatalf("single: %+v, %v", m, err)
+    }
+    if !Paused() || e.state.Status.Mode != types.ModePaused {
+        t.Fatalf("после измерения в single режим %q", e.state.Status.Mode)
+    }
+    if _, err := e.Fire(types.TriggerWeight); !errors.Is(err, ErrPaused) {
+        t.Errorf("второе измерение в single: %v", err)
+    }
+
+    // Hold приостанавливает и режим auto, пауза оператора не снимается вместе с ним
+    SetMode(types.ModeAuto)
+    Hold("калибровка")
+    if !Paused() || e.state.Status.ModeReason != "калибровка" {
+        t.Errorf("Hold: пауза %v, причина %q", Paused(), e.state.Status.ModeReason)
+    }
+}
+
+func TestRejectedMeasurement(t *testing.T) {
+    e := setup(t, 1200, "weight")
+    config.Get().Validation = config.ValidationConfig{Enabled: true, MinWeight: 5000}
+    sub, _, _ := events.Default().Subscribe([]string{events.MeasurementCompleted}, 0)
+    defer events.Default().Unsubscribe(sub)
+
+    SetMode(types.ModeSingle)
+    m, err := e.Fire(types.TriggerWeight)
+    if !errors.Is(err, ErrRejected) || m == nil {
+        t.Fatalf("ожидалось отклонение: %+v, %v", m, err)
+    }
+    if m.OutputStatus != types.OutputRejected || len(m.Violations) != 1 {
+        t.Errorf("отклоненное измерение: статус %q, причины %q", m.OutputStatus, m.Violations)
+    }
+    // Отклоненный объект нужно измерить заново: single не переходит в паузу
+    if Paused() {
+        t.Error("после отклонения single перешел в паузу")
+    }
+
+    saved, err := history.Default().Get(m.ID)
+    if err != nil || saved == nil || saved.OutputStatus != types.OutputRejected {
+        t.Errorf("в истории: %+v, %v", saved, err)
+    }
+    select {
+    case event := <-sub.C:
+        var published types.Measurement
+        json.Unmarshal(event.Data, &published)
+        if published.ID != m.ID || published.OutputStatus != types.OutputRejected {
+            t.Errorf("событие %s: %+v", event.Type, published)
+        }
+    case <-time.After(time.Second):
+        t.Error("событие об отклоненном измерении не опубликовано")
+    }
+}
+
+func TestConfirmBeforeSend(t *testing.T) {
+    e := setup(t, 1200, "weight")
+    config.Get().ConfirmBeforeSend = true
+
+    SetMode(types.ModeSingle)
+    m, err := e.Fire(types.TriggerWeight)
+    if err != nil || m == nil || m.OutputStatus != types.OutputAwaiting {
+        t.Fatalf("ожидание подтверждения: %+v, %v", m, err)
+    }
+    // Измерение, ждущее подтверждения, завершает объект
+    if !Paused() {
+        t.Error("после измерения в single режим не приостановлен")
+    }
+
+    if _, err := Discard(m.ID, "smena1"); err != nil {
+        t.Fatal(err)
+    }
+    if _, err := Discard(m.ID, "smena1"); !errors.Is(err, ErrNotAwaiting) {
+        t.Errorf("повторная отмена: %v", err)
+    }
+    if _, err := Discard("no-such-id", "smena1"); !errors.Is(err, ErrNotFound) {
+        t.Errorf("отмена несуществующего: %v", err)
+    }
+}
\ No newline at end of file
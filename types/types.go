@@ -3,6 +3,7 @@ package types
 import (
     "io"
     "sync"
+    "time"
     
     arduinoSerial "go.bug.st/serial"
 )
@@ -10,31 +11,128 @@ import (
 type ArduinoPort struct {
     Port     arduinoSerial.Port
     PortName string
+    Mutex    sync.Mutex // порт опрашивают основной цикл, веб-интерфейс и Modbus
+
+    // Максимумы габаритов из последнего ответа прошивки на 0x89, 0 — еще неизвестны
+    MaxLength int
+    MaxWidth  int
+    MaxHeight int
 }
 
 type ScalePort struct {
     Connection io.ReadWriteCloser
     PortName   string
+    Mutex      sync.Mutex // порт опрашивают основной цикл, веб-интерфейс и Modbus
+}
+
+type ScannerPort struct {
+    Port     arduinoSerial.Port
+    PortName string
 }
 
 type DeviceStatus struct {
-    ArduinoConnected bool    `json:"arduino_connected"`
-    ArduinoPort      string  `json:"arduino_port"`
-    ScaleConnected   bool    `json:"scale_connected"`
-    ScalePort        string  `json:"scale_port"`
-    LastWeight       float64 `json:"last_weight"`
-    LastDimensions   string  `json:"last_dimensions"`
+    ArduinoConnected bool         `json:"arduino_connected"`
+    ArduinoPort      string       `json:"arduino_port"`
+    ScaleConnected   bool         `json:"scale_connected"`
+    ScalePort        string       `json:"scale_port"`
+    ScannerConnected bool         `json:"scanner_connected"`
+    ScannerPort      string       `json:"scanner_port,omitempty"`
+    PendingBarcode   string       `json:"pending_barcode,omitempty"` // отсканированный код, ожидающий измерения
+    CurrentWeight    float64      `json:"current_weight"` // последнее показание весов, в том числе без измерения
+    LastWeight       float64      `json:"last_weight"`
+    LastDimensions   string       `json:"last_dimensions"`
+    LastMeasurement  *Measurement `json:"last_measurement,omitempty"`
+    MeasurementCount uint64       `json:"measurement_count"` // измерений с момента запуска
+    Mode             string       `json:"mode"`                  // режим автоматических измерений: auto, paused, single
+    ModeReason       string       `json:"mode_reason,omitempty"` // почему измерения приостановлены
+    Headless         bool         `json:"headless"`
+}
+
+// Режимы автоматических измерений
+const (
+    ModeAuto   = "auto"   // каждый новый объект измеряется
+    ModePaused = "paused" // автоматические измерения приостановлены
+    ModeSingle = "single" // измеряется только следующий объект, затем пауза
+)
+
+// Источники запуска измерения
+const (
+    TriggerWeight  = "weight"  // изменение веса в основном цикле
+    TriggerWeb     = "web"     // кнопка в веб-интерфейсе
+    TriggerModbus  = "modbus"  // катушка запуска Modbus TCP
+    TriggerButton  = "button"  // кнопка на Arduino (пин 8)
+    TriggerBarcode = "barcode" // скан штрихкода
+)
+
+// Статусы вывода результата измерения
+const (
+    OutputPending   = "pending"
+    OutputSent      = "sent"
+    OutputQueued    = "queued"    // выход сохранил результат и доставит его позже
+    OutputPartial   = "partial"   // часть выходов не получила результат
+    OutputFailed    = "failed"
+    OutputNone      = "none"
+    OutputRejected  = "rejected"  // измерение не прошло проверку и не отправлялось в выходы
+    OutputAwaiting  = "awaiting"  // ждет подтверждения оператора
+    OutputDiscarded = "discarded" // оператор отказался отправлять измерение
+)
+
+// OutputResult — результат доставки измерения в один выход
+type OutputResult struct {
+    Sink   string    `json:"sink"`
+    Status string    `json:"status"`
+    Error  string    `json:"error,omitempty"`
+    Time   time.Time `json:"time"`
+}
+
+// Correction — исправление одного поля измерения оператором: исходное и новое значение
+type Correction struct {
+    Time  time.Time `json:"time"`
+    User  string    `json:"user"`
+    Field string    `json:"field"`
+    Old   string    `json:"old"`
+    New   string    `json:"new"`
+}
+
+// Measurement — одно завершенное измерение объекта.
+// Вес в граммах (Unit), габариты в сантиметрах, объем в кубических сантиметрах.
+// Объемный и оплачиваемый вес считаются по профилю перевозчика Carrier и тоже хранятся в граммах.
+type Measurement struct {
+    ID               string         `json:"id"`
+    Timestamp        time.Time      `json:"timestamp"`
+    StationID        string         `json:"station_id"`
+    Weight           float64        `json:"weight"`
+    Unit             string         `json:"unit"`
+    Length           int            `json:"length"`
+    Width            int            `json:"width"`
+    Height           int            `json:"height"`
+    Volume           int            `json:"volume"`
+    Carrier          string         `json:"carrier,omitempty"`
+    VolumetricWeight float64        `json:"volumetric_weight"`
+    ChargeableWeight float64        `json:"chargeable_weight"`
+    Barcode          string         `json:"barcode,omitempty"`
+    Trigger          string         `json:"trigger"`
+    ScalePort        string         `json:"scale_port"`
+    ArduinoPort      string         `json:"arduino_port,omitempty"`
+    Confidence       float64        `json:"confidence"`
+    OutputStatus     string         `json:"output_status"`
+    Violations       []string       `json:"violations,omitempty"` // причины отклонения проверкой
+    Revision         int            `json:"revision,omitempty"`   // число исправлений оператором
+    Corrections      []Correction   `json:"corrections,omitempty"`
+    Outputs          []OutputResult `json:"outputs,omitempty"`
 }
 
 type LogMessage struct {
     Time    string `json:"time"`
     Message string `json:"message"`
-    Type    string `json:"type"` // "arduino", "scale", "system"
+    Type    string `json:"type"`  // "arduino", "scale", "system"
+    Level   string `json:"level"` // "info", "warn", "error"
 }
 
 type AppState struct {
     Arduino    *ArduinoPort
     Scale      *ScalePort
+    Scanner    *ScannerPort
     Status     DeviceStatus
     LogClients map[chan LogMessage]bool
     LogMutex   sync.RWMutex
@@ -3,6 +3,7 @@ package utils
 import (
     "fmt"
     "strings"
+    "time"
 )
 
 func BoolToString(b bool) string {
@@ -148,4 +149,23 @@ func decodeSensorID(id byte) string {
     default:
         return fmt.Sprintf("SENSOR_0x%02X", id)
     }
+}
+
+// ParseTimeParam разбирает время в формате RFC3339 или дату "2006-01-02" (по местному времени).
+// Для даты в качестве верхней границы (endOfDay) берется конец дня.
+func ParseTimeParam(value string, endOfDay bool) (time.Time, error) {
+    if value == "" {
+        return time.Time{}, nil
+    }
+    if t, err := time.Parse(time.RFC3339, value); err == nil {
+        return t, nil
+    }
+    t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+    if err != nil {
+        return time.Time{}, fmt.Errorf("неверный формат времени: %s", value)
+    }
+    if endOfDay {
+        t = t.Add(24*time.Hour - time.Nanosecond)
+    }
+    return t, nil
 }
\ No newline at end of file
@@ -0,0 +1,138 @@
+package validation
+
+import (
+    "fmt"
+
+    "betelgeuze-measure-system-main/config"
+    "betelgeuze-measure-system-main/types"
+)
+
+// Limits — максимумы габаритов, которые сообщила прошивка Arduino (0 — неизвестно)
+type Limits struct {
+    Length int
+    Width  int
+    Height int
+}
+
+// Rule — одно правило проверки; возвращает причину отклонения или пустую строку
+type Rule struct {
+    Name  string
+    Check func(m *types.Measurement, limits Limits) string
+}
+
+// Rules собирает правила из настроек станции
+func Rules(cfg config.ValidationConfig) []Rule {
+    if !cfg.Enabled {
+        return nil
+    }
+    var rules []Rule
+    if cfg.NonZeroDimensions {
+        rules = append(rules, Rule{"non_zero_dimensions", nonZeroDimensions})
+    }
+    if cfg.FirmwareLimits {
+        rules = append(rules, Rule{"firmware_limits", firmwareLimits})
+    }
+    rules = appendRange(rules, "length", "длина", "см", float64(cfg.MinLength), float64(cfg.MaxLength), func(m *types.Measurement) float64 { return float64(m.Length) })
+    rules = appendRange(rules, "width", "ширина", "см", float64(cfg.MinWidth), float64(cfg.MaxWidth), func(m *types.Measurement) float64 { return float64(m.Width) })
+    rules = appendRange(rules, "height", "высота", "см", float64(cfg.MinHeight), float64(cfg.MaxHeight), func(m *types.Measurement) float64 { return float64(m.Height) })
+    rules = appendRange(rules, "weight", "вес", "г", cfg.MinWeight, cfg.MaxWeight, func(m *types.Measurement) float64 { return m.Weight })
+    if cfg.MinDensity > 0 || cfg.MaxDensity > 0 {
+        rules = append(rules, Rule{"density", func(m *types.Measurement, _ Limits) string {
+            if m.Volume <= 0 {
+                return ""
+            }
+            // г/см³ → кг/м³
+            density := m.Weight / float64(m.Volume) * 1000
+            if cfg.MinDensity > 0 && density < cfg.MinDensity {
+                return fmt.Sprintf("плотность %.0f кг/м³ меньше %.0f: габариты завышены или вес занижен", density, cfg.MinDensity)
+            }
+            if cfg.MaxDensity > 0 && density > cfg.MaxDensity {
+                return fmt.Sprintf("плотность %.0f кг/м³ больше %.0f: габариты занижены или вес завышен", density, cfg.MaxDensity)
+            }
+            return ""
+        }})
+    }
+    return rules
+}
+
+// appendRange добавляет правило диапазона, если задана хотя бы одна граница.
+// Габариты проверяются только у полных измерений (с Arduino).
+func appendRange(rules []Rule, name, title, unit string, min, max float64, value func(m *types.Measurement) float64) []Rule {
+    if min <= 0 && max <= 0 {
+        return rules
+    }
+    dimension := name != "weight"
+    return append(rules, Rule{name + "_range", func(m *types.Measurement, _ Limits) string {
+        if dimension && !fullMeasurement(m) {
+            return ""
+        }
+        v := value(m)
+        if min > 0 && v < min {
+            return fmt.Sprintf("%s %g %s меньше минимума %g %s", title, v, unit, min, unit)
+        }
+        if max > 0 && v > max {
+            return fmt.Sprintf("%s %g %s больше максимума %g %s", title, v, unit, max, unit)
+        }
+        return ""
+    }})
+}
+
+func fullMeasurement(m *types.Measurement) bool {
+    return m.ArduinoPort != ""
+}
+
+func nonZeroDimensions(m *types.Measurement, _ Limits) string {
+    if !fullMeasurement(m) {
+        return ""
+    }
+    if m.Length <= 0 || m.Width <= 0 || m.Height <= 0 {
+        return fmt.Sprintf("нулевые габариты %d×%d×%d: датчики не распознали объект", m.Length, m.Width, m.Height)
+    }
+    return ""
+}
+
+func firmwareLimits(m *types.Measurement, limits Limits) string {
+    if !fullMeasurement(m) {
+        return ""
+    }
+    check := []struct {
+        title string
+        value int
+        max   int
+    }{
+        {"длина", m.Length, limits.Length},
+        {"ширина", m.Width, limits.Width},
+        {"высота", m.Height, limits.Height},
+    }
+    for _, c := range check {
+        if c.max > 0 && c.value > c.max {
+            return fmt.Sprintf("%s %d см больше максимума прошивки %d см", c.title, c.value, c.max)
+        }
+    }
+    return ""
+}
+
+// Check проверяет измерение и возвращает причины отклонения
+func Check(m *types.Measurement, rules []Rule, limits Limits) []string {
+    var violations []string
+    for _, rule := range rules {
+        if reason := rule.Check(m, limits); reason != "" {
+            violations = append(violations, reason)
+        }
+    }
+    return violations
+}
+
+// Validate проверяет измерение по настройкам станции и отмечает отклонение в самом измерении
+func Validate(state *types.AppState, m *types.Measurement) bool {
+    var limits Limits
+    if state.Arduino != nil {
+        limits = Limits{Length: state.Arduino.MaxLength, Width: state.Arduino.MaxWidth, Height: state.Arduino.MaxHeight}
+    }
+    m.Violations = Check(m, Rules(config.Get().Validation), limits)
+    if len(m.Violations) > 0 {
+        m.OutputStatus = types.OutputRejected
+        return false
+    }
+    return true
+}
\ No newline at end of file
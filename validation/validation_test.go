@@ -0,0 +1,128 @@
+package validation
+
+import (
+    "path/filepath"
+    "strings"
+    "testing"
+
+    "betelgeuze-measure-system-main/config"
+    "betelgeuze-measure-system-main/types"
+)
+
+// full — измерение с Arduino; габариты в см, вес в г, объем в см³
+func full(length, width, height int, weight float64) *types.Measurement {
+    return &types.Measurement{
+        ID:          "m-1",
+        ArduinoPort: "/dev/ttyUSB0",
+        Length:      length,
+        Width:       width,
+        Height:      height,
+        Volume:      length * width * height,
+        Weight:      weight,
+        Unit:        "g",
+    }
+}
+
+func TestRulesDisabled(t *testing.T) {
+    cfg := config.ValidationConfig{NonZeroDimensions: true, MaxWeight: 1}
+    if rules := Rules(cfg); rules != nil {
+        t.Fatalf("выключенная проверка вернула правила: %d", len(rules))
+    }
+    cfg.Enabled = true
+    if rules := Rules(cfg); len(rules) != 2 {
+        t.Fatalf("правил %d, ожидалось 2", len(rules))
+    }
+}
+
+func TestCheck(t *testing.T) {
+    cfg := config.ValidationConfig{
+        Enabled:           true,
+        NonZeroDimensions: true,
+        FirmwareLimits:    true,
+        MinLength:         5,
+        MaxLength:         100,
+        MaxHeight:         50,
+        MinWeight:         10,
+        MaxWeight:         30000,
+        MinDensity:        20,
+        MaxDensity:        2000,
+    }
+    limits := Limits{Length: 80, Width: 60, Height: 40}
+    weightOnly := &types.Measurement{ID: "m-1", Weight: 1200, Unit: "g"}
+
+    tests := []struct {
+        name string
+        m    *types.Measurement
+        want []string // начала причин отклонения по порядку
+    }{
+        {"в пределах", full(30, 20, 10, 1200), nil},
+        {"нулевой габарит", full(30, 0, 10, 1200), []string{"нулевые габариты 30×0×10"}},
+        {"больше максимума прошивки", full(90, 20, 10, 1200), []string{"длина 90 см больше максимума прошивки 80 см"}},
+        {"меньше минимума", full(3, 20, 10, 100), []string{"длина 3 см меньше минимума 5 см"}},
+        {"несколько правил", full(30, 20, 45, 5), []string{
+            "высота 45 см больше максимума прошивки 40 см",
+            "вес 5 г меньше минимума 10 г",
+            "плотность 0 кг/м³ меньше 20",
+        }},
+        {"тяжелый", full(30, 20, 10, 40000), []string{"вес 40000 г больше максимума 30000 г", "плотность 6667 кг/м³ больше 2000"}},
+        {"легкий для объема", full(60, 50, 40, 500), []string{"плотность 4 кг/м³ меньше 20"}},
+        // Без Arduino габариты и плотность не проверяются, вес — проверяется
+        {"только вес", weightOnly, nil},
+        {"только вес вне диапазона", &types.Measurement{ID: "m-2", Weight: 5, Unit: "g"}, []string{"вес 5 г меньше минимума 10 г"}},
+    }
+    rules := Rules(cfg)
+    for _, tt := range tests {
+        got := Check(tt.m, rules, limits)
+        if len(got) != len(tt.want) {
+            t.Errorf("%s: причины %q, ожидалось %q", tt.name, got, tt.want)
+            continue
+        }
+        for i := range got {
+            if !strings.HasPrefix(got[i], tt.want[i]) {
+                t.Errorf("%s: причина %q, ожидалось %q", tt.name, got[i], tt.want[i])
+            }
+        }
+    }
+}
+
+func TestFirmwareLimitsUnknown(t *testing.T) {
+    rules := Rules(config.ValidationConfig{Enabled: true, FirmwareLimits: true})
+    // Пока прошивка не ответила на 0x89, максимумы неизвестны и не проверяются
+    if got := Check(full(500, 500, 500, 1200), rules, Limits{}); len(got) != 0 {
+        t.Errorf("без максимумов прошивки: %q", got)
+    }
+    if got := Check(full(500, 20, 10, 1200), rules, Limits{Width: 60}); len(got) != 0 {
+        t.Errorf("максимум длины неизвестен: %q", got)
+    }
+}
+
+func TestValidate(t *testing.T) {
+    config.LoadSettings(filepath.Join(t.TempDir(), "missing.json"))
+    config.Get().Validation = config.ValidationConfig{Enabled: true, FirmwareLimits: true, MaxWeight: 30000}
+    state := &types.AppState{Arduino: &types.ArduinoPort{MaxLength: 80, MaxWidth: 60, MaxHeight: 40}}
+
+    m := full(30, 20, 10, 1200)
+    if !Validate(state, m) {
+        t.Fatalf("измерение отклонено: %q", m.Violations)
+    }
+    if m.OutputStatus == types.OutputRejected || len(m.Violations) != 0 {
+        t.Errorf("принятое измерение отмечено отклоненным: %q, %q", m.OutputStatus, m.Violations)
+    }
+
+    m = full(30, 70, 10, 1200)
+    if Validate(state, m) {
+        t.Fatal("ширина больше максимума прошивки принята")
+    }
+    if m.OutputStatus != types.OutputRejected {
+        t.Errorf("статус вывода %q, ожидался rejected", m.OutputStatus)
+    }
+    if len(m.Violations) != 1 || m.Violations[0] != "ширина 70 см больше максимума прошивки 60 см" {
+        t.Errorf("причины: %q", m.Violations)
+    }
+
+    // Без Arduino максимумы прошивки неизвестны
+    m = full(30, 70, 10, 1200)
+    if !Validate(&types.AppState{}, m) {
+        t.Errorf("без Arduino измерение отклонено: %q", m.Violations)
+    }
+}
\ No newline at end of file
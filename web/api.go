@@ -0,0 +1,124 @@
+package web
+
+import (
+    "encoding/json"
+    "net/http"
+    "slices"
+    "strings"
+
+    "betelgeuze-measure-system-main/types"
+)
+
+// API_PREFIX — версия API. Несовместимые изменения выходят под новым префиксом,
+// прежняя версия остается, пока ей пользуются интеграции.
+const API_PREFIX = "/api/v1"
+
+// Коды ошибок API. Код не меняется между версиями программы, текст сообщения может меняться.
+const (
+    ERR_INVALID_REQUEST      = "invalid_request"      // неверное тело или параметры запроса
+    ERR_METHOD_NOT_ALLOWED   = "method_not_allowed"
+    ERR_NOT_FOUND            = "not_found"
+    ERR_CONFLICT             = "conflict"             // состояние объекта не допускает операцию
+    ERR_DEVICE_UNAVAILABLE   = "device_unavailable"   // устройство не подключено
+    ERR_DEVICE_ERROR         = "device_error"         // устройство не ответило или ответило ошибкой
+    ERR_TRIGGER_NOT_ALLOWED  = "trigger_not_allowed"  // источник запуска не входит в политику станции
+    ERR_MEASUREMENT_REJECTED = "measurement_rejected" // измерение не прошло проверку, в details — измерение
+    ERR_OUTPUT_FAILED        = "output_failed"        // ни один выход не принял результат, в details — измерение
+    ERR_HISTORY_UNAVAILABLE  = "history_unavailable"
+    ERR_UNAUTHORIZED         = "unauthorized"         // нет входа или неверный токен
+    ERR_FORBIDDEN            = "forbidden"            // роли пользователя недостаточно
+    ERR_TOO_MANY_REQUESTS    = "too_many_requests"    // слишком много неудачных попыток входа, в Retry-After — секунды ожидания
+    ERR_INTERNAL             = "internal_error"
+)
+
+// apiError — тело ответа с ошибкой: {"error": {"code": "...", "message": "...", "details": ...}}
+type apiError struct {
+    Code    string      `json:"code"`
+    Message string      `json:"message"`
+    Details interface{} `json:"details,omitempty"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(status)
+    json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+    writeErrorDetails(w, status, code, message, nil)
+}
+
+func writeErrorDetails(w http.ResponseWriter, status int, code, message string, details interface{}) {
+    writeJSON(w, status, map[string]apiError{"error": {Code: code, Message: message, Details: details}})
+}
+
+// allowMethods отвечает 405, если метод запроса не из списка
+func allowMethods(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+    if slices.Contains(methods, r.Method) {
+        return true
+    }
+    for _, m := range methods {
+        w.Header().Add("Allow", m)
+    }
+    writeError(w, http.StatusMethodNotAllowed, ERR_METHOD_NOT_ALLOWED, "Метод "+r.Method+" не поддерживается")
+    return false
+}
+
+// decodeBody разбирает JSON-тело запроса; при ошибке отвечает 400
+func decodeBody(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+    if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+        writeError(w, http.StatusBadRequest, ERR_INVALID_REQUEST, "Неверный JSON: "+err.Error())
+        return false
+    }
+    return true
+}
+
+// apiRoute — маршрут API относительно API_PREFIX и допустимые методы через запятую
+type apiRoute struct {
+    Methods string
+    Path    string
+    Handler func(http.ResponseWriter, *http.Request, *types.AppState)
+}
+
+var apiRoutes = []apiRoute{
+    {"GET", "/status", apiStatusHandler},
+    {"POST", "/devices/reconnect", apiReconnectHandler},
+    {"GET,PUT", "/mode", apiModeHandler},
+    {"POST", "/arduino/commands", apiArduinoCommandHandler},
+    {"GET,PUT", "/calibration", apiCalibrationHandler},
+    {"GET", "/scale/weight", apiScaleWeightHandler},
+    {"POST", "/scale/tare", apiScaleTareHandler},
+    {"GET", "/scale/stream", apiScaleStreamHandler},
+    {"GET", "/events", apiEventsHandler},
+    {"POST", "/auth/login", apiLoginHandler},
+    {"POST", "/auth/logout", apiLogoutHandler},
+    {"GET", "/auth/me", apiMeHandler},
+    {"POST", "/barcode", apiBarcodeHandler},
+    {"GET,POST", "/measurements", apiMeasurementsHandler},
+    {"GET", "/measurements/export", apiExportHandler},
+    {"GET", "/measurements/{id}", apiMeasurementHandler},
+    {"POST", "/measurements/{id}/approve", apiApproveHandler},
+    {"POST", "/measurements/{id}/discard", apiDiscardHandler},
+    {"GET", "/config", apiConfigHandler},
+    {"GET", "/feedback", apiFeedbackHandler},
+    {"GET", "/outbox", apiOutboxHandler},
+    {"POST", "/outbox/retry", apiOutboxRetryHandler},
+    {"POST", "/outbox/purge", apiOutboxPurgeHandler},
+}
+
+// registerAPI регистрирует маршруты API; запрос с другим методом получает 405 в формате API.
+// Неизвестные пути под префиксом получают JSON-ошибку 404.
+func registerAPI(rt *router, state *types.AppState) {
+    for _, route := range apiRoutes {
+        route := route
+        methods := strings.Split(route.Methods, ",")
+        rt.handle(route.Methods, API_PREFIX+route.Path, func(w http.ResponseWriter, r *http.Request) {
+            if allowMethods(w, r, methods...) {
+                route.Handler(w, r, state)
+            }
+        })
+    }
+    rt.mux.HandleFunc(API_PREFIX+"/", func(w http.ResponseWriter, r *http.Request) {
+        writeError(w, http.StatusNotFound, ERR_NOT_FOUND, "Неизвестный путь API: "+r.URL.Path)
+    })
+}
\ No newline at end of file
@@ -0,0 +1,620 @@
+package web
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net/http"
+    "strconv"
+    "strings"
+    "time"
+
+    "betelgeuze-measure-system-main/config"
+    "betelgeuze-measure-system-main/devices"
+    "betelgeuze-measure-system-main/events"
+    "betelgeuze-measure-system-main/feedback"
+    "betelgeuze-measure-system-main/history"
+    "betelgeuze-measure-system-main/liveweight"
+    "betelgeuze-measure-system-main/measurement"
+    "betelgeuze-measure-system-main/mqtt"
+    "betelgeuze-measure-system-main/outbox"
+    "betelgeuze-measure-system-main/trigger"
+    "betelgeuze-measure-system-main/types"
+)
+
+// requireArduino отвечает 503, если Arduino не подключен
+func requireArduino(w http.ResponseWriter, state *types.AppState) bool {
+    if !state.Status.ArduinoConnected || state.Arduino == nil {
+        writeError(w, http.StatusServiceUnavailable, ERR_DEVICE_UNAVAILABLE, "Arduino не подключен")
+        return false
+    }
+    return true
+}
+
+// requireScale отвечает 503, если весы не подключены
+func requireScale(w http.ResponseWriter, state *types.AppState) bool {
+    if !state.Status.ScaleConnected || state.Scale == nil {
+        writeError(w, http.StatusServiceUnavailable, ERR_DEVICE_UNAVAILABLE, "Весы не подключены")
+        return false
+    }
+    return true
+}
+
+// requireHistory возвращает хранилище истории или отвечает 503
+func requireHistory(w http.ResponseWriter) *history.Store {
+    store := history.Default()
+    if store == nil {
+        writeError(w, http.StatusServiceUnavailable, ERR_HISTORY_UNAVAILABLE, "История измерений недоступна")
+    }
+    return store
+}
+
+func apiStatusHandler(w http.ResponseWriter, r *http.Request, state *types.AppState) {
+    writeJSON(w, http.StatusOK, state.Status)
+}
+
+func apiReconnectHandler(w http.ResponseWriter, r *http.Request, state *types.AppState) {
+    reconnectDevices(state)
+    writeJSON(w, http.StatusOK, state.Status)
+}
+
+// apiModeHandler: GET — режим автоматических измерений, PUT {"mode": "auto" | "paused" | "single"} — переключение
+func apiModeHandler(w http.ResponseWriter, r *http.Request, state *types.AppState) {
+    if r.Method == "PUT" {
+        var req struct {
+            Mode string `json:"mode"`
+        }
+        if !decodeBody(w, r, &req) {
+            return
+        }
+        if err := trigger.SetMode(req.Mode); err != nil {
+            writeError(w, http.StatusBadRequest, ERR_INVALID_REQUEST, err.Error())
+            return
+        }
+    }
+    writeJSON(w, http.StatusOK, map[string]string{
+        "mode":   state.Status.Mode,
+        "reason": state.Status.ModeReason,
+    })
+}
+
+// dimensions — габариты или максимумы габаритов, см
+type dimensions struct {
+    Length int `json:"length"`
+    Width  int `json:"width"`
+    Height int `json:"height"`
+}
+
+// commandResult — результат команды Arduino
+type commandResult struct {
+    Command    string      `json:"command"`
+    Reply      string      `json:"reply,omitempty"`      // ping: ответ прошивки
+    Dimensions *dimensions `json:"dimensions,omitempty"` // get_dimensions
+}
+
+// apiArduinoCommandHandler выполняет команду Arduino: POST {"command": "ping"}.
+// Команды: ping, get_dimensions и команды без ответа из devices.ARDUINO_COMMANDS.
+// Максимумы габаритов задаются через /calibration.
+func apiArduinoCommandHandler(w http.ResponseWriter, r *http.Request, state *types.AppState) {
+    var req struct {
+        Command string `json:"command"`
+    }
+    if !decodeBody(w, r, &req) || !requireArduino(w, state) {
+        return
+    }
+
+    result := commandResult{Command: req.Command}
+    switch req.Command {
+    case "ping":
+        reply, err := devices.Ping(state.Arduino)
+        if err != nil {
+            writeError(w, http.StatusBadGateway, ERR_DEVICE_ERROR, err.Error())
+            return
+        }
+        if !strings.Contains(reply, "OK") {
+            writeErrorDetails(w, http.StatusBadGateway, ERR_DEVICE_ERROR, "Arduino ответил нестандартно", result)
+            return
+        }
+        result.Reply = reply
+    case "get_dimensions":
+        trigger.Hold("ручная команда Arduino " + req.Command)
+        length, width, height := measurement.ReadDimensions(state)
+        result.Dimensions = &dimensions{Length: length, Width: width, Height: height}
+    default:
+        // Ручные команды сбивают датчики: автоматические измерения ждут
+        trigger.Hold("ручная команда Arduino " + req.Command)
+        if err := devices.SendNamedCommand(state.Arduino, req.Command); err != nil {
+            writeError(w, http.StatusBadRequest, ERR_INVALID_REQUEST, err.Error())
+            return
+        }
+    }
+    writeJSON(w, http.StatusOK, result)
+}
+
+// apiCalibrationHandler: GET — максимумы габаритов прошивки (?refresh=1 перечитывает их у Arduino),
+// PUT {"length": 120, "width": 80, "height": 100} — задает максимумы; отсутствующие оси не меняются.
+func apiCalibrationHandler(w http.ResponseWriter, r *http.Request, state *types.AppState) {
+    if !requireArduino(w, state) {
+        return
+    }
+
+    switch {
+    case r.Method == "PUT":
+        var req struct {
+            Length *int `json:"length"`
+            Width  *int `json:"width"`
+            Height *int `json:"height"`
+        }
+        if !decodeBody(w, r, &req) {
+            return
+        }
+        axes := map[string]*int{"length": req.Length, "width": req.Width, "height": req.Height}
+        // Проверяем все значения до отправки, чтобы не применить калибровку частично
+        for axis, value := range axes {
+            if value != nil && (*value < 1 || *value > 255) {
+                writeError(w, http.StatusBadRequest, ERR_INVALID_REQUEST, fmt.Sprintf("%s: %v", axis, devices.ErrInvalidValue))
+                return
+            }
+        }
+        trigger.Hold("калибровка")
+        for axis, value := range axes {
+            if value == nil {
+                continue
+            }
+            if err := devices.SetMaximum(state.Arduino, axis, *value); err != nil {
+                writeError(w, http.StatusBadGateway, ERR_DEVICE_ERROR, fmt.Sprintf("%s: %v", axis, err))
+                return
+            }
+        }
+    case r.URL.Query().Get("refresh") != "":
+        trigger.Hold("калибровка")
+        measurement.ReadDimensions(state)
+    }
+
+    a := state.Arduino
+    writeJSON(w, http.StatusOK, dimensions{Length: a.MaxLength, Width: a.MaxWidth, Height: a.MaxHeight})
+}
+
+// apiScaleWeightHandler возвращает текущее показание весов без записи в историю
+func apiScaleWeightHandler(w http.ResponseWriter, r *http.Request, state *types.AppState) {
+    if !requireScale(w, state) {
+        return
+    }
+    weight, err := devices.ReadWeight(state.Scale)
+    if err != nil {
+        writeError(w, http.StatusBadGateway, ERR_DEVICE_ERROR, fmt.Sprintf("Ошибка чтения веса: %v", err))
+        return
+    }
+    state.Status.CurrentWeight = weight
+    mqtt.PublishWeight(weight)
+    writeJSON(w, http.StatusOK, map[string]interface{}{
+        "weight": weight,
+        "unit":   "g",
+    })
+}
+
+func apiScaleTareHandler(w http.ResponseWriter, r *http.Request, state *types.AppState) {
+    if !requireScale(w, state) {
+        return
+    }
+    trigger.Hold("тарирование весов")
+    if err := liveweight.Tare(state); err != nil {
+        writeError(w, http.StatusBadGateway, ERR_DEVICE_ERROR, fmt.Sprintf("Ошибка тарирования: %v", err))
+        return
+    }
+    w.WriteHeader(http.StatusNoContent)
+}
+
+// apiBarcodeHandler принимает штрихкод: POST {"code": "..."}
+func apiBarcodeHandler(w http.ResponseWriter, r *http.Request, state *types.AppState) {
+    var req struct {
+        Code string `json:"code"`
+    }
+    if !decodeBody(w, r, &req) {
+        return
+    }
+    code, err := trigger.Scan(state, req.Code)
+    if err != nil {
+        writeError(w, http.StatusBadRequest, ERR_INVALID_REQUEST, err.Error())
+        return
+    }
+    if trigger.Uses(types.TriggerBarcode) {
+        go trigger.Fire(types.TriggerBarcode)
+    }
+    writeJSON(w, http.StatusOK, map[string]string{"code": code})
+}
+
+// apiMeasurementsHandler: GET — история с фильтрами parseHistoryQuery,
+// POST — новое измерение через политику запуска; {"weight_only": true} только считывает вес в историю.
+func apiMeasurementsHandler(w http.ResponseWriter, r *http.Request, state *types.AppState) {
+    if r.Method == "POST" {
+        apiMeasure(w, r, state)
+        return
+    }
+
+    store := requireHistory(w)
+    if store == nil {
+        return
+    }
+    q, err := parseHistoryQuery(r)
+    if err != nil {
+        writeError(w, http.StatusBadRequest, ERR_INVALID_REQUEST, err.Error())
+        return
+    }
+    page, err := store.Query(q)
+    if err != nil {
+        writeError(w, http.StatusInternalServerError, ERR_INTERNAL, fmt.Sprintf("Ошибка чтения истории: %v", err))
+        return
+    }
+    if page.Items == nil {
+        page.Items = []*types.Measurement{}
+    }
+    writeJSON(w, http.StatusOK, page)
+}
+
+func apiMeasure(w http.ResponseWriter, r *http.Request, state *types.AppState) {
+    var req struct {
+        WeightOnly bool `json:"weight_only"`
+    }
+    // Тело необязательно
+    if r.ContentLength != 0 && !decodeBody(w, r, &req) {
+        return
+    }
+    if !requireScale(w, state) {
+        return
+    }
+
+    if req.WeightOnly {
+        weight, err := devices.ReadWeight(state.Scale)
+        if err != nil {
+            writeError(w, http.StatusBadGateway, ERR_DEVICE_ERROR, fmt.Sprintf("Ошибка чтения веса: %v", err))
+            return
+        }
+        state.Status.CurrentWeight = weight
+        mqtt.PublishWeight(weight)
+        m := measurement.NewWeight(state, weight, types.TriggerWeb)
+        m.OutputStatus = types.OutputNone
+        trigger.Record(state, m)
+        writeJSON(w, http.StatusCreated, m)
+        return
+    }
+
+    m, err := trigger.Fire(types.TriggerWeb)
+    switch {
+    case errors.Is(err, trigger.ErrNotAllowed):
+        writeError(w, http.StatusForbidden, ERR_TRIGGER_NOT_ALLOWED, err.Error())
+    case errors.Is(err, trigger.ErrRejected):
+        writeErrorDetails(w, http.StatusUnprocessableEntity, ERR_MEASUREMENT_REJECTED, err.Error(), m)
+    case errors.Is(err, measurement.ErrNoBarcode):
+        writeError(w, http.StatusConflict, ERR_CONFLICT, err.Error())
+    case err != nil:
+        writeError(w, http.StatusBadGateway, ERR_DEVICE_ERROR, err.Error())
+    case m == nil:
+        // Правило политики ждет других событий, например скана штрихкода
+        writeJSON(w, http.StatusAccepted, map[string]string{"status": "waiting"})
+    case m.OutputStatus == types.OutputFailed:
+        writeErrorDetails(w, http.StatusBadGateway, ERR_OUTPUT_FAILED, "Ни один выход не принял результат", m)
+    default:
+        writeJSON(w, http.StatusCreated, m)
+    }
+}
+
+// apiMeasurementHandler возвращает измерение; ?carrier= пересчитывает оплачиваемый вес для перевозчика
+func apiMeasurementHandler(w http.ResponseWriter, r *http.Request, state *types.AppState) {
+    store := requireHistory(w)
+    if store == nil {
+        return
+    }
+    m, err := store.Get(r.PathValue("id"))
+    if err != nil {
+        writeError(w, http.StatusInternalServerError, ERR_INTERNAL, fmt.Sprintf("Ошибка чтения истории: %v", err))
+        return
+    }
+    if m == nil {
+        writeError(w, http.StatusNotFound, ERR_NOT_FOUND, "Измерение не найдено")
+        return
+    }
+    if carrier := r.URL.Query().Get("carrier"); carrier != "" {
+        profile, ok := config.Get().CarrierProfile(carrier)
+        if !ok {
+            writeError(w, http.StatusBadRequest, ERR_INVALID_REQUEST, "Неизвестный перевозчик: "+carrier)
+            return
+        }
+        measurement.ApplyCarrier(m, profile)
+    }
+    writeJSON(w, http.StatusOK, m)
+}
+
+// apiConfirmError переводит ошибку подтверждения измерения в ответ API
+func apiConfirmError(w http.ResponseWriter, err error) {
+    switch {
+    case errors.Is(err, trigger.ErrNotFound):
+        writeError(w, http.StatusNotFound, ERR_NOT_FOUND, err.Error())
+    case errors.Is(err, trigger.ErrNotAwaiting):
+        writeError(w, http.StatusConflict, ERR_CONFLICT, err.Error())
+    default:
+        writeError(w, http.StatusInternalServerError, ERR_INTERNAL, err.Error())
+    }
+}
+
+// apiApproveHandler подтверждает измерение с исправлениями: тело как у approveHandler
+func apiApproveHandler(w http.ResponseWriter, r *http.Request, state *types.AppState) {
+    var req struct {
+        Operator string `json:"operator"`
+        measurement.Changes
+    }
+    if !decodeBody(w, r, &req) {
+        return
+    }
+    m, err := trigger.Approve(r.PathValue("id"), req.Changes, operatorName(r, req.Operator))
+    switch {
+    case errors.Is(err, trigger.ErrRejected):
+        writeErrorDetails(w, http.StatusUnprocessableEntity, ERR_MEASUREMENT_REJECTED, err.Error(), m)
+    case err != nil:
+        apiConfirmError(w, err)
+    default:
+        writeJSON(w, http.StatusOK, m)
+    }
+}
+
+func apiDiscardHandler(w http.ResponseWriter, r *http.Request, state *types.AppState) {
+    var req struct {
+        Operator string `json:"operator"`
+    }
+    if r.ContentLength != 0 && !decodeBody(w, r, &req) {
+        return
+    }
+    m, err := trigger.Discard(r.PathValue("id"), operatorName(r, req.Operator))
+    if err != nil {
+        apiConfirmError(w, err)
+        return
+    }
+    writeJSON(w, http.StatusOK, m)
+}
+
+// apiExportHandler выгружает историю в CSV или XLSX; ошибки параметров — в формате API
+func apiExportHandler(w http.ResponseWriter, r *http.Request, state *types.AppState) {
+    store := requireHistory(w)
+    if store == nil {
+        return
+    }
+    job, err := parseExport(r)
+    if err != nil {
+        writeError(w, http.StatusBadRequest, ERR_INVALID_REQUEST, err.Error())
+        return
+    }
+    job.write(w, store)
+}
+
+// outputView — выход без адресов, заголовков и ключей
+type outputView struct {
+    Type     string   `json:"type"`
+    Name     string   `json:"name,omitempty"`
+    Triggers []string `json:"triggers,omitempty"`
+    Format   string   `json:"format,omitempty"`
+}
+
+// apiConfigHandler отдает настройки станции без паролей, ключей и адресов выходов
+func apiConfigHandler(w http.ResponseWriter, r *http.Request, state *types.AppState) {
+    settings := config.Get()
+    outputs := []outputView{}
+    for _, o := range settings.Outputs {
+        outputs = append(outputs, outputView{Type: o.Type, Name: o.Name, Triggers: o.Triggers, Format: o.Format})
+    }
+    writeJSON(w, http.StatusOK, map[string]interface{}{
+        "station_id":          settings.StationID,
+        "weight_unit":         settings.WeightUnit,
+        "carrier":             settings.Carrier,
+        "carriers":            settings.Carriers,
+        "output_template":     measurement.StationTemplate(),
+        "output_templates":    settings.OutputTemplates,
+        "trigger":             settings.Trigger,
+        "validation":          settings.Validation,
+        "confirm_before_send": settings.ConfirmBeforeSend,
+        "feedback":            settings.Feedback,
+        "live_weight":         settings.LiveWeight,
+        "outputs":             outputs,
+        "mqtt":                settings.MQTT != nil,
+        "modbus":              settings.Modbus != nil,
+        "scanner":             settings.Scanner != nil,
+        "headless":            settings.Headless,
+        "auth":                settings.Auth != nil && settings.Auth.Enabled,
+    })
+}
+
+func apiFeedbackHandler(w http.ResponseWriter, r *http.Request, state *types.AppState) {
+    writeJSON(w, http.StatusOK, feedback.Events())
+}
+
+func apiOutboxHandler(w http.ResponseWriter, r *http.Request, state *types.AppState) {
+    views, err := outboxViews()
+    if err != nil {
+        writeError(w, http.StatusInternalServerError, ERR_INTERNAL, err.Error())
+        return
+    }
+    writeJSON(w, http.StatusOK, views)
+}
+
+// apiOutboxQueue разбирает тело повтора или очистки и находит очередь
+func apiOutboxQueue(w http.ResponseWriter, r *http.Request) (*outbox.Queue, *outboxRequest, bool) {
+    var req outboxRequest
+    if !decodeBody(w, r, &req) {
+        return nil, nil, false
+    }
+    q := outbox.Get(req.Sink)
+    if q == nil {
+        writeError(w, http.StatusNotFound, ERR_NOT_FOUND, "Очередь не найдена: "+req.Sink)
+        return nil, nil, false
+    }
+    return q, &req, true
+}
+
+func apiOutboxRetryHandler(w http.ResponseWriter, r *http.Request, state *types.AppState) {
+    q, req, ok := apiOutboxQueue(w, r)
+    if !ok {
+        return
+    }
+    count, err := q.Retry(req.ID)
+    if errors.Is(err, outbox.ErrNotFound) {
+        writeError(w, http.StatusNotFound, ERR_NOT_FOUND, err.Error())
+        return
+    }
+    if err != nil {
+        writeError(w, http.StatusBadRequest, ERR_INVALID_REQUEST, err.Error())
+        return
+    }
+    writeJSON(w, http.StatusOK, map[string]int{"count": count})
+}
+
+func apiOutboxPurgeHandler(w http.ResponseWriter, r *http.Request, state *types.AppState) {
+    q, req, ok := apiOutboxQueue(w, r)
+    if !ok {
+        return
+    }
+    if req.Queue == "" {
+        req.Queue = outbox.QueueDead
+    }
+    count, err := q.Purge(req.Queue, req.ID)
+    if errors.Is(err, outbox.ErrNotFound) {
+        writeError(w, http.StatusNotFound, ERR_NOT_FOUND, err.Error())
+        return
+    }
+    if err != nil {
+        writeError(w, http.StatusBadRequest, ERR_INVALID_REQUEST, err.Error())
+        return
+    }
+    writeJSON(w, http.StatusOK, map[string]int{"count": count})
+}
+
+// WS_WRITE_TIMEOUT — сколько ждать клиента, который не принимает сообщения, перед отключением
+const WS_WRITE_TIMEOUT = 5 * time.Second
+
+// apiScaleStreamHandler отдает живой вес по WebSocket: сообщение на каждое показание весов,
+// но не чаще ?interval_ms= (не меньше client_interval_ms из настроек). Медленный клиент
+// получает только последнее показание, в поле dropped — сколько показаний он пропустил.
+func apiScaleStreamHandler(w http.ResponseWriter, r *http.Request, state *types.AppState) {
+    feed := liveweight.Default()
+    if feed == nil {
+        writeError(w, http.StatusServiceUnavailable, ERR_DEVICE_UNAVAILABLE, "Опрос весов не запущен")
+        return
+    }
+    interval := feed.ClientInterval()
+    if value := r.URL.Query().Get("interval_ms"); value != "" {
+        ms, err := strconv.Atoi(value)
+        if err != nil || ms < 0 {
+            writeError(w, http.StatusBadRequest, ERR_INVALID_REQUEST, "Неверное значение interval_ms: "+value)
+            return
+        }
+        interval = max(interval, time.Duration(ms)*time.Millisecond)
+    }
+
+    conn, err := upgradeWebSocket(w, r)
+    if err != nil {
+        writeError(w, http.StatusBadRequest, ERR_INVALID_REQUEST, err.Error())
+        return
+    }
+    defer conn.Close()
+
+    sub := feed.Subscribe()
+    defer feed.Unsubscribe(sub)
+    closed := make(chan struct{})
+    go func() {
+        conn.readLoop()
+        close(closed)
+    }()
+
+    for {
+        var reading liveweight.Reading
+        select {
+        case <-closed:
+            return
+        case reading = <-sub.C:
+        }
+        data, _ := json.Marshal(struct {
+            liveweight.Reading
+            Dropped uint64 `json:"dropped"`
+        }{reading, sub.Dropped()})
+        if err := conn.WriteText(data, time.Now().Add(WS_WRITE_TIMEOUT)); err != nil {
+            return
+        }
+        // Ограничение частоты: показания за это время заменяют друг друга в канале подписчика
+        select {
+        case <-closed:
+            return
+        case <-time.After(interval):
+        }
+    }
+}
+
+// EVENTS_KEEPALIVE — как часто отправлять комментарий в пустой поток событий, чтобы прокси не закрыли соединение
+const EVENTS_KEEPALIVE = 15 * time.Second
+
+// apiEventsHandler отдает события станции по Server-Sent Events. ?topics= — типы событий
+// через запятую (measurement — все события измерений). После обрыва клиент продолжает поток
+// с заголовком Last-Event-ID или ?last_event_id=: пропущенные события приходят из буфера.
+func apiEventsHandler(w http.ResponseWriter, r *http.Request, state *types.AppState) {
+    flusher, ok := w.(http.Flusher)
+    if !ok {
+        writeError(w, http.StatusInternalServerError, ERR_INTERNAL, "Сервер не поддерживает потоковые ответы")
+        return
+    }
+    var topics []string
+    for _, t := range strings.Split(r.URL.Query().Get("topics"), ",") {
+        if t = strings.TrimSpace(t); t != "" {
+            topics = append(topics, t)
+        }
+    }
+    last := r.Header.Get("Last-Event-ID")
+    if last == "" {
+        last = r.URL.Query().Get("last_event_id")
+    }
+    var lastID uint64
+    if last != "" {
+        id, err := strconv.ParseUint(last, 10, 64)
+        if err != nil {
+            writeError(w, http.StatusBadRequest, ERR_INVALID_REQUEST, "Неверный Last-Event-ID: "+last)
+            return
+        }
+        lastID = id
+    }
+
+    bus := events.Default()
+    sub, replay, gap := bus.Subscribe(topics, lastID)
+    defer bus.Unsubscribe(sub)
+
+    w.Header().Set("Content-Type", "text/event-stream")
+    w.Header().Set("Cache-Control", "no-cache")
+    w.WriteHeader(http.StatusOK)
+    fmt.Fprint(w, "retry: 3000\n\n")
+    if gap {
+        // Без id: Last-Event-ID клиента не меняется
+        fmt.Fprintf(w, "event: %s\ndata: {\"last_event_id\":%d}\n\n", events.StreamGap, lastID)
+    }
+    for _, e := range replay {
+        writeEvent(w, e)
+    }
+    flusher.Flush()
+
+    keepalive := time.NewTicker(EVENTS_KEEPALIVE)
+    defer keepalive.Stop()
+    for {
+        select {
+        case e, ok := <-sub.C:
+            if !ok {
+                // Клиент не успевал забирать события: он переподключится и получит их из буфера
+                return
+            }
+            writeEvent(w, e)
+        case <-keepalive.C:
+            fmt.Fprint(w, ": keepalive\n\n")
+        case <-r.Context().Done():
+            return
+        }
+        flusher.Flush()
+    }
+}
+
+func writeEvent(w http.ResponseWriter, e events.Event) {
+    data, _ := json.Marshal(e)
+    fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+}
\ No newline at end of file
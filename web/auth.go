@@ -0,0 +1,199 @@
+package web
+
+import (
+    "errors"
+    "fmt"
+    "math"
+    "net/http"
+    "strconv"
+    "strings"
+    "time"
+
+    "betelgeuze-measure-system-main/auth"
+    "betelgeuze-measure-system-main/logging"
+    "betelgeuze-measure-system-main/types"
+)
+
+// publicRoutes — маршруты, доступные без входа
+var publicRoutes = map[string]bool{
+    "/login":                   true,
+    API_PREFIX + "/auth/login": true,
+    "/openapi.json":            true,
+    "/docs":                    true,
+}
+
+// adminRoutes — "МЕТОД шаблон" маршрутов, которые меняют настройку и калибровку Arduino
+var adminRoutes = map[string]bool{
+    "POST /arduino/command":                    true,
+    "POST " + API_PREFIX + "/arduino/commands": true,
+    "PUT " + API_PREFIX + "/calibration":       true,
+}
+
+// requiredRole возвращает роль, нужную для запроса; пустая строка — маршрут открыт.
+// Чтение доступно наблюдателю, изменения — оператору, настройка Arduino — администратору.
+func requiredRole(method, pattern string) string {
+    switch {
+    case publicRoutes[pattern]:
+        return ""
+    case adminRoutes[method+" "+pattern]:
+        return auth.RoleAdmin
+    case method == "GET" || method == "HEAD" || strings.HasPrefix(pattern, API_PREFIX+"/auth/"):
+        return auth.RoleViewer
+    default:
+        return auth.RoleOperator
+    }
+}
+
+// authorize проверяет вход и роль перед обработчиком, если вход настроен
+func authorize(pattern string, handler http.HandlerFunc) http.HandlerFunc {
+    return func(w http.ResponseWriter, r *http.Request) {
+        service := auth.Default()
+        if service == nil {
+            handler(w, r)
+            return
+        }
+        need := requiredRole(r.Method, pattern)
+        if need == "" {
+            handler(w, r)
+            return
+        }
+        api := strings.HasPrefix(pattern, API_PREFIX+"/")
+
+        p := service.Authenticate(r)
+        if p == nil {
+            switch {
+            case api:
+                writeError(w, http.StatusUnauthorized, ERR_UNAUTHORIZED, "Требуется вход")
+            case pattern == "/" && r.Method == "GET":
+                http.Redirect(w, r, "/login", http.StatusSeeOther)
+            default:
+                http.Error(w, "Требуется вход", http.StatusUnauthorized)
+            }
+            return
+        }
+        if !auth.Allows(p.Role, need) {
+            logging.Warn(fmt.Sprintf("Доступ запрещен: %s (%s) — %s %s", p.Name, p.Role, r.Method, r.URL.Path), "system")
+            message := fmt.Sprintf("Недостаточно прав: нужна роль %s", need)
+            if api {
+                writeError(w, http.StatusForbidden, ERR_FORBIDDEN, message)
+            } else {
+                http.Error(w, message, http.StatusForbidden)
+            }
+            return
+        }
+        handler(w, r.WithContext(auth.WithPrincipal(r.Context(), p)))
+    }
+}
+
+// apiLoginHandler проверяет пароль и выдает cookie сессии
+func apiLoginHandler(w http.ResponseWriter, r *http.Request, state *types.AppState) {
+    service := auth.Default()
+    if service == nil {
+        writeError(w, http.StatusConflict, ERR_CONFLICT, "Вход не настроен: сервер открыт без пароля")
+        return
+    }
+    var req struct {
+        Name     string `json:"name"`
+        Password string `json:"password"`
+    }
+    if !decodeBody(w, r, &req) {
+        return
+    }
+    id, p, expires, err := service.Login(req.Name, req.Password, r.RemoteAddr)
+    if errors.Is(err, auth.ErrTooManyAttempts) {
+        wait := service.LoginDelay(req.Name, r.RemoteAddr)
+        w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+        writeError(w, http.StatusTooManyRequests, ERR_TOO_MANY_REQUESTS, err.Error())
+        return
+    }
+    if err != nil {
+        writeError(w, http.StatusUnauthorized, ERR_UNAUTHORIZED, err.Error())
+        return
+    }
+    http.SetCookie(w, &http.Cookie{
+        Name:     auth.SESSION_COOKIE,
+        Value:    id,
+        Path:     "/",
+        Expires:  expires,
+        HttpOnly: true,
+        Secure:   r.TLS != nil,
+        SameSite: http.SameSiteStrictMode,
+    })
+    writeJSON(w, http.StatusOK, p)
+}
+
+// apiLogoutHandler закрывает сессию
+func apiLogoutHandler(w http.ResponseWriter, r *http.Request, state *types.AppState) {
+    if service := auth.Default(); service != nil {
+        if cookie, err := r.Cookie(auth.SESSION_COOKIE); err == nil {
+            service.Logout(cookie.Value)
+        }
+    }
+    http.SetCookie(w, &http.Cookie{Name: auth.SESSION_COOKIE, Value: "", Path: "/", Expires: time.Unix(0, 0), HttpOnly: true})
+    w.WriteHeader(http.StatusNoContent)
+}
+
+// apiMeHandler сообщает, кто вошел. Без настройки входа у всех права администратора.
+func apiMeHandler(w http.ResponseWriter, r *http.Request, state *types.AppState) {
+    p := auth.FromRequest(r)
+    if p == nil {
+        writeJSON(w, http.StatusOK, map[string]interface{}{"auth_enabled": false, "role": auth.RoleAdmin})
+        return
+    }
+    writeJSON(w, http.StatusOK, map[string]interface{}{"auth_enabled": true, "name": p.Name, "role": p.Role})
+}
+
+func loginHandler(w http.ResponseWriter, r *http.Request) {
+    if !auth.Enabled() {
+        http.Redirect(w, r, "/", http.StatusSeeOther)
+        return
+    }
+    w.Header().Set("Content-Type", "text/html; charset=utf-8")
+    w.Write([]byte(loginPage))
+}
+
+const loginPage = `<!DOCTYPE html>
+<html lang="ru">
+<head>
+    <meta charset="UTF-8">
+    <title>Вход — Betelgeuze</title>
+    <style>
+        body { font-family: Arial, sans-serif; background-color: #f5f5f5; }
+        form { max-width: 320px; margin: 80px auto; background: white; padding: 20px; border-radius: 8px; box-shadow: 0 2px 4px rgba(0,0,0,0.1); }
+        input { display: block; width: 100%; box-sizing: border-box; padding: 8px; margin: 8px 0; border: 1px solid #ddd; border-radius: 4px; }
+        button { width: 100%; background-color: #2196F3; color: white; border: none; padding: 10px; border-radius: 4px; cursor: pointer; }
+        #error { color: #f44336; min-height: 20px; }
+    </style>
+</head>
+<body>
+    <form onsubmit="login(event)">
+        <h2>🔧 Вход</h2>
+        <input id="name" placeholder="Пользователь" autocomplete="username" autofocus>
+        <input id="password" type="password" placeholder="Пароль" autocomplete="current-password">
+        <div id="error"></div>
+        <button type="submit">Войти</button>
+    </form>
+    <script>
+        function login(event) {
+            event.preventDefault();
+            fetch('/api/v1/auth/login', {
+                method: 'POST',
+                headers: {'Content-Type': 'application/json'},
+                body: JSON.stringify({
+                    name: document.getElementById('name').value,
+                    password: document.getElementById('password').value
+                })
+            }).then(response => {
+                if (response.ok) {
+                    location.href = '/';
+                    return;
+                }
+                return response.json().then(data => {
+                    document.getElementById('error').textContent = data.error ? data.error.message : response.statusText;
+                });
+            });
+        }
+    </script>
+</body>
+</html>
+`
\ No newline at end of file
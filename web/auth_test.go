@@ -0,0 +1,220 @@
+package web
+
+import (
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+
+    "betelgeuze-measure-system-main/auth"
+    "betelgeuze-measure-system-main/config"
+    "betelgeuze-measure-system-main/types"
+)
+
+// routeRoles — роль для каждого маршрута и метода; "" — маршрут открыт без входа
+var routeRoles = map[string]string{
+    "GET /":                                  auth.RoleViewer,
+    "GET /status":                            auth.RoleViewer,
+    "POST /reconnect":                        auth.RoleOperator,
+    "POST /arduino/command":                  auth.RoleAdmin,
+    "POST /scale/read":                       auth.RoleOperator,
+    "POST /measure/combined":                 auth.RoleOperator,
+    "GET /measurements":                      auth.RoleViewer,
+    "GET /measurements/export":               auth.RoleViewer,
+    "GET /measurements/{id}":                 auth.RoleViewer,
+    "POST /measurements/{id}/approve":        auth.RoleOperator,
+    "POST /measurements/{id}/discard":        auth.RoleOperator,
+    "GET /carriers":                          auth.RoleViewer,
+    "GET /templates":                         auth.RoleViewer,
+    "GET /mode":                              auth.RoleViewer,
+    "POST /mode":                             auth.RoleOperator,
+    "GET /feedback":                          auth.RoleViewer,
+    "POST /barcode":                          auth.RoleOperator,
+    "GET /outbox":                            auth.RoleViewer,
+    "POST /outbox/retry":                     auth.RoleOperator,
+    "POST /outbox/purge":                     auth.RoleOperator,
+    "GET /api/v1/status":                     auth.RoleViewer,
+    "POST /api/v1/devices/reconnect":         auth.RoleOperator,
+    "GET /api/v1/mode":                       auth.RoleViewer,
+    "PUT /api/v1/mode":                       auth.RoleOperator,
+    "POST /api/v1/arduino/commands":          auth.RoleAdmin,
+    "GET /api/v1/calibration":                auth.RoleViewer,
+    "PUT /api/v1/calibration":                auth.RoleAdmin,
+    "GET /api/v1/scale/weight":               auth.RoleViewer,
+    "POST /api/v1/scale/tare":                auth.RoleOperator,
+    "GET /api/v1/scale/stream":               auth.RoleViewer,
+    "GET /api/v1/events":                     auth.RoleViewer,
+    "POST /api/v1/auth/login":                "",
+    "POST /api/v1/auth/logout":               auth.RoleViewer,
+    "GET /api/v1/auth/me":                    auth.RoleViewer,
+    "POST /api/v1/barcode":                   auth.RoleOperator,
+    "GET /api/v1/measurements":               auth.RoleViewer,
+    "POST /api/v1/measurements":              auth.RoleOperator,
+    "GET /api/v1/measurements/export":        auth.RoleViewer,
+    "GET /api/v1/measurements/{id}":          auth.RoleViewer,
+    "POST /api/v1/measurements/{id}/approve": auth.RoleOperator,
+    "POST /api/v1/measurements/{id}/discard": auth.RoleOperator,
+    "GET /api/v1/config":                     auth.RoleViewer,
+    "GET /api/v1/feedback":                   auth.RoleViewer,
+    "GET /api/v1/outbox":                     auth.RoleViewer,
+    "POST /api/v1/outbox/retry":              auth.RoleOperator,
+    "POST /api/v1/outbox/purge":              auth.RoleOperator,
+    "GET /openapi.json":                      "",
+    "GET /docs":                              "",
+    "GET /login":                             "",
+    "GET /logs/stream":                       auth.RoleViewer,
+}
+
+func TestRequiredRoleForEveryRoute(t *testing.T) {
+    seen := make(map[string]bool)
+    for _, route := range RegisterRoutes(http.NewServeMux(), &types.AppState{}) {
+        for _, method := range route.Methods {
+            key := method + " " + route.Pattern
+            seen[key] = true
+            want, ok := routeRoles[key]
+            if !ok {
+                t.Errorf("маршрут %s не описан в таблице ролей теста", key)
+                continue
+            }
+            if got := requiredRole(method, route.Pattern); got != want {
+                t.Errorf("%s: нужна роль %q, ожидалось %q", key, got, want)
+            }
+        }
+    }
+    for key := range routeRoles {
+        if !seen[key] {
+            t.Errorf("маршрут %s из таблицы ролей не зарегистрирован", key)
+        }
+    }
+    // HEAD читает так же, как GET
+    if got := requiredRole("HEAD", API_PREFIX+"/status"); got != auth.RoleViewer {
+        t.Errorf("HEAD %s/status: нужна роль %q", API_PREFIX, got)
+    }
+}
+
+// enableTokens включает проверку доступа с токенами viewer и admin и возвращает их
+func enableTokens(t *testing.T) (viewer, admin string) {
+    t.Helper()
+    viewer, viewerHash, _ := auth.NewToken()
+    admin, adminHash, _ := auth.NewToken()
+    err := auth.Init(&config.AuthConfig{
+        Enabled: true,
+        Tokens: []config.TokenConfig{
+            {Name: "panel", TokenHash: viewerHash, Role: auth.RoleViewer},
+            {Name: "service", TokenHash: adminHash, Role: auth.RoleAdmin},
+        },
+    })
+    if err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(func() { auth.Init(nil) })
+    return viewer, admin
+}
+
+func TestAuthorizeStatusCodes(t *testing.T) {
+    viewer, admin := enableTokens(t)
+    ok := func(w http.ResponseWriter, r *http.Request) {
+        if auth.FromRequest(r) == nil && requiredRole(r.Method, r.URL.Path) != "" {
+            t.Errorf("%s %s: пользователь не передан обработчику", r.Method, r.URL.Path)
+        }
+        w.WriteHeader(http.StatusOK)
+    }
+
+    tests := []struct {
+        name     string
+        method   string
+        pattern  string
+        token    string
+        status   int
+        code     string // код ошибки API; "" — ответ не JSON
+        location string
+    }{
+        {"API без входа", "GET", API_PREFIX + "/status", "", http.StatusUnauthorized, ERR_UNAUTHORIZED, ""},
+        {"API с неверным токеном", "GET", API_PREFIX + "/status", "not-a-token", http.StatusUnauthorized, ERR_UNAUTHORIZED, ""},
+        {"API с ролью ниже нужной", "POST", API_PREFIX + "/arduino/commands", viewer, http.StatusForbidden, ERR_FORBIDDEN, ""},
+        {"калибровка наблюдателем", "PUT", API_PREFIX + "/calibration", viewer, http.StatusForbidden, ERR_FORBIDDEN, ""},
+        {"измерение наблюдателем", "POST", API_PREFIX + "/measurements", viewer, http.StatusForbidden, ERR_FORBIDDEN, ""},
+        {"чтение наблюдателем", "GET", API_PREFIX + "/measurements", viewer, http.StatusOK, "", ""},
+        {"калибровка администратором", "PUT", API_PREFIX + "/calibration", admin, http.StatusOK, "", ""},
+        {"вход без учетных данных", "POST", API_PREFIX + "/auth/login", "", http.StatusOK, "", ""},
+        {"страница без входа", "GET", "/", "", http.StatusSeeOther, "", "/login"},
+        {"старый маршрут без входа", "GET", "/status", "", http.StatusUnauthorized, "", ""},
+        {"старый маршрут с ролью ниже нужной", "POST", "/arduino/command", viewer, http.StatusForbidden, "", ""},
+        {"старый маршрут администратором", "POST", "/arduino/command", admin, http.StatusOK, "", ""},
+    }
+    for _, tt := range tests {
+        r := httptest.NewRequest(tt.method, tt.pattern, nil)
+        if tt.token != "" {
+            r.Header.Set("Authorization", "Bearer "+tt.token)
+        }
+        w := httptest.NewRecorder()
+        authorize(tt.pattern, ok)(w, r)
+        if w.Code != tt.status {
+            t.Errorf("%s: статус %d, ожидался %d", tt.name, w.Code, tt.status)
+            continue
+        }
+        if tt.location != "" && w.Header().Get("Location") != tt.location {
+            t.Errorf("%s: переадресация на %q", tt.name, w.Header().Get("Location"))
+        }
+        isJSON := strings.HasPrefix(w.Header().Get("Content-Type"), "application/json")
+        if tt.code == "" {
+            if isJSON {
+                t.Errorf("%s: ответ в формате API: %s", tt.name, w.Body)
+            }
+            continue
+        }
+        var body struct {
+            Error apiError `json:"error"`
+        }
+        if err := json.NewDecoder(w.Body).Decode(&body); err != nil || body.Error.Code != tt.code {
+            t.Errorf("%s: код ошибки %q, ожидался %q (%v)", tt.name, body.Error.Code, tt.code, err)
+        }
+    }
+}
+
+func TestAuthorizeWithoutAuth(t *testing.T) {
+    auth.Init(nil)
+    called := false
+    w := httptest.NewRecorder()
+    authorize(API_PREFIX+"/arduino/commands", func(w http.ResponseWriter, r *http.Request) { called = true })(w,
+        httptest.NewRequest("POST", API_PREFIX+"/arduino/commands", nil))
+    if !called {
+        t.Errorf("без настройки входа запрос не дошел до обработчика: %d", w.Code)
+    }
+}
+
+func TestLoginTooManyAttempts(t *testing.T) {
+    hash, err := auth.HashPassword("secret")
+    if err != nil {
+        t.Fatal(err)
+    }
+    err = auth.Init(&config.AuthConfig{Enabled: true, Users: []config.UserConfig{{Name: "admin", PasswordHash: hash, Role: auth.RoleAdmin}}})
+    if err != nil {
+        t.Fatal(err)
+    }
+    t.Cleanup(func() { auth.Init(nil) })
+
+    login := func(password string) *httptest.ResponseRecorder {
+        r := httptest.NewRequest("POST", API_PREFIX+"/auth/login", strings.NewReader(`{"name": "admin", "password": "`+password+`"}`))
+        r.RemoteAddr = "10.0.0.5:40000"
+        w := httptest.NewRecorder()
+        apiLoginHandler(w, r, &types.AppState{})
+        return w
+    }
+    for i := 0; i <= auth.LOGIN_FREE_ATTEMPTS; i++ {
+        if w := login("wrong"); w.Code != http.StatusUnauthorized {
+            t.Fatalf("попытка %d: статус %d", i+1, w.Code)
+        }
+    }
+    w := login("secret")
+    if w.Code != http.StatusTooManyRequests {
+        t.Fatalf("статус %d, ожидался 429: %s", w.Code, w.Body)
+    }
+    if w.Header().Get("Retry-After") != "1" {
+        t.Errorf("Retry-After: %q", w.Header().Get("Retry-After"))
+    }
+    if cookies := w.Result().Cookies(); len(cookies) != 0 {
+        t.Errorf("во время задержки выдана сессия: %v", cookies)
+    }
+}
\ No newline at end of file
@@ -0,0 +1,91 @@
+package web
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net/http"
+
+    "betelgeuze-measure-system-main/auth"
+    "betelgeuze-measure-system-main/measurement"
+    "betelgeuze-measure-system-main/trigger"
+    "betelgeuze-measure-system-main/types"
+)
+
+// operatorName — имя оператора для журнала исправлений. При настроенном входе это пользователь
+// запроса, иначе имя из тела запроса, а без него — адрес клиента.
+func operatorName(r *http.Request, name string) string {
+    if p := auth.FromRequest(r); p != nil {
+        return p.Name
+    }
+    if name != "" {
+        return name
+    }
+    return r.RemoteAddr
+}
+
+// confirmError переводит ошибку подтверждения в HTTP-статус
+func confirmError(w http.ResponseWriter, err error) {
+    switch {
+    case errors.Is(err, trigger.ErrNotFound):
+        http.Error(w, err.Error(), http.StatusNotFound)
+    case errors.Is(err, trigger.ErrNotAwaiting):
+        http.Error(w, err.Error(), http.StatusConflict)
+    default:
+        http.Error(w, fmt.Sprintf("Ошибка: %v", err), http.StatusInternalServerError)
+    }
+}
+
+// approveHandler подтверждает измерение с исправлениями оператора и отправляет его в выходы.
+// Тело: {"operator": "...", "weight": 1250, "length": 300, "width": 200, "height": 100, "barcode": "..."};
+// поля, которых нет в теле, не меняются.
+func approveHandler(w http.ResponseWriter, r *http.Request, state *types.AppState) {
+    if r.Method != "POST" {
+        http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+        return
+    }
+
+    var req struct {
+        Operator string `json:"operator"`
+        measurement.Changes
+    }
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        http.Error(w, "Invalid JSON", http.StatusBadRequest)
+        return
+    }
+
+    m, err := trigger.Approve(r.PathValue("id"), req.Changes, operatorName(r, req.Operator))
+    if err != nil && !errors.Is(err, trigger.ErrRejected) {
+        confirmError(w, err)
+        return
+    }
+
+    // Исправленное измерение снова не прошло проверку: возвращаем его с нарушениями
+    w.Header().Set("Content-Type", "application/json")
+    if err != nil {
+        w.WriteHeader(http.StatusUnprocessableEntity)
+    }
+    json.NewEncoder(w).Encode(m)
+}
+
+// discardHandler отменяет отправку измерения в выходы
+func discardHandler(w http.ResponseWriter, r *http.Request, state *types.AppState) {
+    if r.Method != "POST" {
+        http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+        return
+    }
+
+    var req struct {
+        Operator string `json:"operator"`
+    }
+    // Тело необязательно
+    json.NewDecoder(r.Body).Decode(&req)
+
+    m, err := trigger.Discard(r.PathValue("id"), operatorName(r, req.Operator))
+    if err != nil {
+        confirmError(w, err)
+        return
+    }
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(m)
+}
\ No newline at end of file
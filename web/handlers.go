@@ -2,15 +2,19 @@ package web
 
 import (
     "encoding/json"
+    "errors"
     "fmt"
     "net/http"
+    "strings"
     "time"
     
     "betelgeuze-measure-system-main/devices"
+    "betelgeuze-measure-system-main/feedback"
+    "betelgeuze-measure-system-main/measurement"
+    "betelgeuze-measure-system-main/mqtt"
+    "betelgeuze-measure-system-main/trigger"
     "betelgeuze-measure-system-main/types"
     "betelgeuze-measure-system-main/logging"
-    
-    "github.com/atotto/clipboard"
 )
 
 func statusHandler(w http.ResponseWriter, r *http.Request, state *types.AppState) {
@@ -24,6 +28,17 @@ func reconnectHandler(w http.ResponseWriter, r *http.Request, state *types.AppSt
         return
     }
 
+    reconnectDevices(state)
+
+    response := fmt.Sprintf("Arduino: %s (%s), Весы: %s (%s)", 
+        boolToString(state.Status.ArduinoConnected), state.Status.ArduinoPort,
+        boolToString(state.Status.ScaleConnected), state.Status.ScalePort)
+    
+    w.Write([]byte(response))
+}
+
+// reconnectDevices закрывает порты Arduino и весов и ищет устройства заново
+func reconnectDevices(state *types.AppState) {
     // Закрываем существующие соединения
     if state.Arduino != nil && state.Arduino.Port != nil {
         state.Arduino.Port.Close()
@@ -51,12 +66,6 @@ func reconnectHandler(w http.ResponseWriter, r *http.Request, state *types.AppSt
         state.Status.ScaleConnected = true
         state.Status.ScalePort = state.Scale.PortName
     }
-
-    response := fmt.Sprintf("Arduino: %s (%s), Весы: %s (%s)", 
-        boolToString(state.Status.ArduinoConnected), state.Status.ArduinoPort,
-        boolToString(state.Status.ScaleConnected), state.Status.ScalePort)
-    
-    w.Write([]byte(response))
 }
 
 func arduinoCommandHandler(w http.ResponseWriter, r *http.Request, state *types.AppState) {
@@ -79,6 +88,8 @@ func arduinoCommandHandler(w http.ResponseWriter, r *http.Request, state *types.
         return
     }
 
+    // Калибровка и ручные команды сбивают датчики: автоматические измерения ждут
+    trigger.Hold("ручная команда Arduino " + req.Command)
     response := devices.ExecuteArduinoCommand(state.Arduino, req.Command)
     w.Write([]byte(response))
 }
@@ -99,9 +110,13 @@ func scaleReadHandler(w http.ResponseWriter, r *http.Request, state *types.AppSt
         http.Error(w, fmt.Sprintf("Ошибка чтения веса: %v", err), http.StatusInternalServerError)
         return
     }
+    state.Status.CurrentWeight = weight
+    mqtt.PublishWeight(weight)
 
-    state.Status.LastWeight = weight
-    response := fmt.Sprintf("%.1f г", weight)
+    m := measurement.NewWeight(state, weight, types.TriggerWeb)
+    m.OutputStatus = types.OutputNone
+    trigger.Record(state, m)
+    response := fmt.Sprintf("%.1f г", m.Weight)
     w.Write([]byte(response))
 }
 
@@ -121,55 +136,169 @@ func combinedMeasureHandler(w http.ResponseWriter, r *http.Request, state *types
         return
     }
 
-    // Читаем вес
-    weight, err := devices.ReadWeight(state.Scale)
+    // Измерение запускается через политику станции: результат сохраняется в историю
+    // и отправляется в выходы (по умолчанию — буфер обмена)
+    m, err := trigger.Fire(types.TriggerWeb)
+    if errors.Is(err, trigger.ErrNotAllowed) {
+        http.Error(w, err.Error(), http.StatusForbidden)
+        return
+    }
+    if errors.Is(err, trigger.ErrRejected) {
+        http.Error(w, fmt.Sprintf("%s — %v", measurement.Format(m), err), http.StatusUnprocessableEntity)
+        return
+    }
     if err != nil {
-        http.Error(w, fmt.Sprintf("Ошибка чтения веса: %v", err), http.StatusInternalServerError)
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+    if m == nil {
+        w.Write([]byte("Запуск принят, ожидание остальных условий политики запуска"))
         return
     }
 
-    // Получаем размеры
-    devices.SendCommandToArduino(state.Arduino, 0x89) // CMD_GET_DIMENSIONS
-    time.Sleep(100 * time.Millisecond) // Небольшая задержка для обработки
-    length, width, height := devices.GetDimensionsFromArduino(state.Arduino)
-
-    // Формируем результат в формате "вес:высота:ширина:длина"
-    result := fmt.Sprintf("%.0f:%d:%d:%d", weight, height, width, length)
-    
-    // Обновляем статус
-    state.Status.LastWeight = weight
-    state.Status.LastDimensions = result
+    if m.OutputStatus == types.OutputAwaiting {
+        w.Write([]byte(fmt.Sprintf("Измерение: %s — ждет подтверждения оператора", measurement.Format(m))))
+        return
+    }
 
-    // Копируем в буфер обмена
-    err = clipboard.WriteAll(result)
-    if err != nil {
-        http.Error(w, fmt.Sprintf("Ошибка копирования в буфер: %v", err), http.StatusInternalServerError)
+    status := m.OutputStatus
+    var details []string
+    for _, res := range m.Outputs {
+        if res.Error != "" {
+            details = append(details, fmt.Sprintf("%s: %s", res.Sink, res.Error))
+        } else {
+            details = append(details, fmt.Sprintf("%s: ok", res.Sink))
+        }
+    }
+    if len(details) == 0 {
+        details = append(details, "выходы не настроены, измерение сохранено в историю")
+    }
+    if status == types.OutputFailed {
+        http.Error(w, fmt.Sprintf("Ошибка вывода результата %s (%s)", measurement.Format(m), strings.Join(details, "; ")), http.StatusInternalServerError)
         return
     }
 
     // Возвращаем результат
-    response := fmt.Sprintf("Измерение завершено: %s (скопировано в буфер)", result)
+    response := fmt.Sprintf("Измерение завершено: %s (%s)", measurement.Format(m), strings.Join(details, "; "))
     w.Write([]byte(response))
 }
 
+// modeHandler показывает и переключает режим автоматических измерений.
+// POST {"mode": "paused"} — пауза, "auto" — возобновить, "single" — измерить только следующий объект.
+func modeHandler(w http.ResponseWriter, r *http.Request, state *types.AppState) {
+    if r.Method == "POST" {
+        var req struct {
+            Mode string `json:"mode"`
+        }
+        if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+            http.Error(w, "Invalid JSON", http.StatusBadRequest)
+            return
+        }
+        if err := trigger.SetMode(req.Mode); err != nil {
+            http.Error(w, err.Error(), http.StatusBadRequest)
+            return
+        }
+    } else if r.Method != "GET" {
+        http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+        return
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(map[string]string{
+        "mode":   state.Status.Mode,
+        "reason": state.Status.ModeReason,
+    })
+}
+
+// feedbackHandler отдает сигналы событий, чтобы веб-интерфейс проигрывал те же звуки
+func feedbackHandler(w http.ResponseWriter, r *http.Request, state *types.AppState) {
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(feedback.Events())
+}
+
+// barcodeHandler принимает штрихкод, введенный вручную или ручным сканером-клавиатурой
+func barcodeHandler(w http.ResponseWriter, r *http.Request, state *types.AppState) {
+    if r.Method != "POST" {
+        http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+        return
+    }
+
+    var req struct {
+        Code string `json:"code"`
+    }
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        http.Error(w, "Invalid JSON", http.StatusBadRequest)
+        return
+    }
+
+    code, err := trigger.Scan(state, req.Code)
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
+    }
+    if trigger.Uses(types.TriggerBarcode) {
+        go trigger.Fire(types.TriggerBarcode)
+    }
+    w.Write([]byte(fmt.Sprintf("Штрихкод принят: %s", code)))
+}
+
+// DROPPED_CHECK_INTERVAL — как часто поток логов сообщает о пропущенных сообщениях
+const DROPPED_CHECK_INTERVAL = time.Second
+
+// logsStreamHandler отдает лог станции по Server-Sent Events. При подключении приходят последние
+// сообщения из буфера. ?type=arduino,scale выбирает типы, ?level=warn — минимальный уровень.
+// Если клиент не успевает читать поток, он получает событие dropped с числом пропущенных сообщений.
 func logsStreamHandler(w http.ResponseWriter, r *http.Request, state *types.AppState) {
+    filter := logging.Filter{Level: r.URL.Query().Get("level")}
+    if filter.Level != "" && !logging.ValidLevel(filter.Level) {
+        http.Error(w, "Неизвестный уровень: "+filter.Level, http.StatusBadRequest)
+        return
+    }
+    for _, t := range strings.Split(r.URL.Query().Get("type"), ",") {
+        if t = strings.TrimSpace(t); t != "" {
+            filter.Types = append(filter.Types, t)
+        }
+    }
+
     w.Header().Set("Content-Type", "text/event-stream")
     w.Header().Set("Cache-Control", "no-cache")
     w.Header().Set("Connection", "keep-alive")
     w.Header().Set("Access-Control-Allow-Origin", "*")
 
-    client := make(chan types.LogMessage, 100)
-    logging.AddLogClient(client)
-    defer logging.RemoveLogClient(client)
+    client, backlog := logging.Subscribe(filter)
+    defer logging.Unsubscribe(client)
 
+    flush := func() {
+        if f, ok := w.(http.Flusher); ok {
+            f.Flush()
+        }
+    }
+    for _, msg := range backlog {
+        data, _ := json.Marshal(msg)
+        fmt.Fprintf(w, "data: %s\n\n", data)
+    }
+    flush()
+
+    // Пропуски проверяются и по таймеру: после них новых сообщений может не быть
+    ticker := time.NewTicker(DROPPED_CHECK_INTERVAL)
+    defer ticker.Stop()
+    var reported uint64
+    reportDropped := func() {
+        if dropped := client.Dropped(); dropped != reported {
+            reported = dropped
+            fmt.Fprintf(w, "event: dropped\ndata: {\"dropped\":%d}\n\n", dropped)
+        }
+    }
     for {
         select {
-        case msg := <-client:
+        case msg := <-client.C:
             data, _ := json.Marshal(msg)
             fmt.Fprintf(w, "data: %s\n\n", data)
-            if f, ok := w.(http.Flusher); ok {
-                f.Flush()
-            }
+            reportDropped()
+            flush()
+        case <-ticker.C:
+            reportDropped()
+            flush()
         case <-r.Context().Done():
             return
         }
@@ -0,0 +1,81 @@
+package web
+
+import (
+    "bytes"
+    "context"
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "sync"
+    "testing"
+    "time"
+
+    "betelgeuze-measure-system-main/logging"
+    "betelgeuze-measure-system-main/types"
+)
+
+// slowWriter — медленный клиент: запись ждет, пока тест не откроет gate
+type slowWriter struct {
+    header  http.Header
+    gate    chan struct{}
+    entered chan struct{}
+
+    mu  sync.Mutex
+    buf bytes.Buffer
+}
+
+func (w *slowWriter) Header() http.Header { return w.header }
+func (w *slowWriter) WriteHeader(int)     {}
+func (w *slowWriter) Flush()              {}
+
+func (w *slowWriter) Write(p []byte) (int, error) {
+    select {
+    case w.entered <- struct{}{}:
+    default:
+    }
+    <-w.gate
+    w.mu.Lock()
+    defer w.mu.Unlock()
+    return w.buf.Write(p)
+}
+
+func (w *slowWriter) String() string {
+    w.mu.Lock()
+    defer w.mu.Unlock()
+    return w.buf.String()
+}
+
+func TestLogsStreamReportsDropped(t *testing.T) {
+    const logType = "slow-reader-test"
+    logging.BroadcastLog("первое сообщение", logType)
+
+    w := &slowWriter{header: http.Header{}, gate: make(chan struct{}), entered: make(chan struct{}, 1)}
+    ctx, cancel := context.WithCancel(context.Background())
+    defer cancel()
+    r := httptest.NewRequest("GET", "/logs/stream?type="+logType, nil).WithContext(ctx)
+    done := make(chan struct{})
+    go func() {
+        logsStreamHandler(w, r, &types.AppState{})
+        close(done)
+    }()
+
+    // Обработчик подписался и застрял на отправке буфера последних сообщений
+    <-w.entered
+    const extra = 5
+    for i := 0; i < logging.CLIENT_BUFFER+extra; i++ {
+        logging.BroadcastLog(fmt.Sprintf("сообщение %d", i), logType)
+    }
+    close(w.gate)
+
+    want := fmt.Sprintf("event: dropped\ndata: {\"dropped\":%d}\n\n", extra)
+    deadline := time.Now().Add(DROPPED_CHECK_INTERVAL + 2*time.Second)
+    for !strings.Contains(w.String(), want) {
+        if time.Now().After(deadline) {
+            t.Fatalf("нет события о %d пропущенных сообщениях", extra)
+        }
+        time.Sleep(10 * time.Millisecond)
+    }
+    cancel()
+    <-done
+}
\ No newline at end of file
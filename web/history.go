@@ -0,0 +1,234 @@
+package web
+
+import (
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "strconv"
+    "strings"
+
+    "betelgeuze-measure-system-main/config"
+    "betelgeuze-measure-system-main/export"
+    "betelgeuze-measure-system-main/history"
+    "betelgeuze-measure-system-main/logging"
+    "betelgeuze-measure-system-main/measurement"
+    "betelgeuze-measure-system-main/types"
+    "betelgeuze-measure-system-main/utils"
+)
+
+// parseHistoryQuery собирает фильтр истории из параметров запроса:
+// from, to, station, barcode, min_weight, max_weight, status (через запятую), offset, limit, order=asc
+func parseHistoryQuery(r *http.Request) (history.Query, error) {
+    params := r.URL.Query()
+    var q history.Query
+    var err error
+
+    if q.From, err = utils.ParseTimeParam(params.Get("from"), false); err != nil {
+        return q, err
+    }
+    if q.To, err = utils.ParseTimeParam(params.Get("to"), true); err != nil {
+        return q, err
+    }
+    q.StationID = params.Get("station")
+    q.Barcode = params.Get("barcode")
+    if status := params.Get("status"); status != "" {
+        q.Statuses = strings.Split(status, ",")
+    }
+
+    floats := map[string]*float64{"min_weight": &q.MinWeight, "max_weight": &q.MaxWeight}
+    for name, target := range floats {
+        if value := params.Get(name); value != "" {
+            if *target, err = strconv.ParseFloat(value, 64); err != nil {
+                return q, fmt.Errorf("неверное значение %s: %s", name, value)
+            }
+        }
+    }
+    ints := map[string]*int{"offset": &q.Offset, "limit": &q.Limit}
+    for name, target := range ints {
+        if value := params.Get(name); value != "" {
+            if *target, err = strconv.Atoi(value); err != nil {
+                return q, fmt.Errorf("неверное значение %s: %s", name, value)
+            }
+        }
+    }
+    q.Ascending = params.Get("order") == "asc"
+    return q, nil
+}
+
+func measurementsHandler(w http.ResponseWriter, r *http.Request, state *types.AppState) {
+    if r.Method != "GET" {
+        http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+        return
+    }
+
+    store := history.Default()
+    if store == nil {
+        http.Error(w, "История измерений недоступна", http.StatusServiceUnavailable)
+        return
+    }
+
+    q, err := parseHistoryQuery(r)
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
+    }
+
+    page, err := store.Query(q)
+    if err != nil {
+        http.Error(w, fmt.Sprintf("Ошибка чтения истории: %v", err), http.StatusInternalServerError)
+        return
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(page)
+}
+
+func measurementHandler(w http.ResponseWriter, r *http.Request, state *types.AppState) {
+    if r.Method != "GET" {
+        http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+        return
+    }
+
+    store := history.Default()
+    if store == nil {
+        http.Error(w, "История измерений недоступна", http.StatusServiceUnavailable)
+        return
+    }
+
+    m, err := store.Get(r.PathValue("id"))
+    if err != nil {
+        http.Error(w, fmt.Sprintf("Ошибка чтения истории: %v", err), http.StatusInternalServerError)
+        return
+    }
+    if m == nil {
+        http.Error(w, "Измерение не найдено", http.StatusNotFound)
+        return
+    }
+
+    // Пересчет объемного и оплачиваемого веса для другого перевозчика
+    if carrier := r.URL.Query().Get("carrier"); carrier != "" {
+        profile, ok := config.Get().CarrierProfile(carrier)
+        if !ok {
+            http.Error(w, "Неизвестный перевозчик: "+carrier, http.StatusBadRequest)
+            return
+        }
+        measurement.ApplyCarrier(m, profile)
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(m)
+}
+
+func exportHandler(w http.ResponseWriter, r *http.Request, state *types.AppState) {
+    if r.Method != "GET" {
+        http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+        return
+    }
+
+    store := history.Default()
+    if store == nil {
+        http.Error(w, "История измерений недоступна", http.StatusServiceUnavailable)
+        return
+    }
+
+    job, err := parseExport(r)
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
+    }
+    job.write(w, store)
+}
+
+// exportJob — разобранный запрос выгрузки истории
+type exportJob struct {
+    query    history.Query
+    format   string
+    opts     export.Options
+    filename string
+}
+
+// parseExport разбирает фильтр истории и параметры выгрузки: format, weight_unit, dimension_unit, columns
+func parseExport(r *http.Request) (*exportJob, error) {
+    q, err := parseHistoryQuery(r)
+    if err != nil {
+        return nil, err
+    }
+
+    params := r.URL.Query()
+    format := params.Get("format")
+    if format == "" {
+        format = export.FormatCSV
+    }
+    if format != export.FormatCSV && format != export.FormatXLSX {
+        return nil, fmt.Errorf("Неизвестный формат выгрузки: %s", format)
+    }
+    opts := export.Options{
+        WeightUnit:    params.Get("weight_unit"),
+        DimensionUnit: params.Get("dimension_unit"),
+    }
+    if columns := params.Get("columns"); columns != "" {
+        opts.Columns = strings.Split(columns, ",")
+    }
+    if err := export.Validate(opts); err != nil {
+        return nil, err
+    }
+
+    filename := "measurements"
+    if q.StationID != "" {
+        filename += "_" + q.StationID
+    }
+    if !q.From.IsZero() {
+        filename += "_" + q.From.Format("2006-01-02")
+    }
+    if !q.To.IsZero() {
+        filename += "_" + q.To.Format("2006-01-02")
+    }
+    return &exportJob{query: q, format: format, opts: opts, filename: filename + "." + format}, nil
+}
+
+func (job *exportJob) write(w http.ResponseWriter, store *history.Store) {
+    w.Header().Set("Content-Type", export.ContentType(job.format))
+    w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", job.filename))
+    if err := export.Write(w, job.format, store, job.query, job.opts); err != nil {
+        // Заголовки уже отправлены, поэтому ошибку можно только залогировать
+        logging.Error(fmt.Sprintf("Ошибка выгрузки истории: %v", err), "system")
+    }
+}
+
+func carriersHandler(w http.ResponseWriter, r *http.Request, state *types.AppState) {
+    if r.Method != "GET" {
+        http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+        return
+    }
+
+    settings := config.Get()
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(map[string]interface{}{
+        "active":   settings.Carrier,
+        "carriers": settings.Carriers,
+    })
+}
+
+func templatesHandler(w http.ResponseWriter, r *http.Request, state *types.AppState) {
+    if r.Method != "GET" {
+        http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+        return
+    }
+
+    // Для наглядности показываем, как последнее измерение выглядит в каждом шаблоне
+    settings := config.Get()
+    previews := make(map[string]string)
+    if last := state.Status.LastMeasurement; last != nil {
+        for name, tpl := range settings.OutputTemplates {
+            previews[name] = measurement.FormatWith(last, tpl)
+        }
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(map[string]interface{}{
+        "station":   settings.StationID,
+        "active":    measurement.StationTemplate(),
+        "templates": settings.OutputTemplates,
+        "previews":  previews,
+    })
+}
\ No newline at end of file
@@ -0,0 +1,243 @@
+package web
+
+import (
+    _ "embed"
+    "encoding/json"
+    "fmt"
+    "net/http"
+    "slices"
+    "strings"
+)
+
+// openapiSpec — спецификация OpenAPI 3 HTTP API станции. При изменении маршрутов
+// в StartServer спецификацию нужно обновить: CheckSpec сверяет их при запуске.
+//go:embed openapi.json
+var openapiSpec []byte
+
+// Route — маршрут, зарегистрированный в RegisterRoutes, и его методы
+type Route struct {
+    Pattern string
+    Methods []string
+}
+
+// router регистрирует обработчики в mux и запоминает маршруты для сверки со спецификацией
+type router struct {
+    mux    *http.ServeMux
+    routes []Route
+}
+
+// handle регистрирует обработчик с проверкой доступа. methods — допустимые методы через запятую.
+func (rt *router) handle(methods, pattern string, handler func(http.ResponseWriter, *http.Request)) {
+    rt.routes = append(rt.routes, Route{Pattern: pattern, Methods: strings.Split(methods, ",")})
+    rt.mux.HandleFunc(pattern, authorize(pattern, handler))
+}
+
+// CheckSpec сверяет маршруты со спецификацией и возвращает расхождения:
+// маршруты и методы без описания и описанные, но не зарегистрированные пути.
+func CheckSpec(routes []Route) []string {
+    var spec struct {
+        Paths map[string]map[string]json.RawMessage `json:"paths"`
+    }
+    if err := json.Unmarshal(openapiSpec, &spec); err != nil {
+        return []string{fmt.Sprintf("спецификация OpenAPI не разбирается: %v", err)}
+    }
+
+    var problems []string
+    registered := make(map[string]bool)
+    for _, r := range routes {
+        registered[r.Pattern] = true
+        ops, ok := spec.Paths[r.Pattern]
+        if !ok {
+            problems = append(problems, fmt.Sprintf("маршрут %s не описан в спецификации", r.Pattern))
+            continue
+        }
+        for _, m := range r.Methods {
+            if _, ok := ops[strings.ToLower(m)]; !ok {
+                problems = append(problems, fmt.Sprintf("метод %s %s не описан в спецификации", m, r.Pattern))
+            }
+        }
+        for m := range ops {
+            if isMethod(m) && !slices.Contains(r.Methods, strings.ToUpper(m)) {
+                problems = append(problems, fmt.Sprintf("метод %s %s описан, но не поддерживается", strings.ToUpper(m), r.Pattern))
+            }
+        }
+    }
+    for path := range spec.Paths {
+        if !registered[path] {
+            problems = append(problems, fmt.Sprintf("путь %s описан, но не зарегистрирован", path))
+        }
+    }
+    slices.Sort(problems)
+    return problems
+}
+
+// isMethod отличает операции пути от общих полей (parameters, summary)
+func isMethod(key string) bool {
+    switch key {
+    case "get", "put", "post", "delete", "patch", "head", "options", "trace":
+        return true
+    }
+    return false
+}
+
+func openapiHandler(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-Type", "application/json")
+    w.Header().Set("Access-Control-Allow-Origin", "*")
+    w.Write(openapiSpec)
+}
+
+// docsHandler отдает страницу документации. Страница строится из /openapi.json
+// в браузере и не требует доступа в интернет.
+func docsHandler(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-Type", "text/html; charset=utf-8")
+    w.Write([]byte(docsPage))
+}
+
+const docsPage = `<!DOCTYPE html>
+<html>
+<head>
+    <title>API станции</title>
+    <meta charset="utf-8">
+    <style>
+        body { font-family: Arial, sans-serif; margin: 20px; background-color: #f5f5f5; }
+        .container { max-width: 1200px; margin: 0 auto; }
+        h1 { color: #333; }
+        h2 { color: #555; border-bottom: 2px solid #2196F3; padding-bottom: 5px; }
+        details { background: white; margin: 6px 0; border-radius: 6px; box-shadow: 0 1px 3px rgba(0,0,0,0.1); }
+        summary { padding: 10px; cursor: pointer; font-family: monospace; font-size: 14px; }
+        .body { padding: 0 15px 10px 15px; font-size: 14px; }
+        .method { display: inline-block; width: 60px; font-weight: bold; color: white; text-align: center; border-radius: 3px; margin-right: 8px; }
+        .get { background: #2196F3; } .post { background: #4CAF50; } .put { background: #FF9800; } .delete { background: #f44336; }
+        .deprecated { text-decoration: line-through; color: #999; }
+        pre { background: #f0f0f0; padding: 8px; border-radius: 4px; overflow-x: auto; font-size: 12px; }
+        table { border-collapse: collapse; font-size: 13px; }
+        th, td { border-bottom: 1px solid #ddd; padding: 4px 8px; text-align: left; }
+        input { padding: 8px; width: 300px; border: 1px solid #ddd; border-radius: 4px; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <h1 id="title">API станции</h1>
+        <p id="description"></p>
+        <p><a href="/openapi.json">openapi.json</a> · <a href="/">Веб-интерфейс</a></p>
+        <input type="text" id="filter" placeholder="Фильтр по пути" oninput="render()">
+        <div id="operations"></div>
+    </div>
+    <script>
+        let spec = null;
+
+        // resolve заменяет ссылки $ref на схемы из components
+        function resolve(schema, depth) {
+            if (!schema || depth > 4) {
+                return schema;
+            }
+            if (schema.$ref) {
+                const name = schema.$ref.split('/').pop();
+                return Object.assign({title: name}, resolve(spec.components.schemas[name], depth + 1));
+            }
+            const copy = Object.assign({}, schema);
+            if (copy.properties) {
+                copy.properties = Object.fromEntries(Object.entries(copy.properties).map(([k, v]) => [k, resolve(v, depth + 1)]));
+            }
+            if (copy.items) {
+                copy.items = resolve(copy.items, depth + 1);
+            }
+            return copy;
+        }
+
+        function schemaBlock(content) {
+            const pre = document.createElement('pre');
+            const entry = content && Object.entries(content)[0];
+            pre.textContent = entry ? entry[0] + '\n' + JSON.stringify(resolve(entry[1].schema, 0), null, 2) : '';
+            return pre;
+        }
+
+        function render() {
+            const filter = document.getElementById('filter').value;
+            const root = document.getElementById('operations');
+            root.innerHTML = '';
+            const groups = {};
+            Object.entries(spec.paths).forEach(([path, ops]) => {
+                if (filter && !path.includes(filter)) {
+                    return;
+                }
+                Object.entries(ops).forEach(([method, op]) => {
+                    const tag = (op.tags || ['Прочее'])[0];
+                    (groups[tag] = groups[tag] || []).push([path, method, op]);
+                });
+            });
+            (spec.tags || []).map(t => t.name).concat(Object.keys(groups)).filter((t, i, all) => all.indexOf(t) === i).forEach(tag => {
+                if (!groups[tag]) {
+                    return;
+                }
+                const title = document.createElement('h2');
+                title.textContent = tag;
+                root.appendChild(title);
+                groups[tag].forEach(([path, method, op]) => {
+                    const item = document.createElement('details');
+                    const summary = document.createElement('summary');
+                    const badge = document.createElement('span');
+                    badge.className = 'method ' + method;
+                    badge.textContent = method.toUpperCase();
+                    const name = document.createElement('span');
+                    name.textContent = path + ' — ' + (op.summary || '');
+                    if (op.deprecated) {
+                        name.className = 'deprecated';
+                    }
+                    summary.appendChild(badge);
+                    summary.appendChild(name);
+                    item.appendChild(summary);
+
+                    const body = document.createElement('div');
+                    body.className = 'body';
+                    if (op.description) {
+                        const p = document.createElement('p');
+                        p.textContent = op.description;
+                        body.appendChild(p);
+                    }
+                    if (op.parameters) {
+                        const table = document.createElement('table');
+                        table.innerHTML = '<tr><th>Параметр</th><th>Где</th><th>Тип</th><th>Описание</th></tr>';
+                        op.parameters.forEach(p => {
+                            const row = table.insertRow();
+                            [p.name, p.in, (p.schema.enum || [p.schema.type]).join(' | '), p.description || ''].forEach(v => {
+                                row.insertCell().textContent = v;
+                            });
+                        });
+                        body.appendChild(table);
+                    }
+                    if (op.requestBody) {
+                        const h = document.createElement('h4');
+                        h.textContent = 'Тело запроса';
+                        body.appendChild(h);
+                        body.appendChild(schemaBlock(op.requestBody.content));
+                    }
+                    Object.entries(op.responses || {}).forEach(([code, resp]) => {
+                        const h = document.createElement('h4');
+                        h.textContent = code + ' — ' + resp.description;
+                        body.appendChild(h);
+                        if (resp.content) {
+                            body.appendChild(schemaBlock(resp.content));
+                        }
+                    });
+                    item.appendChild(body);
+                    root.appendChild(item);
+                });
+            });
+        }
+
+        fetch('/openapi.json')
+            .then(response => response.json())
+            .then(data => {
+                spec = data;
+                document.getElementById('title').textContent = spec.info.title;
+                document.getElementById('description').textContent = spec.info.description || '';
+                render();
+            })
+            .catch(err => {
+                document.getElementById('operations').textContent = 'Ошибка загрузки спецификации: ' + err;
+            });
+    </script>
+</body>
+</html>
+`
\ No newline at end of file
@@ -0,0 +1,54 @@
+package web
+
+import (
+    "net/http"
+    "strings"
+    "testing"
+
+    "betelgeuze-measure-system-main/types"
+)
+
+func TestSpecMatchesRoutes(t *testing.T) {
+    routes := RegisterRoutes(http.NewServeMux(), &types.AppState{})
+    if len(routes) == 0 {
+        t.Fatal("маршруты не зарегистрированы")
+    }
+    for _, problem := range CheckSpec(routes) {
+        t.Error(problem)
+    }
+}
+
+func TestCheckSpecReportsUndocumentedRoute(t *testing.T) {
+    routes := RegisterRoutes(http.NewServeMux(), &types.AppState{})
+    routes = append(routes, Route{Pattern: "/not-in-spec", Methods: []string{"GET"}})
+    routes[0].Methods = append(routes[0].Methods, "DELETE")
+
+    problems := CheckSpec(routes)
+    want := []string{
+        "маршрут /not-in-spec не описан в спецификации",
+        "метод DELETE " + routes[0].Pattern + " не описан в спецификации",
+    }
+    for _, w := range want {
+        found := false
+        for _, p := range problems {
+            if p == w {
+                found = true
+            }
+        }
+        if !found {
+            t.Errorf("нет расхождения %q, получено: %s", w, strings.Join(problems, "; "))
+        }
+    }
+    if len(problems) != len(want) {
+        t.Errorf("ожидалось %d расхождения, получено: %s", len(want), strings.Join(problems, "; "))
+    }
+}
+
+func TestCheckSpecReportsUnregisteredPath(t *testing.T) {
+    routes := RegisterRoutes(http.NewServeMux(), &types.AppState{})
+    problems := CheckSpec(routes[1:])
+    want := "путь " + routes[0].Pattern + " описан, но не зарегистрирован"
+    if len(problems) != 1 || problems[0] != want {
+        t.Errorf("ожидалось %q, получено: %v", want, problems)
+    }
+}
\ No newline at end of file
@@ -0,0 +1,119 @@
+package web
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net/http"
+
+    "betelgeuze-measure-system-main/outbox"
+    "betelgeuze-measure-system-main/types"
+)
+
+// outboxView — состояние одной очереди доставки для веб-интерфейса
+type outboxView struct {
+    Name    string         `json:"name"`
+    Pending []*outbox.Item `json:"pending"`
+    Dead    []*outbox.Item `json:"dead"`
+}
+
+func outboxHandler(w http.ResponseWriter, r *http.Request, state *types.AppState) {
+    if r.Method != "GET" {
+        http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+        return
+    }
+
+    views, err := outboxViews()
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+
+    w.Header().Set("Content-Type", "application/json")
+    json.NewEncoder(w).Encode(views)
+}
+
+// outboxViews читает все очереди доставки
+func outboxViews() ([]outboxView, error) {
+    views := []outboxView{}
+    for _, q := range outbox.All() {
+        pending, err := q.List(outbox.QueuePending)
+        if err != nil {
+            return nil, fmt.Errorf("Ошибка чтения очереди %s: %v", q.Name, err)
+        }
+        dead, err := q.List(outbox.QueueDead)
+        if err != nil {
+            return nil, fmt.Errorf("Ошибка чтения очереди %s: %v", q.Name, err)
+        }
+        if pending == nil {
+            pending = []*outbox.Item{}
+        }
+        if dead == nil {
+            dead = []*outbox.Item{}
+        }
+        views = append(views, outboxView{Name: q.Name, Pending: pending, Dead: dead})
+    }
+    return views, nil
+}
+
+// outboxRequest — тело запросов повтора и очистки. Пустой ID — все записи очереди.
+type outboxRequest struct {
+    Sink  string `json:"sink"`
+    Queue string `json:"queue"`
+    ID    string `json:"id"`
+}
+
+func decodeOutboxRequest(w http.ResponseWriter, r *http.Request) (*outbox.Queue, *outboxRequest, bool) {
+    if r.Method != "POST" {
+        http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+        return nil, nil, false
+    }
+    var req outboxRequest
+    if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+        http.Error(w, "Invalid JSON", http.StatusBadRequest)
+        return nil, nil, false
+    }
+    q := outbox.Get(req.Sink)
+    if q == nil {
+        http.Error(w, "Очередь не найдена: "+req.Sink, http.StatusNotFound)
+        return nil, nil, false
+    }
+    return q, &req, true
+}
+
+func outboxRetryHandler(w http.ResponseWriter, r *http.Request, state *types.AppState) {
+    q, req, ok := decodeOutboxRequest(w, r)
+    if !ok {
+        return
+    }
+    count, err := q.Retry(req.ID)
+    if errors.Is(err, outbox.ErrNotFound) {
+        http.Error(w, err.Error(), http.StatusNotFound)
+        return
+    }
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
+    }
+    w.Write([]byte(fmt.Sprintf("Поставлено на повтор: %d", count)))
+}
+
+func outboxPurgeHandler(w http.ResponseWriter, r *http.Request, state *types.AppState) {
+    q, req, ok := decodeOutboxRequest(w, r)
+    if !ok {
+        return
+    }
+    if req.Queue == "" {
+        req.Queue = outbox.QueueDead
+    }
+    count, err := q.Purge(req.Queue, req.ID)
+    if errors.Is(err, outbox.ErrNotFound) {
+        http.Error(w, err.Error(), http.StatusNotFound)
+        return
+    }
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
+    }
+    w.Write([]byte(fmt.Sprintf("Удалено: %d", count)))
+}
\ No newline at end of file
@@ -10,28 +10,85 @@ import (
 )
 
 func StartServer(state *types.AppState) {
-    http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+    mux := http.NewServeMux()
+    routes := RegisterRoutes(mux, state)
+
+    // Спецификация должна описывать все маршруты: расхождение — ошибка разработчика
+    for _, problem := range CheckSpec(routes) {
+        log.Printf("OpenAPI: %s", problem)
+    }
+
+    fmt.Println("Веб-сервер запущен на http://localhost" + config.SERVER_PORT)
+    log.Fatal(http.ListenAndServe(config.SERVER_PORT, mux))
+}
+
+// RegisterRoutes регистрирует все маршруты веб-сервера в mux и возвращает их для сверки со спецификацией
+func RegisterRoutes(mux *http.ServeMux, state *types.AppState) []Route {
+    rt := &router{mux: mux}
+    rt.handle("GET", "/", func(w http.ResponseWriter, r *http.Request) {
         indexHandler(w, r, state)
     })
-    http.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+    rt.handle("GET", "/status", func(w http.ResponseWriter, r *http.Request) {
         statusHandler(w, r, state)
     })
-    http.HandleFunc("/reconnect", func(w http.ResponseWriter, r *http.Request) {
+    rt.handle("POST", "/reconnect", func(w http.ResponseWriter, r *http.Request) {
         reconnectHandler(w, r, state)
     })
-    http.HandleFunc("/arduino/command", func(w http.ResponseWriter, r *http.Request) {
+    rt.handle("POST", "/arduino/command", func(w http.ResponseWriter, r *http.Request) {
         arduinoCommandHandler(w, r, state)
     })
-    http.HandleFunc("/scale/read", func(w http.ResponseWriter, r *http.Request) {
+    rt.handle("POST", "/scale/read", func(w http.ResponseWriter, r *http.Request) {
         scaleReadHandler(w, r, state)
     })
-    http.HandleFunc("/measure/combined", func(w http.ResponseWriter, r *http.Request) {
+    rt.handle("POST", "/measure/combined", func(w http.ResponseWriter, r *http.Request) {
         combinedMeasureHandler(w, r, state)
     })
-    http.HandleFunc("/logs/stream", func(w http.ResponseWriter, r *http.Request) {
-        logsStreamHandler(w, r, state)
+    rt.handle("GET", "/measurements", func(w http.ResponseWriter, r *http.Request) {
+        measurementsHandler(w, r, state)
+    })
+    rt.handle("GET", "/measurements/export", func(w http.ResponseWriter, r *http.Request) {
+        exportHandler(w, r, state)
+    })
+    rt.handle("GET", "/measurements/{id}", func(w http.ResponseWriter, r *http.Request) {
+        measurementHandler(w, r, state)
+    })
+    rt.handle("POST", "/measurements/{id}/approve", func(w http.ResponseWriter, r *http.Request) {
+        approveHandler(w, r, state)
+    })
+    rt.handle("POST", "/measurements/{id}/discard", func(w http.ResponseWriter, r *http.Request) {
+        discardHandler(w, r, state)
+    })
+    rt.handle("GET", "/carriers", func(w http.ResponseWriter, r *http.Request) {
+        carriersHandler(w, r, state)
     })
+    rt.handle("GET", "/templates", func(w http.ResponseWriter, r *http.Request) {
+        templatesHandler(w, r, state)
+    })
+    rt.handle("GET,POST", "/mode", func(w http.ResponseWriter, r *http.Request) {
+        modeHandler(w, r, state)
+    })
+    rt.handle("GET", "/feedback", func(w http.ResponseWriter, r *http.Request) {
+        feedbackHandler(w, r, state)
+    })
+    rt.handle("POST", "/barcode", func(w http.ResponseWriter, r *http.Request) {
+        barcodeHandler(w, r, state)
+    })
+    rt.handle("GET", "/outbox", func(w http.ResponseWriter, r *http.Request) {
+        outboxHandler(w, r, state)
+    })
+    rt.handle("POST", "/outbox/retry", func(w http.ResponseWriter, r *http.Request) {
+        outboxRetryHandler(w, r, state)
+    })
+    rt.handle("POST", "/outbox/purge", func(w http.ResponseWriter, r *http.Request) {
+        outboxPurgeHandler(w, r, state)
+    })
+    registerAPI(rt, state)
+    rt.handle("GET", "/openapi.json", openapiHandler)
+    rt.handle("GET", "/docs", docsHandler)
+    rt.handle("GET", "/login", loginHandler)
 
-    fmt.Println("Веб-сервер запущен на http://localhost" + config.SERVER_PORT)
-    log.Fatal(http.ListenAndServe(config.SERVER_PORT, nil))
+    rt.handle("GET", "/logs/stream", func(w http.ResponseWriter, r *http.Request) {
+        logsStreamHandler(w, r, state)
+    })
+    return rt.routes
 }
\ No newline at end of file
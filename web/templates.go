@@ -31,14 +31,33 @@ const htmlTemplate = `
         .log { height: 200px; overflow-y: scroll; background-color: #000; color: #0f0; padding: 10px; font-family: monospace; font-size: 12px; }
         h1 { color: #333; text-align: center; }
         h2 { color: #555; border-bottom: 2px solid #2196F3; padding-bottom: 5px; }
+        table { width: 100%; border-collapse: collapse; font-size: 14px; }
+        th, td { border-bottom: 1px solid #ddd; padding: 6px 8px; text-align: left; }
+        th { background-color: #f0f0f0; }
+        tr.rejected td { background-color: #ffebee; }
+        .violations { color: #f44336; font-weight: bold; }
+        .confirm input[type=number] { width: 80px; }
+        .gauge { margin-bottom: 15px; }
+        .gauge-value { font-size: 48px; font-weight: bold; font-family: monospace; }
+        .gauge-bar { height: 16px; background-color: #eee; border-radius: 8px; overflow: hidden; }
+        .gauge-fill { height: 100%; width: 0; background-color: #FF9800; transition: width 0.2s; }
+        .gauge-fill.stable { background-color: #4CAF50; }
+        .gauge-fill.over { background-color: #f44336; }
     </style>
 </head>
 <body>
     <div class="container">
         <h1>🔧 Система измерения веса и размеров</h1>
+        <p style="text-align: center;"><a href="/docs">📘 Документация API</a> <span id="user-info"></span></p>
         
         <div class="card">
             <h2>📊 Статус устройств</h2>
+            <div class="gauge">
+                <span class="gauge-value" id="live-weight">—</span> <span id="live-unit">г</span>
+                <span id="live-stable" class="disconnected">нет данных</span>
+                <span id="live-tare"></span>
+                <div class="gauge-bar"><div class="gauge-fill" id="live-fill"></div></div>
+            </div>
             <div class="status">
                 <div class="device">
                     <h3>Arduino</h3>
@@ -50,13 +69,25 @@ const htmlTemplate = `
                     <p>Статус: <span id="scale-status" class="disconnected">Загрузка...</span></p>
                     <p>Порт: <span id="scale-port">Загрузка...</span></p>
                 </div>
+                <div class="device">
+                    <h3>Сканер штрихкодов</h3>
+                    <p>Статус: <span id="scanner-status" class="disconnected">Загрузка...</span></p>
+                    <p>Ожидает измерения: <span id="pending-barcode">-</span></p>
+                    <input type="text" id="barcode-input" placeholder="Штрихкод" onkeydown="if (event.key === 'Enter') submitBarcode()">
+                    <button onclick="submitBarcode()">Привязать</button>
+                </div>
                 <div class="device">
                     <h3>Последние данные</h3>
                     <p>Вес: <span id="last-weight">-</span> г</p>
                     <p>Размеры: <span id="last-dimensions">-</span></p>
+                    <p>Штрихкод: <span id="last-barcode">-</span></p>
+                    <p id="last-violations" class="violations"></p>
+                    <p>Объемный вес: <span id="last-volumetric">-</span> г</p>
+                    <p>Оплачиваемый вес: <span id="last-chargeable">-</span> г <span id="last-carrier"></span></p>
                 </div>
             </div>
             <button onclick="reconnectDevices()">🔄 Переподключить устройства</button>
+            <label><input type="checkbox" id="sound-enabled" onchange="toggleSound(this.checked)"> 🔊 Звуковые сигналы</label>
         </div>
 
         <div class="card">
@@ -105,24 +136,162 @@ const htmlTemplate = `
             <div style="display: flex; gap: 10px; flex-wrap: wrap; align-items: center;">
                 <button onclick="readWeight()">📊 Считать только вес</button>
                 <button onclick="combinedMeasure()" style="background-color: #4CAF50; font-weight: bold;">
-                    🎯 Измерить ВСЁ + копировать
+                    🎯 Измерить ВСЁ + отправить
                 </button>
             </div>
+            <div style="display: flex; gap: 10px; flex-wrap: wrap; align-items: center;">
+                <span>Автоматические измерения: <b id="loop-mode">-</b> <span id="loop-reason"></span></span>
+                <button onclick="setMode('paused')">⏸️ Пауза</button>
+                <button onclick="setMode('auto')">▶️ Возобновить</button>
+                <button onclick="setMode('single')">1️⃣ Только следующий объект</button>
+            </div>
             
             <h3>Ответ системы:</h3>
             <div id="scale-response" class="response">Ожидание команды...</div>
         </div>
 
+        <div class="card confirm">
+            <h2>✋ Подтверждение измерений</h2>
+            <div style="display: flex; gap: 5px; flex-wrap: wrap; align-items: center;">
+                <label>Оператор:</label>
+                <input type="text" id="operator-name" placeholder="Имя оператора" onchange="localStorage.setItem('operator-name', this.value)">
+                <button onclick="loadConfirm(true)">🔄 Обновить</button>
+            </div>
+            <table>
+                <thead>
+                    <tr><th>Время</th><th>Статус</th><th>Вес, г</th><th>Д</th><th>Ш</th><th>В</th><th>Штрихкод</th><th>Нарушения</th><th></th></tr>
+                </thead>
+                <tbody id="confirm-rows"></tbody>
+            </table>
+            <div id="confirm-info">Нет измерений, ожидающих подтверждения</div>
+        </div>
+
+        <div class="card">
+            <h2>🗂️ История измерений</h2>
+            <div style="display: flex; gap: 5px; flex-wrap: wrap; align-items: center;">
+                <label>С:</label><input type="date" id="history-from">
+                <label>По:</label><input type="date" id="history-to">
+                <input type="text" id="history-barcode" placeholder="Штрихкод">
+                <input type="number" id="history-min-weight" placeholder="Вес от, г" style="width: 110px;">
+                <input type="number" id="history-max-weight" placeholder="Вес до, г" style="width: 110px;">
+                <button onclick="loadHistory(0)">🔍 Найти</button>
+            </div>
+            <div style="display: flex; gap: 5px; flex-wrap: wrap; align-items: center;">
+                <label>Вес в:</label>
+                <select id="export-weight-unit">
+                    <option value="g">г</option>
+                    <option value="kg">кг</option>
+                    <option value="lb">lb</option>
+                </select>
+                <label>Размеры в:</label>
+                <select id="export-dimension-unit">
+                    <option value="cm">см</option>
+                    <option value="mm">мм</option>
+                    <option value="in">in</option>
+                </select>
+                <button onclick="exportHistory('csv')">⬇️ Скачать CSV</button>
+                <button onclick="exportHistory('xlsx')">⬇️ Скачать XLSX</button>
+            </div>
+            <table>
+                <thead>
+                    <tr><th>Время</th><th>ID</th><th>Вес, г</th><th>Д</th><th>Ш</th><th>В</th><th>Оплач., г</th><th>Штрихкод</th><th>Источник</th><th>Вывод</th></tr>
+                </thead>
+                <tbody id="history-rows"></tbody>
+            </table>
+            <div style="margin-top: 10px;">
+                <button id="history-prev" onclick="loadHistory(historyOffset - historyLimit)">◀️ Назад</button>
+                <span id="history-info">-</span>
+                <button id="history-next" onclick="loadHistory(historyOffset + historyLimit)">Вперед ▶️</button>
+            </div>
+        </div>
+
+        <div class="card">
+            <h2>📮 Очередь доставки (webhook)</h2>
+            <button onclick="loadOutbox()">🔄 Обновить</button>
+            <div id="outbox-panel">Нет очередей доставки</div>
+        </div>
+
         <div class="card">
             <h2>📝 Лог системы</h2>
+            <select id="log-type" onchange="connectToLogs()">
+                <option value="">Все источники</option>
+                <option value="arduino">Arduino</option>
+                <option value="scale">Весы</option>
+                <option value="system">Система</option>
+            </select>
+            <select id="log-level" onchange="connectToLogs()">
+                <option value="">Все уровни</option>
+                <option value="warn">Предупреждения и ошибки</option>
+                <option value="error">Только ошибки</option>
+            </select>
+            <span id="log-dropped" class="violations"></span>
             <div id="system-log" class="log">Система запущена...\n</div>
         </div>
     </div>
 
     <script>
+        // Запрос к API станции: возвращает разобранный JSON, ошибку API — как Error с кодом
+        const API = '/api/v1';
+        function api(method, path, body) {
+            const options = {method: method};
+            if (body !== undefined) {
+                options.headers = {'Content-Type': 'application/json'};
+                options.body = JSON.stringify(body);
+            }
+            return fetch(API + path, options).then(response => {
+                // Сессия истекла: на страницу входа
+                if (response.status === 401) {
+                    location.href = '/login';
+                }
+                if (response.status === 204) {
+                    return null;
+                }
+                return response.json().then(data => {
+                    if (!response.ok) {
+                        const err = new Error(data.error ? data.error.message : response.statusText);
+                        err.code = data.error && data.error.code;
+                        err.details = data.error && data.error.details;
+                        throw err;
+                    }
+                    return data;
+                });
+            });
+        }
+
+        // Пользователь показывается, только если на станции настроен вход
+        function loadUser() {
+            api('GET', '/auth/me')
+                .then(me => {
+                    if (!me.auth_enabled) {
+                        return;
+                    }
+                    const info = document.getElementById('user-info');
+                    info.textContent = '· 👤 ' + me.name + ' (' + me.role + ') · ';
+                    const link = document.createElement('a');
+                    link.href = '#';
+                    link.textContent = 'Выйти';
+                    link.onclick = function(event) {
+                        event.preventDefault();
+                        api('POST', '/auth/logout').then(() => location.href = '/login');
+                    };
+                    info.appendChild(link);
+                })
+                .catch(() => {});
+        }
+
+        function describeMeasurement(m) {
+            let text = m.weight + ' г';
+            if (m.length || m.width || m.height) {
+                text += ', ' + m.length + '×' + m.width + '×' + m.height + ' см';
+            }
+            if (m.barcode) {
+                text += ', ' + m.barcode;
+            }
+            return text;
+        }
+
         function updateStatus() {
-            fetch('/status')
-                .then(response => response.json())
+            api('GET', '/status')
                 .then(data => {
                     document.getElementById('arduino-status').textContent = data.arduino_connected ? 'Подключен' : 'Отключен';
                     document.getElementById('arduino-status').className = data.arduino_connected ? 'connected' : 'disconnected';
@@ -132,38 +301,200 @@ const htmlTemplate = `
                     document.getElementById('scale-status').className = data.scale_connected ? 'connected' : 'disconnected';
                     document.getElementById('scale-port').textContent = data.scale_port;
                     
+                    document.getElementById('scanner-status').textContent = data.scanner_connected ? 'Подключен (' + data.scanner_port + ')' : 'Отключен';
+                    document.getElementById('scanner-status').className = data.scanner_connected ? 'connected' : 'disconnected';
+                    document.getElementById('pending-barcode').textContent = data.pending_barcode || '-';
+                    
                     document.getElementById('last-weight').textContent = data.last_weight || '-';
                     document.getElementById('last-dimensions').textContent = data.last_dimensions || '-';
+                    const last = data.last_measurement;
+                    document.getElementById('last-volumetric').textContent = last ? last.volumetric_weight : '-';
+                    document.getElementById('last-chargeable').textContent = last ? last.chargeable_weight : '-';
+                    document.getElementById('last-barcode').textContent = last && last.barcode ? last.barcode : '-';
+                    document.getElementById('last-violations').textContent = last && last.violations ? '⛔ Отклонено: ' + last.violations.join('; ') : '';
+                    document.getElementById('last-carrier').textContent = last && last.carrier ? '(' + last.carrier + ')' : '';
+
+                    const modes = {auto: 'работают', paused: 'на паузе', single: 'только следующий объект'};
+                    document.getElementById('loop-mode').textContent = modes[data.mode] || data.mode;
+                    document.getElementById('loop-reason').textContent = data.mode_reason ? '(' + data.mode_reason + ')' : '';
                 })
                 .catch(err => {
                     addLog('Ошибка получения статуса: ' + err);
                 });
         }
 
+        function setMode(mode) {
+            api('PUT', '/mode', {mode: mode})
+                .then(() => updateStatus())
+                .catch(err => {
+                    addLog('Ошибка переключения режима: ' + err.message);
+                });
+        }
+
+        function submitBarcode() {
+            const input = document.getElementById('barcode-input');
+            api('POST', '/barcode', {code: input.value})
+                .then(result => {
+                    addLog('Штрихкод принят: ' + result.code);
+                    input.value = '';
+                    updateStatus();
+                })
+                .catch(err => addLog('Ошибка отправки штрихкода: ' + err.message));
+        }
+
+        // Звуковые сигналы по событиям измерения: настройка событий приходит с /feedback
+        let feedbackEvents = {};
+        let audioContext = null;
+        const SOUNDS = {
+            'success': [[880, 0.12], [1320, 0.18]],
+            'warning': [[660, 0.15], [0, 0.08], [660, 0.15]],
+            'error':   [[220, 0.5]]
+        };
+
+        function loadFeedback() {
+            api('GET', '/feedback')
+                .then(data => { feedbackEvents = data || {}; })
+                .catch(err => addLog('Ошибка загрузки сигналов: ' + err));
+        }
+
+        function toggleSound(enabled) {
+            localStorage.setItem('sound-enabled', enabled ? '1' : '');
+            // Браузер разрешает звук только после действия пользователя
+            if (enabled && !audioContext) {
+                audioContext = new (window.AudioContext || window.webkitAudioContext)();
+            }
+        }
+
+        function playCue(event) {
+            const cue = feedbackEvents[event];
+            if (!cue || !audioContext || !document.getElementById('sound-enabled').checked) {
+                return;
+            }
+            const notes = SOUNDS[cue.sound];
+            if (!notes) {
+                return;
+            }
+            let at = audioContext.currentTime;
+            notes.forEach(([freq, duration]) => {
+                if (freq > 0) {
+                    const osc = audioContext.createOscillator();
+                    const gain = audioContext.createGain();
+                    osc.frequency.value = freq;
+                    gain.gain.value = 0.2;
+                    osc.connect(gain).connect(audioContext.destination);
+                    osc.start(at);
+                    osc.stop(at + duration);
+                }
+                at += duration;
+            });
+        }
+
+        // Живой вес по WebSocket; шкала — до max_weight из правил проверки
+        let gaugeMax = 30000;
+        function connectLiveWeight() {
+            const proto = location.protocol === 'https:' ? 'wss://' : 'ws://';
+            const ws = new WebSocket(proto + location.host + API + '/scale/stream?interval_ms=200');
+            ws.onmessage = function(event) {
+                const r = JSON.parse(event.data);
+                document.getElementById('live-weight').textContent = r.weight.toFixed(1);
+                document.getElementById('live-unit').textContent = r.unit || 'г';
+                const stable = document.getElementById('live-stable');
+                stable.textContent = r.stable ? '● стабильно' : '○ меняется';
+                stable.className = r.stable ? 'connected' : 'disconnected';
+                document.getElementById('live-tare').textContent = r.tare ? 'тара: ' + r.tare.toFixed(1) : '';
+                const fill = document.getElementById('live-fill');
+                fill.style.width = Math.min(Math.max(r.weight, 0) / gaugeMax * 100, 100) + '%';
+                fill.className = 'gauge-fill' + (r.weight > gaugeMax ? ' over' : r.stable ? ' stable' : '');
+            };
+            ws.onclose = function() {
+                const stable = document.getElementById('live-stable');
+                stable.textContent = 'нет связи';
+                stable.className = 'disconnected';
+                setTimeout(connectLiveWeight, 3000);
+            };
+        }
+        function loadGaugeMax() {
+            api('GET', '/config')
+                .then(cfg => {
+                    if (cfg.validation && cfg.validation.max_weight) {
+                        gaugeMax = cfg.validation.max_weight;
+                    }
+                })
+                .catch(() => {});
+        }
+
+        // События измерений обновляют историю и список подтверждения без опроса.
+        // EventSource сам переподключается и передает Last-Event-ID.
+        function connectToEvents() {
+            const source = new EventSource(API + '/events?topics=measurement');
+            const refresh = () => {
+                loadConfirm();
+                loadHistory(historyOffset);
+            };
+            source.onopen = refresh;
+            source.addEventListener('measurement.completed', refresh);
+            source.addEventListener('measurement.updated', refresh);
+            source.addEventListener('stream.gap', refresh);
+        }
+
         // Подключение к потоку логов
+        // При подключении сервер повторяет последние сообщения, поэтому лог очищается
+        let logSource = null;
+        let logRetry = null;
         function connectToLogs() {
-            const eventSource = new EventSource('/logs/stream');
+            if (logSource) {
+                logSource.close();
+            }
+            clearTimeout(logRetry);
+            const params = new URLSearchParams();
+            const type = document.getElementById('log-type').value;
+            const level = document.getElementById('log-level').value;
+            if (type) {
+                // Сигналы оператору приходят с типом feedback: без него звук пропадет
+                params.set('type', type + ',feedback');
+            }
+            if (level) {
+                params.set('level', level);
+            }
+            document.getElementById('system-log').innerHTML = '';
+            document.getElementById('log-dropped').textContent = '';
+            const eventSource = new EventSource('/logs/stream?' + params);
+            logSource = eventSource;
             
             eventSource.onmessage = function(event) {
                 const logData = JSON.parse(event.data);
-                addLogToDisplay(logData.time, logData.message, logData.type);
+                // Сигналы оператору не пишутся в лог, а проигрываются звуком
+                if (logData.type === 'feedback') {
+                    playCue(logData.message);
+                    return;
+                }
+                addLogToDisplay(logData.time, logData.message, logData.type, logData.level);
             };
+            eventSource.addEventListener('dropped', function(event) {
+                const data = JSON.parse(event.data);
+                document.getElementById('log-dropped').textContent = '⚠️ Пропущено сообщений: ' + data.dropped;
+            });
             
             eventSource.onerror = function(event) {
                 console.error('Ошибка подключения к логам:', event);
-                addLogToDisplay(new Date().toLocaleTimeString(), 'Ошибка подключения к логам, переподключение через 5 сек...', 'system');
-                setTimeout(connectToLogs, 5000);
+                eventSource.close();
+                addLogToDisplay(new Date().toLocaleTimeString(), 'Ошибка подключения к логам, переподключение через 5 сек...', 'system', 'error');
+                logRetry = setTimeout(connectToLogs, 5000);
             };
         }
         
-        function addLogToDisplay(time, message, type) {
+        function addLogToDisplay(time, message, type, level) {
             const log = document.getElementById('system-log');
             const colorMap = {
                 'arduino': '#00ff00',
                 'scale': '#ffff00', 
                 'system': '#ffffff'
             };
-            const color = colorMap[type] || '#ffffff';
+            const levelColors = {
+                'warn': '#ff9800',
+                'error': '#ff5252'
+            };
+            const color = levelColors[level] || colorMap[type] || '#ffffff';
             
             const logEntry = document.createElement('div');
             logEntry.style.color = color;
@@ -180,10 +511,11 @@ const htmlTemplate = `
 
         function reconnectDevices() {
             addLog('Переподключение устройств...');
-            fetch('/reconnect', {method: 'POST'})
-                .then(response => response.text())
+            api('POST', '/devices/reconnect')
                 .then(data => {
-                    addLog('Результат переподключения: ' + data);
+                    addLog('Результат переподключения: Arduino: ' + (data.arduino_connected ? 'подключен' : 'не подключен') +
+                        ' (' + data.arduino_port + '), Весы: ' + (data.scale_connected ? 'подключены' : 'не подключены') +
+                        ' (' + data.scale_port + ')');
                     updateStatus();
                 })
                 .catch(err => {
@@ -193,48 +525,63 @@ const htmlTemplate = `
 
         function sendArduinoCommand(cmd) {
             addLog('Отправка команды Arduino: ' + cmd);
-            fetch('/arduino/command', {
-                method: 'POST',
-                headers: {'Content-Type': 'application/json'},
-                body: JSON.stringify({command: cmd})
-            })
-            .then(response => response.text())
-            .then(data => {
-                document.getElementById('arduino-response').textContent = data;
-                addLog('Ответ Arduino: ' + data);
-            })
-            .catch(err => {
-                document.getElementById('arduino-response').textContent = 'Ошибка: ' + err;
-                addLog('Ошибка команды Arduino: ' + err);
-            });
+            api('POST', '/arduino/commands', {command: cmd})
+                .then(result => {
+                    let text = 'Команда ' + result.command + ' выполнена';
+                    if (result.reply) {
+                        text = 'Arduino ответил: ' + result.reply;
+                    }
+                    if (result.dimensions) {
+                        const d = result.dimensions;
+                        text = 'Размеры: Д=' + d.length + ', Ш=' + d.width + ', В=' + d.height;
+                    }
+                    document.getElementById('arduino-response').textContent = text;
+                    addLog('Ответ Arduino: ' + text);
+                })
+                .catch(err => {
+                    document.getElementById('arduino-response').textContent = 'Ошибка: ' + err.message;
+                    addLog('Ошибка команды Arduino: ' + err.message);
+                });
+        }
+
+        // Калибровка: максимум габарита по одной оси
+        function setMaximum(axis, inputID) {
+            const body = {};
+            body[axis] = parseInt(document.getElementById(inputID).value, 10);
+            api('PUT', '/calibration', body)
+                .then(limits => {
+                    const text = 'Максимумы: Д=' + limits.length + ', Ш=' + limits.width + ', В=' + limits.height;
+                    document.getElementById('arduino-response').textContent = text;
+                    addLog(text);
+                })
+                .catch(err => {
+                    document.getElementById('arduino-response').textContent = 'Ошибка: ' + err.message;
+                    addLog('Ошибка калибровки: ' + err.message);
+                });
         }
 
         function setTopMax() {
-            const value = document.getElementById('top-max').value;
-            sendArduinoCommand('set_top_max:' + value);
+            setMaximum('height', 'top-max');
         }
 
         function setWidthMax() {
-            const value = document.getElementById('width-max').value;
-            sendArduinoCommand('set_width_max:' + value);
+            setMaximum('width', 'width-max');
         }
 
         function setLengthMax() {
-            const value = document.getElementById('length-max').value;
-            sendArduinoCommand('set_length_max:' + value);
+            setMaximum('length', 'length-max');
         }
 
         function readWeight() {
             addLog('Считывание веса...');
-            fetch('/scale/read', {method: 'POST'})
-                .then(response => response.text())
-                .then(data => {
-                    document.getElementById('scale-response').textContent = data;
-                    addLog('Вес: ' + data);
+            api('POST', '/measurements', {weight_only: true})
+                .then(m => {
+                    document.getElementById('scale-response').textContent = m.weight + ' г';
+                    addLog('Вес: ' + m.weight + ' г');
                 })
                 .catch(err => {
-                    document.getElementById('scale-response').textContent = 'Ошибка: ' + err;
-                    addLog('Ошибка чтения веса: ' + err);
+                    document.getElementById('scale-response').textContent = 'Ошибка: ' + err.message;
+                    addLog('Ошибка чтения веса: ' + err.message);
                 });
         }
 
@@ -244,16 +591,28 @@ const htmlTemplate = `
             button.disabled = true;
             button.textContent = '⏳ Измеряю...';
             
-            fetch('/measure/combined', {method: 'POST'})
-                .then(response => response.text())
-                .then(data => {
+            api('POST', '/measurements')
+                .then(m => {
+                    if (m.status === 'waiting') {
+                        document.getElementById('scale-response').textContent = 'Запуск принят, ожидание остальных условий политики запуска';
+                        return;
+                    }
+                    loadHistory(0);
+                    if (m.output_status === 'awaiting') {
+                        document.getElementById('scale-response').textContent = 'Измерение: ' + describeMeasurement(m) + ' — ждет подтверждения оператора';
+                        loadConfirm(true);
+                        return;
+                    }
+                    const details = (m.outputs || []).map(o => o.sink + ': ' + (o.error || 'ok'));
+                    const data = 'Измерение завершено: ' + describeMeasurement(m) + ' (' +
+                        (details.length ? details.join('; ') : 'выходы не настроены, измерение сохранено в историю') + ')';
                     document.getElementById('scale-response').textContent = data;
                     addLog('Комплексное измерение: ' + data);
                     
-                    // Показываем уведомление об успешном копировании
+                    // Показываем уведомление об успешной отправке
                     const notification = document.createElement('div');
                     notification.style.cssText = 'position: fixed; top: 20px; right: 20px; z-index: 1000; background: #4CAF50; color: white; padding: 15px 20px; border-radius: 5px; box-shadow: 0 2px 10px rgba(0,0,0,0.3); font-weight: bold;';
-                    notification.textContent = '✅ Данные скопированы в буфер обмена!';
+                    notification.textContent = '✅ Измерение отправлено в выходы станции!';
                     document.body.appendChild(notification);
                     
                     setTimeout(function() {
@@ -261,12 +620,19 @@ const htmlTemplate = `
                     }, 3000);
                 })
                 .catch(err => {
-                    document.getElementById('scale-response').textContent = 'Ошибка: ' + err;
-                    addLog('Ошибка комплексного измерения: ' + err);
+                    // Отклоненное проверкой измерение приходит в details
+                    const text = err.code === 'measurement_rejected' && err.details
+                        ? describeMeasurement(err.details) + ' — ' + err.message
+                        : err.message;
+                    document.getElementById('scale-response').textContent = 'Ошибка: ' + text;
+                    addLog('Ошибка комплексного измерения: ' + text);
+                    if (err.details) {
+                        loadHistory(0);
+                    }
                 })
                 .finally(() => {
                     button.disabled = false;
-                    button.textContent = '🎯 Измерить ВСЁ + копировать';
+                    button.textContent = '🎯 Измерить ВСЁ + отправить';
                 });
         }        
 
@@ -277,11 +643,251 @@ const htmlTemplate = `
             log.scrollTop = log.scrollHeight;
         }
 
+        // История измерений
+        let historyOffset = 0;
+        const historyLimit = 20;
+
+        function historyParams() {
+            const params = new URLSearchParams();
+            const fields = {
+                'from': 'history-from',
+                'to': 'history-to',
+                'barcode': 'history-barcode',
+                'min_weight': 'history-min-weight',
+                'max_weight': 'history-max-weight'
+            };
+            for (const name in fields) {
+                const value = document.getElementById(fields[name]).value;
+                if (value) {
+                    params.set(name, value);
+                }
+            }
+            return params;
+        }
+
+        function exportHistory(format) {
+            const params = historyParams();
+            params.set('format', format);
+            params.set('weight_unit', document.getElementById('export-weight-unit').value);
+            params.set('dimension_unit', document.getElementById('export-dimension-unit').value);
+            window.location = API + '/measurements/export?' + params.toString();
+        }
+
+        function loadHistory(offset) {
+            historyOffset = Math.max(0, offset);
+            const params = historyParams();
+            params.set('offset', historyOffset);
+            params.set('limit', historyLimit);
+
+            api('GET', '/measurements?' + params.toString())
+                .then(page => {
+                    const rows = document.getElementById('history-rows');
+                    rows.innerHTML = '';
+                    page.items.forEach(m => {
+                        const row = document.createElement('tr');
+                        const cells = [
+                            new Date(m.timestamp).toLocaleString(), m.id, m.weight,
+                            m.length, m.width, m.height, m.chargeable_weight, m.barcode || '-', m.trigger, m.output_status
+                        ];
+                        cells.forEach(value => {
+                            const cell = document.createElement('td');
+                            cell.textContent = value;
+                            row.appendChild(cell);
+                        });
+                        // Отклоненные проверкой измерения выделяются, причина — во всплывающей подсказке
+                        if (m.output_status === 'rejected') {
+                            row.className = 'rejected';
+                            row.title = (m.violations || []).join('; ');
+                        }
+                        rows.appendChild(row);
+                    });
+                    const last = Math.min(page.offset + page.items.length, page.total);
+                    document.getElementById('history-info').textContent =
+                        (page.total ? (page.offset + 1) : 0) + '–' + last + ' из ' + page.total;
+                    document.getElementById('history-prev').disabled = page.offset === 0;
+                    document.getElementById('history-next').disabled = last >= page.total;
+                })
+                .catch(err => {
+                    addLog('Ошибка загрузки истории: ' + err);
+                });
+        }
+
+        // Очередь доставки
+        function loadOutbox() {
+            api('GET', '/outbox')
+                .then(queues => {
+                    const panel = document.getElementById('outbox-panel');
+                    panel.innerHTML = '';
+                    if (queues.length === 0) {
+                        panel.textContent = 'Нет очередей доставки';
+                        return;
+                    }
+                    queues.forEach(q => {
+                        const title = document.createElement('h3');
+                        title.textContent = q.name + ': в ожидании ' + q.pending.length + ', не доставлено ' + q.dead.length;
+                        panel.appendChild(title);
+
+                        const actions = [
+                            ['🔁 Повторить все', () => outboxAction('retry', q.name, 'dead', '')],
+                            ['🗑️ Очистить недоставленные', () => outboxAction('purge', q.name, 'dead', '')]
+                        ];
+                        actions.forEach(a => {
+                            const button = document.createElement('button');
+                            button.textContent = a[0];
+                            button.onclick = a[1];
+                            panel.appendChild(button);
+                        });
+
+                        const table = document.createElement('table');
+                        table.innerHTML = '<thead><tr><th>Очередь</th><th>Измерение</th><th>Попыток</th><th>Следующая попытка</th><th>Ошибка</th><th></th></tr></thead>';
+                        const body = document.createElement('tbody');
+                        q.pending.concat(q.dead).forEach(item => {
+                            const row = document.createElement('tr');
+                            const cells = [
+                                item.queue === 'dead' ? 'не доставлено' : 'ожидает',
+                                item.measurement_id, item.attempts,
+                                item.queue === 'dead' ? '-' : new Date(item.next_attempt).toLocaleString(),
+                                item.last_error || '-'
+                            ];
+                            cells.forEach(value => {
+                                const cell = document.createElement('td');
+                                cell.textContent = value;
+                                row.appendChild(cell);
+                            });
+                            const cell = document.createElement('td');
+                            const retry = document.createElement('button');
+                            retry.textContent = '🔁';
+                            retry.onclick = () => outboxAction('retry', q.name, item.queue, item.id);
+                            const purge = document.createElement('button');
+                            purge.textContent = '🗑️';
+                            purge.onclick = () => outboxAction('purge', q.name, item.queue, item.id);
+                            cell.appendChild(retry);
+                            cell.appendChild(purge);
+                            row.appendChild(cell);
+                            body.appendChild(row);
+                        });
+                        table.appendChild(body);
+                        panel.appendChild(table);
+                    });
+                })
+                .catch(err => {
+                    addLog('Ошибка загрузки очереди доставки: ' + err);
+                });
+        }
+
+        function outboxAction(action, sink, queue, id) {
+            api('POST', '/outbox/' + action, {sink: sink, queue: queue, id: id})
+                .then(result => {
+                    addLog('Очередь ' + sink + ': ' + (action === 'retry' ? 'поставлено на повтор' : 'удалено') + ': ' + result.count);
+                    loadOutbox();
+                })
+                .catch(err => {
+                    addLog('Ошибка операции с очередью: ' + err.message);
+                });
+        }
+
+        // Измерения, ожидающие решения оператора. Список перерисовывается только при изменении,
+        // чтобы не сбрасывать поля, которые оператор сейчас исправляет.
+        let confirmIDs = '';
+        function loadConfirm(force) {
+            api('GET', '/measurements?status=awaiting,rejected&limit=20')
+                .then(page => {
+                    const ids = page.items.map(m => m.id + ':' + (m.revision || 0)).join(',');
+                    if (ids === confirmIDs && !force) {
+                        return;
+                    }
+                    confirmIDs = ids;
+                    const rows = document.getElementById('confirm-rows');
+                    rows.innerHTML = '';
+                    page.items.forEach(m => {
+                        const row = document.createElement('tr');
+                        if (m.output_status === 'rejected') {
+                            row.className = 'rejected';
+                        }
+                        const text = value => {
+                            const cell = document.createElement('td');
+                            cell.textContent = value;
+                            row.appendChild(cell);
+                        };
+                        const fields = {};
+                        const input = (name, type, value) => {
+                            const cell = document.createElement('td');
+                            const field = document.createElement('input');
+                            field.type = type;
+                            field.value = value;
+                            fields[name] = field;
+                            cell.appendChild(field);
+                            row.appendChild(cell);
+                        };
+                        text(new Date(m.timestamp).toLocaleString());
+                        text(m.output_status === 'rejected' ? 'отклонено' : 'ждет');
+                        input('weight', 'number', m.weight);
+                        input('length', 'number', m.length);
+                        input('width', 'number', m.width);
+                        input('height', 'number', m.height);
+                        input('barcode', 'text', m.barcode || '');
+                        text((m.violations || []).join('; ') || '-');
+
+                        const cell = document.createElement('td');
+                        const approve = document.createElement('button');
+                        approve.textContent = '✅ Отправить';
+                        approve.onclick = () => {
+                            const body = {operator: document.getElementById('operator-name').value};
+                            body.weight = parseFloat(fields.weight.value);
+                            ['length', 'width', 'height'].forEach(name => body[name] = parseInt(fields[name].value, 10));
+                            body.barcode = fields.barcode.value;
+                            confirmAction(m.id, 'approve', body);
+                        };
+                        const discard = document.createElement('button');
+                        discard.textContent = '🗑️ Отменить';
+                        discard.onclick = () => confirmAction(m.id, 'discard', {operator: document.getElementById('operator-name').value});
+                        cell.appendChild(approve);
+                        cell.appendChild(discard);
+                        row.appendChild(cell);
+                        rows.appendChild(row);
+                    });
+                    document.getElementById('confirm-info').textContent = page.total
+                        ? 'Ожидают решения: ' + page.total
+                        : 'Нет измерений, ожидающих подтверждения';
+                })
+                .catch(err => {
+                    addLog('Ошибка загрузки измерений на подтверждение: ' + err);
+                });
+        }
+
+        function confirmAction(id, action, body) {
+            api('POST', '/measurements/' + encodeURIComponent(id) + '/' + action, body)
+                .then(m => {
+                    addLog('Измерение ' + m.id + ': ' + (action === 'approve' ? 'подтверждено, вывод ' + m.output_status : 'отменено'));
+                })
+                .catch(err => {
+                    addLog('Измерение ' + id + ': ' + err.message);
+                })
+                .finally(() => {
+                    loadConfirm(true);
+                    loadHistory(historyOffset);
+                });
+        }
+
         // Обновляем статус каждые 2 секунды
         setInterval(updateStatus, 2000);
         updateStatus();
         // Подключаемся к потоку логов
         connectToLogs();
+        loadFeedback();
+        if (localStorage.getItem('sound-enabled')) {
+            document.getElementById('sound-enabled').checked = true;
+            document.addEventListener('click', () => toggleSound(true), {once: true});
+        }
+        loadHistory(0);
+        loadOutbox();
+        setInterval(loadOutbox, 10000);
+        document.getElementById('operator-name').value = localStorage.getItem('operator-name') || '';
+        loadConfirm(true);
+        connectToEvents();
+        loadUser();
+        loadGaugeMax();
+        connectLiveWeight();
     </script>
 </body>
 </html>
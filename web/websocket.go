@@ -0,0 +1,170 @@
+package web
+
+import (
+    "bufio"
+    "crypto/sha1"
+    "encoding/base64"
+    "encoding/binary"
+    "errors"
+    "io"
+    "net"
+    "net/http"
+    "strings"
+    "sync"
+    "time"
+)
+
+// Минимальный сервер WebSocket (RFC 6455): только текстовые сообщения от сервера,
+// ответы на ping и закрытие. Сообщения клиента не используются и пропускаются.
+
+const wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// Коды операций кадров
+const (
+    wsOpText  = 0x1
+    wsOpClose = 0x8
+    wsOpPing  = 0x9
+    wsOpPong  = 0xA
+)
+
+// WS_MAX_FRAME — наибольший кадр, который принимается от клиента
+const WS_MAX_FRAME = 64 * 1024
+
+// wsConn — соединение WebSocket после рукопожатия
+type wsConn struct {
+    conn net.Conn
+    buf  *bufio.ReadWriter
+    mu   sync.Mutex // пишут обработчик и ответ на ping из readLoop
+}
+
+func headerContains(h http.Header, name, value string) bool {
+    for _, v := range h.Values(name) {
+        for _, part := range strings.Split(v, ",") {
+            if strings.EqualFold(strings.TrimSpace(part), value) {
+                return true
+            }
+        }
+    }
+    return false
+}
+
+// upgradeWebSocket выполняет рукопожатие. При ошибке до перехвата соединения ответ еще не отправлен.
+func upgradeWebSocket(w http.ResponseWriter, r *http.Request) (*wsConn, error) {
+    if !headerContains(r.Header, "Connection", "upgrade") || !headerContains(r.Header, "Upgrade", "websocket") {
+        return nil, errors.New("ожидается запрос WebSocket (Upgrade: websocket)")
+    }
+    if r.Header.Get("Sec-WebSocket-Version") != "13" {
+        return nil, errors.New("поддерживается только WebSocket версии 13")
+    }
+    key := r.Header.Get("Sec-WebSocket-Key")
+    if key == "" {
+        return nil, errors.New("нет заголовка Sec-WebSocket-Key")
+    }
+    hijacker, ok := w.(http.Hijacker)
+    if !ok {
+        return nil, errors.New("сервер не поддерживает WebSocket")
+    }
+    conn, buf, err := hijacker.Hijack()
+    if err != nil {
+        return nil, err
+    }
+
+    sum := sha1.Sum([]byte(key + wsGUID))
+    buf.WriteString("HTTP/1.1 101 Switching Protocols\r\n" +
+        "Upgrade: websocket\r\n" +
+        "Connection: Upgrade\r\n" +
+        "Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(sum[:]) + "\r\n\r\n")
+    if err := buf.Flush(); err != nil {
+        conn.Close()
+        return nil, err
+    }
+    return &wsConn{conn: conn, buf: buf}, nil
+}
+
+// writeFrame отправляет один кадр без маски (кадры сервера не маскируются)
+func (c *wsConn) writeFrame(op byte, payload []byte, deadline time.Time) error {
+    c.mu.Lock()
+    defer c.mu.Unlock()
+
+    header := []byte{0x80 | op}
+    switch n := len(payload); {
+    case n < 126:
+        header = append(header, byte(n))
+    case n <= 0xFFFF:
+        header = append(header, 126, byte(n>>8), byte(n))
+    default:
+        header = append(header, 127)
+        header = binary.BigEndian.AppendUint64(header, uint64(n))
+    }
+    c.conn.SetWriteDeadline(deadline)
+    if _, err := c.buf.Write(header); err != nil {
+        return err
+    }
+    if _, err := c.buf.Write(payload); err != nil {
+        return err
+    }
+    return c.buf.Flush()
+}
+
+// WriteText отправляет текстовое сообщение. Клиент, который не принимает данные
+// до deadline, считается зависшим: соединение нужно закрыть.
+func (c *wsConn) WriteText(data []byte, deadline time.Time) error {
+    return c.writeFrame(wsOpText, data, deadline)
+}
+
+// readLoop читает кадры клиента: отвечает на ping, на close — закрытием.
+// Завершается, когда клиент закрыл соединение или прислал неверный кадр.
+func (c *wsConn) readLoop() {
+    for {
+        var head [2]byte
+        if _, err := io.ReadFull(c.buf, head[:]); err != nil {
+            return
+        }
+        op := head[0] & 0x0F
+        masked := head[1]&0x80 != 0
+        length := uint64(head[1] & 0x7F)
+        switch length {
+        case 126:
+            var ext [2]byte
+            if _, err := io.ReadFull(c.buf, ext[:]); err != nil {
+                return
+            }
+            length = uint64(binary.BigEndian.Uint16(ext[:]))
+        case 127:
+            var ext [8]byte
+            if _, err := io.ReadFull(c.buf, ext[:]); err != nil {
+                return
+            }
+            length = binary.BigEndian.Uint64(ext[:])
+        }
+        // Кадры клиента обязаны быть замаскированы
+        if !masked || length > WS_MAX_FRAME {
+            return
+        }
+        var mask [4]byte
+        if _, err := io.ReadFull(c.buf, mask[:]); err != nil {
+            return
+        }
+        payload := make([]byte, length)
+        if _, err := io.ReadFull(c.buf, payload); err != nil {
+            return
+        }
+        for i := range payload {
+            payload[i] ^= mask[i%4]
+        }
+
+        switch op {
+        case wsOpPing:
+            if c.writeFrame(wsOpPong, payload, time.Now().Add(5*time.Second)) != nil {
+                return
+            }
+        case wsOpClose:
+            c.writeFrame(wsOpClose, payload, time.Now().Add(time.Second))
+            return
+        }
+    }
+}
+
+func (c *wsConn) Close() error {
+    return c.conn.Close()
+}
\ No newline at end of file